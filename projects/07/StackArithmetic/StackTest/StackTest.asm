@@ -20,13 +20,13 @@ AM=M-1
 D=M
 A=A-1
 D=M-D
-@is-eq-9
+@$$eq.0.true
 D;JEQ
-@end-9
+@$$eq.0.end
 D=0;JMP
-(is-eq-9)
+($$eq.0.true)
 D=-1
-(end-9)
+($$eq.0.end)
 @SP
 A=M-1
 M=D
@@ -52,13 +52,13 @@ AM=M-1
 D=M
 A=A-1
 D=M-D
-@is-eq-12
+@$$eq.1.true
 D;JEQ
-@end-12
+@$$eq.1.end
 D=0;JMP
-(is-eq-12)
+($$eq.1.true)
 D=-1
-(end-12)
+($$eq.1.end)
 @SP
 A=M-1
 M=D
@@ -84,13 +84,13 @@ AM=M-1
 D=M
 A=A-1
 D=M-D
-@is-eq-15
+@$$eq.2.true
 D;JEQ
-@end-15
+@$$eq.2.end
 D=0;JMP
-(is-eq-15)
+($$eq.2.true)
 D=-1
-(end-15)
+($$eq.2.end)
 @SP
 A=M-1
 M=D
@@ -114,15 +114,39 @@ M=M+1
 @SP
 AM=M-1
 D=M
-A=A-1
+@R13
+M=D
+@SP
+A=M-1
+D=M
+@$$lt.0.x_neg
+D;JLT
+@R13
+D=M
+@$$lt.0.same_sign
+D;JGE
+@$$lt.0.false
+0;JMP
+($$lt.0.x_neg)
+@R13
+D=M
+@$$lt.0.same_sign
+D;JLT
+@$$lt.0.true
+0;JMP
+($$lt.0.same_sign)
+@SP
+A=M-1
 D=M-D
-@is-lt-18
+@$$lt.0.true
 D;JLT
-@end-18
-D=0;JMP
-(is-lt-18)
+($$lt.0.false)
+D=0
+@$$lt.0.end
+0;JMP
+($$lt.0.true)
 D=-1
-(end-18)
+($$lt.0.end)
 @SP
 A=M-1
 M=D
@@ -146,15 +170,39 @@ M=M+1
 @SP
 AM=M-1
 D=M
-A=A-1
+@R13
+M=D
+@SP
+A=M-1
+D=M
+@$$lt.1.x_neg
+D;JLT
+@R13
+D=M
+@$$lt.1.same_sign
+D;JGE
+@$$lt.1.false
+0;JMP
+($$lt.1.x_neg)
+@R13
+D=M
+@$$lt.1.same_sign
+D;JLT
+@$$lt.1.true
+0;JMP
+($$lt.1.same_sign)
+@SP
+A=M-1
 D=M-D
-@is-lt-21
+@$$lt.1.true
 D;JLT
-@end-21
-D=0;JMP
-(is-lt-21)
+($$lt.1.false)
+D=0
+@$$lt.1.end
+0;JMP
+($$lt.1.true)
 D=-1
-(end-21)
+($$lt.1.end)
 @SP
 A=M-1
 M=D
@@ -178,15 +226,39 @@ M=M+1
 @SP
 AM=M-1
 D=M
-A=A-1
+@R13
+M=D
+@SP
+A=M-1
+D=M
+@$$lt.2.x_neg
+D;JLT
+@R13
+D=M
+@$$lt.2.same_sign
+D;JGE
+@$$lt.2.false
+0;JMP
+($$lt.2.x_neg)
+@R13
+D=M
+@$$lt.2.same_sign
+D;JLT
+@$$lt.2.true
+0;JMP
+($$lt.2.same_sign)
+@SP
+A=M-1
 D=M-D
-@is-lt-24
+@$$lt.2.true
 D;JLT
-@end-24
-D=0;JMP
-(is-lt-24)
+($$lt.2.false)
+D=0
+@$$lt.2.end
+0;JMP
+($$lt.2.true)
 D=-1
-(end-24)
+($$lt.2.end)
 @SP
 A=M-1
 M=D
@@ -210,15 +282,39 @@ M=M+1
 @SP
 AM=M-1
 D=M
-A=A-1
+@R13
+M=D
+@SP
+A=M-1
+D=M
+@$$gt.0.x_neg
+D;JLT
+@R13
+D=M
+@$$gt.0.same_sign
+D;JGE
+@$$gt.0.true
+0;JMP
+($$gt.0.x_neg)
+@R13
+D=M
+@$$gt.0.same_sign
+D;JLT
+@$$gt.0.false
+0;JMP
+($$gt.0.same_sign)
+@SP
+A=M-1
 D=M-D
-@is-gt-27
+@$$gt.0.true
 D;JGT
-@end-27
-D=0;JMP
-(is-gt-27)
+($$gt.0.false)
+D=0
+@$$gt.0.end
+0;JMP
+($$gt.0.true)
 D=-1
-(end-27)
+($$gt.0.end)
 @SP
 A=M-1
 M=D
@@ -242,15 +338,39 @@ M=M+1
 @SP
 AM=M-1
 D=M
-A=A-1
+@R13
+M=D
+@SP
+A=M-1
+D=M
+@$$gt.1.x_neg
+D;JLT
+@R13
+D=M
+@$$gt.1.same_sign
+D;JGE
+@$$gt.1.true
+0;JMP
+($$gt.1.x_neg)
+@R13
+D=M
+@$$gt.1.same_sign
+D;JLT
+@$$gt.1.false
+0;JMP
+($$gt.1.same_sign)
+@SP
+A=M-1
 D=M-D
-@is-gt-30
+@$$gt.1.true
 D;JGT
-@end-30
-D=0;JMP
-(is-gt-30)
+($$gt.1.false)
+D=0
+@$$gt.1.end
+0;JMP
+($$gt.1.true)
 D=-1
-(end-30)
+($$gt.1.end)
 @SP
 A=M-1
 M=D
@@ -274,15 +394,39 @@ M=M+1
 @SP
 AM=M-1
 D=M
-A=A-1
+@R13
+M=D
+@SP
+A=M-1
+D=M
+@$$gt.2.x_neg
+D;JLT
+@R13
+D=M
+@$$gt.2.same_sign
+D;JGE
+@$$gt.2.true
+0;JMP
+($$gt.2.x_neg)
+@R13
+D=M
+@$$gt.2.same_sign
+D;JLT
+@$$gt.2.false
+0;JMP
+($$gt.2.same_sign)
+@SP
+A=M-1
 D=M-D
-@is-gt-33
+@$$gt.2.true
 D;JGT
-@end-33
-D=0;JMP
-(is-gt-33)
+($$gt.2.false)
+D=0
+@$$gt.2.end
+0;JMP
+($$gt.2.true)
 D=-1
-(end-33)
+($$gt.2.end)
 @SP
 A=M-1
 M=D
@@ -1,3 +1,18 @@
 module vm-translater
 
 go 1.17
+
+require vmt v0.0.0
+
+require (
+	hackemu v0.0.0 // indirect
+	hasm v0.0.0 // indirect
+	jack v0.0.0 // indirect
+)
+
+replace (
+	hackemu => ../../hackemu
+	hasm => ../../hasm
+	jack => ../../jack
+	vmt => ../../vmt
+)
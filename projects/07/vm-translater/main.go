@@ -7,8 +7,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
-	"vm-translater/codewriter"
-	"vm-translater/parser"
+	"vmt/translator"
 )
 
 func main(){
@@ -41,32 +40,42 @@ func main(){
 
 
 	// translate code
-	data := readFile(filePath)
+	file, err := os.Open(filePath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer file.Close()
+
+	statements, err := translator.Parse(file, removeExt(filepath.Base(filePath)))
+	if err != nil {
+		fmt.Println("Error: translating file")
+		fmt.Println(err.Error())
+		return
+	}
 
-	statements := parser.Parse(data)
-	code := codewriter.Write(statements)
+	code, err := translator.Translate(statements, translator.Options{ SingleFile: true })
+	if err != nil {
+		fmt.Println("Error: translating file")
+		fmt.Println(err.Error())
+		return
+	}
 
 	writeFile(fileOutPath, code)
 	fmt.Println("Success!!")
 	fmt.Printf("output file: %v\n", fileOutPath)
 }
 
-func replaceExt(file, newExt string) string {
+func removeExt(file string) string {
 	ext := filepath.Ext(file)
-	return file[0:len(file) - len(ext)] + newExt
+	return file[0:len(file) - len(ext)]
 }
 
-func checkExt(file string) bool {
-	return filepath.Ext(file) == ".vm"
+func replaceExt(file, newExt string) string {
+	return removeExt(file) + newExt
 }
 
-
-func readFile(filePath string) string {
-	data, err := ioutil.ReadFile(filePath)
-	if err != nil {
-		panic(err)
-	}
-	return string(data)
+func checkExt(file string) bool {
+	return filepath.Ext(file) == ".vm"
 }
 
 
@@ -0,0 +1,7 @@
+module hackemu
+
+go 1.17
+
+require hasm v0.0.0
+
+replace hasm => ../hasm
@@ -0,0 +1,90 @@
+package hack
+
+// instruction bits: 111a cccc ccdd djjj
+const (
+	cBit  = 1 << 15
+	aBit  = 1 << 12
+	zxBit = 1 << 11
+	nxBit = 1 << 10
+	zyBit = 1 << 9
+	nyBit = 1 << 8
+	fBit  = 1 << 7
+	noBit = 1 << 6
+	destA = 1 << 5
+	destD = 1 << 4
+	destM = 1 << 3
+	jlt   = 1 << 2
+	jeq   = 1 << 1
+	jgt   = 1 << 0
+)
+
+// ALU computes the hack alu function selected by the six control bits of a
+// c instruction
+func ALU(x, y int16, instruction uint16) int16 {
+	if instruction&zxBit != 0 {
+		x = 0
+	}
+	if instruction&nxBit != 0 {
+		x = ^x
+	}
+	if instruction&zyBit != 0 {
+		y = 0
+	}
+	if instruction&nyBit != 0 {
+		y = ^y
+	}
+
+	var out int16
+	if instruction&fBit != 0 {
+		out = x + y
+	} else {
+		out = x & y
+	}
+
+	if instruction&noBit != 0 {
+		out = ^out
+	}
+	return out
+}
+
+// State is the register file of the cpu
+type State struct {
+	A  int16
+	D  int16
+	PC uint16
+}
+
+// Execute runs a single instruction with inM as the value of M, it returns
+// the next state along with the cpu's outM and writeM outputs
+func (s State) Execute(instruction uint16, inM int16) (next State, outM int16, writeM bool) {
+	next = s
+	next.PC = s.PC + 1
+
+	// a instruction
+	if instruction&cBit == 0 {
+		next.A = int16(instruction)
+		return next, 0, false
+	}
+
+	y := s.A
+	if instruction&aBit != 0 {
+		y = inM
+	}
+	outM = ALU(s.D, y, instruction)
+	writeM = instruction&destM != 0
+
+	if instruction&destA != 0 {
+		next.A = outM
+	}
+	if instruction&destD != 0 {
+		next.D = outM
+	}
+
+	if (instruction&jlt != 0 && outM < 0) ||
+		(instruction&jeq != 0 && outM == 0) ||
+		(instruction&jgt != 0 && outM > 0) {
+		next.PC = uint16(s.A)
+	}
+
+	return next, outM, writeM
+}
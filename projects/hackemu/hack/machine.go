@@ -0,0 +1,95 @@
+package hack
+
+import (
+	"errors"
+	"fmt"
+	"hasm/assembler"
+	"strconv"
+	"strings"
+)
+
+const (
+	ROMSize = 32768
+	RAMSize = 32768
+
+	Screen   = 16384
+	Keyboard = 24576
+)
+
+// Machine is the hack computer, a cpu running a program from rom against
+// ram with the screen and keyboard memory maps
+type Machine struct {
+	State
+	ROM [ROMSize]uint16
+	RAM [RAMSize]int16
+
+	// Cycles counts the instructions run since the last reset
+	Cycles int
+}
+
+func New() *Machine {
+	return &Machine{}
+}
+
+// Reset jumps back to the start of the program, ram is left as it is
+func (m *Machine) Reset() {
+	m.State = State{}
+	m.Cycles = 0
+}
+
+// Load clears rom and copies the program into it
+func (m *Machine) Load(program []uint16) error {
+	if len(program) > ROMSize {
+		return errors.New(fmt.Sprintf("program of %d words does not fit in rom", len(program)))
+	}
+	m.ROM = [ROMSize]uint16{}
+	copy(m.ROM[:], program)
+	m.Reset()
+	return nil
+}
+
+// Step fetches and executes a single instruction
+func (m *Machine) Step() {
+	address := uint16(m.A) % RAMSize
+	next, outM, writeM := m.State.Execute(m.ROM[m.PC%ROMSize], m.RAM[address])
+	if writeM {
+		m.RAM[address] = outM
+	}
+	m.State = next
+	m.Cycles++
+}
+
+// Run steps the machine n times
+func (m *Machine) Run(n int) {
+	for i := 0; i < n; i++ {
+		m.Step()
+	}
+}
+
+// ParseHack reads a .hack file of 16 bit binary words, one per line
+func ParseHack(source string) ([]uint16, error) {
+	var words []uint16
+
+	for i, line := range strings.Split(source, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		w, err := strconv.ParseUint(line, 2, 16)
+		if err != nil || len(line) != 16 {
+			return nil, errors.New(fmt.Sprintf("line %d: invalid hack word: %s", i+1, line))
+		}
+		words = append(words, uint16(w))
+	}
+
+	return words, nil
+}
+
+// ParseProgram reads a program from either a .hack or .asm source
+func ParseProgram(file, source string) ([]uint16, error) {
+	if strings.HasSuffix(file, ".asm") {
+		return assembler.Assemble(source)
+	}
+	return ParseHack(source)
+}
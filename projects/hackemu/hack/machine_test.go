@@ -0,0 +1,91 @@
+package hack
+
+import (
+	"io/ioutil"
+	"testing"
+)
+
+func assert(t *testing.T, n string, a, b interface{}) {
+	if a != b {
+		t.Fatalf("%s : expected: %v <%T>   got: %v <%T>", n, a, a, b, b)
+	}
+}
+
+func load(t *testing.T, path string) *Machine {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	program, err := ParseProgram(path, string(data))
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	m := New()
+	if err := m.Load(program); err != nil {
+		t.Fatalf(err.Error())
+	}
+	return m
+}
+
+func TestMachineMax(t *testing.T) {
+	tests := []struct{ a, b, max int16 }{
+		{3, 5, 5},
+		{5, 3, 5},
+		{-7, -2, -2},
+	}
+
+	for _, file := range []string{"../../05/Max.hack", "../../06/max/Max.asm"} {
+		for _, test := range tests {
+			m := load(t, file)
+			m.RAM[0], m.RAM[1] = test.a, test.b
+			m.Run(20)
+			assert(t, file, test.max, m.RAM[2])
+		}
+	}
+}
+
+func TestALU(t *testing.T) {
+	tests := []struct {
+		comp uint16
+		exp  int16
+	}{
+		{0b101010, 0},
+		{0b111111, 1},
+		{0b111010, -1},
+		{0b001100, 7},   // D
+		{0b110000, -3},  // A
+		{0b001111, -7},  // -D
+		{0b000010, 4},   // D+A
+		{0b010011, 10},  // D-A
+		{0b000111, -10}, // A-D
+		{0b000000, 5},   // D&A
+		{0b010101, -1},  // D|A
+		{0b011111, 8},   // D+1
+		{0b110010, -4},  // A-1
+	}
+
+	for _, test := range tests {
+		assert(t, "ALU", test.exp, ALU(7, -3, test.comp<<6))
+	}
+}
+
+func TestExecuteJump(t *testing.T) {
+	s := State{A: 42, D: -1, PC: 7}
+
+	// D;JLT
+	next, _, _ := s.Execute(0b1110001100000100, 0)
+	assert(t, "JLT", uint16(42), next.PC)
+
+	// D;JGT
+	next, _, _ = s.Execute(0b1110001100000001, 0)
+	assert(t, "JGT", uint16(8), next.PC)
+
+	// AM=M+1 writes M and jumps with the old A
+	next, outM, writeM := s.Execute(0b1111110111101111, 9)
+	assert(t, "AM=M+1", int16(10), outM)
+	assert(t, "AM=M+1", true, writeM)
+	assert(t, "AM=M+1", int16(10), next.A)
+	assert(t, "AM=M+1", uint16(42), next.PC)
+}
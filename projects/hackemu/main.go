@@ -0,0 +1,100 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"hackemu/hack"
+	"hackemu/script"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+var cycles = flag.Int("cycles", 100000, "number of instructions to run a .hack or .asm program for")
+var ram = flag.Int("ram", 16, "number of ram words printed after running a program")
+
+func main() {
+	flag.Parse()
+
+	// check args
+	if flag.NArg() != 1 {
+		fmt.Println("Error: No file name provided")
+		fmt.Println("useage: hackemu [-cycles n] [-ram n] <file.tst | file.hack | file.asm>")
+		os.Exit(2)
+	}
+
+	path := flag.Arg(0)
+
+	var err error
+	switch filepath.Ext(path) {
+	case ".tst":
+		err = runScript(path)
+	case ".hack", ".asm":
+		err = runProgram(path)
+	default:
+		fmt.Printf("Invalid file type, expected: '.tst', '.hack' or '.asm', got: '%v'\n", filepath.Ext(path))
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fmt.Printf("Error: running %v\n", path)
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+}
+
+// runScript runs a test script, writing its output file and checking it
+// against its compare file
+func runScript(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	r := script.New(filepath.Dir(path))
+	r.Echo = func(msg string) { fmt.Println(msg) }
+	err = r.RunScript(string(data))
+
+	// the output is written even on failure, it is the easiest way to see
+	// where the program went wrong
+	if r.OutputFile != "" {
+		if werr := ioutil.WriteFile(r.OutputFile, []byte(r.Output.String()), fs.ModePerm); werr != nil && err == nil {
+			err = werr
+		}
+	}
+
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("End of script - Comparison ended successfully")
+	return nil
+}
+
+// runProgram runs a program for a fixed number of cycles and prints the
+// registers and the start of ram
+func runProgram(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	program, err := hack.ParseProgram(path, string(data))
+	if err != nil {
+		return err
+	}
+
+	m := hack.New()
+	if err := m.Load(program); err != nil {
+		return err
+	}
+	m.Run(*cycles)
+
+	fmt.Printf("A: %d  D: %d  PC: %d\n", m.A, m.D, m.PC)
+	for i := 0; i < *ram && i < hack.RAMSize; i++ {
+		fmt.Printf("RAM[%d]: %d\n", i, m.RAM[i])
+	}
+
+	return nil
+}
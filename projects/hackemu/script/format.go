@@ -0,0 +1,131 @@
+package script
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Column is an output-list entry, <name>%<format><left>.<len>.<right>
+type Column struct {
+	Name   string
+	Format byte
+	Left   int
+	Len    int
+	Right  int
+}
+
+// parseColumn reads an output-list entry, the format defaults to %B1.16.1
+func parseColumn(s string) (Column, error) {
+	col := Column{Name: s, Format: 'B', Left: 1, Len: 16, Right: 1}
+
+	i := strings.LastIndex(s, "%")
+	if i < 0 {
+		return col, nil
+	}
+	col.Name = s[:i]
+
+	spec := s[i+1:]
+	if spec == "" || strings.IndexByte("BDXS", spec[0]) < 0 {
+		return col, errors.New(fmt.Sprintf("invalid output format: %s", s))
+	}
+	col.Format = spec[0]
+
+	parts := strings.Split(spec[1:], ".")
+	if len(parts) != 3 {
+		return col, errors.New(fmt.Sprintf("invalid output format: %s", s))
+	}
+
+	var nums [3]int
+	for j, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return col, errors.New(fmt.Sprintf("invalid output format: %s", s))
+		}
+		nums[j] = n
+	}
+	col.Left, col.Len, col.Right = nums[0], nums[1], nums[2]
+
+	return col, nil
+}
+
+func (c Column) width() int {
+	return c.Left + c.Len + c.Right
+}
+
+// Header is the column name centred in the column, cut down if it does not fit
+func (c Column) Header() string {
+	w := c.width()
+	if len(c.Name) >= w {
+		return c.Name[:w]
+	}
+	left := (w - len(c.Name)) / 2
+	return strings.Repeat(" ", left) + c.Name + strings.Repeat(" ", w-left-len(c.Name))
+}
+
+// Value formats a value for the column, numbers are right aligned and
+// strings left aligned
+func (c Column) Value(v int16) string {
+	var s string
+	switch c.Format {
+	case 'B':
+		s = fmt.Sprintf("%016b", uint16(v))
+		s = s[len(s)-min(c.Len, len(s)):]
+	case 'X':
+		s = fmt.Sprintf("%04X", uint16(v))
+		s = s[len(s)-min(c.Len, len(s)):]
+	default:
+		s = strconv.Itoa(int(v))
+	}
+	return c.pad(s, false)
+}
+
+// String formats a string value, such as the time, for the column
+func (c Column) String(s string) string {
+	return c.pad(s, true)
+}
+
+func (c Column) pad(s string, left bool) string {
+	fill := ""
+	if len(s) < c.Len {
+		fill = strings.Repeat(" ", c.Len-len(s))
+	}
+	if left {
+		s = s + fill
+	} else {
+		s = fill + s
+	}
+	return strings.Repeat(" ", c.Left) + s + strings.Repeat(" ", c.Right)
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// parseValue reads a set value, %B binary, %X hex, %D or plain decimal
+func parseValue(s string) (int16, error) {
+	base := 10
+	digits := s
+	if len(s) > 2 && s[0] == '%' {
+		switch s[1] {
+		case 'B':
+			base = 2
+		case 'X':
+			base = 16
+		case 'D':
+		default:
+			return 0, errors.New(fmt.Sprintf("invalid value: %s", s))
+		}
+		digits = s[2:]
+	}
+
+	v, err := strconv.ParseInt(digits, base, 32)
+	if err != nil || v < -32768 || v > 65535 {
+		return 0, errors.New(fmt.Sprintf("invalid value: %s", s))
+	}
+	return int16(uint16(v)), nil
+}
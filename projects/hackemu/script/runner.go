@@ -0,0 +1,429 @@
+package script
+
+import (
+	"errors"
+	"fmt"
+	"hackemu/hack"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// DefaultMaxLoop bounds while loops so a script waiting on input that never
+// comes fails instead of hanging
+const DefaultMaxLoop = 1000000
+
+// Runner executes a test script, writing output lines and checking them
+// against the compare file as they are written
+type Runner struct {
+	// Dir is the directory files named in the script are relative to
+	Dir string
+
+	// Echo is called with the message of echo commands, nil ignores them
+	Echo func(msg string)
+
+	MaxLoop int
+
+	// Output holds every line written by output and output-list
+	Output     strings.Builder
+	OutputFile string
+
+	target  Target
+	columns []Column
+	compare []string
+	lines   int
+
+	time int
+	half bool
+}
+
+func New(dir string) *Runner {
+	return &Runner{Dir: dir, MaxLoop: DefaultMaxLoop}
+}
+
+// RunFile parses and runs the script at path
+func RunFile(path string) (*Runner, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	r := New(filepath.Dir(path))
+	return r, r.RunScript(string(data))
+}
+
+// RunScript parses and runs a script
+func (r *Runner) RunScript(source string) error {
+	commands, err := Parse(source)
+	if err != nil {
+		return err
+	}
+	return r.Run(commands)
+}
+
+// Run executes the commands in order, stopping at the first error or
+// comparison failure
+func (r *Runner) Run(commands []*Command) error {
+	for _, cmd := range commands {
+		if err := r.execute(cmd); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetKeyboard sets the key the target's keyboard reports as pressed
+func (r *Runner) SetKeyboard(key int16) error {
+	if r.target == nil {
+		return errors.New("no program or chip loaded")
+	}
+	return r.target.Set("Keyboard", -1, key)
+}
+
+// Get reads a variable of the loaded target, such as RAM[256]
+func (r *Runner) Get(variable string) (int16, error) {
+	if r.target == nil {
+		return 0, errors.New("no program or chip loaded")
+	}
+	name, index, err := splitVariable(variable)
+	if err != nil {
+		return 0, err
+	}
+	return r.target.Get(name, index)
+}
+
+func (r *Runner) path(file string) string {
+	if filepath.IsAbs(file) {
+		return file
+	}
+	return filepath.Join(r.Dir, file)
+}
+
+// splitVariable splits RAM[5] into RAM and 5, [] and plain names get -1
+func splitVariable(s string) (string, int, error) {
+	i := strings.Index(s, "[")
+	if i < 0 {
+		return s, -1, nil
+	}
+	if !strings.HasSuffix(s, "]") {
+		return "", 0, errors.New(fmt.Sprintf("invalid variable: %s", s))
+	}
+
+	inner := s[i+1 : len(s)-1]
+	if inner == "" {
+		return s[:i], -1, nil
+	}
+
+	index, err := strconv.Atoi(inner)
+	if err != nil {
+		return "", 0, errors.New(fmt.Sprintf("invalid variable: %s", s))
+	}
+	return s[:i], index, nil
+}
+
+func (r *Runner) execute(cmd *Command) error {
+	err := r.command(cmd)
+	if err != nil && !strings.HasPrefix(err.Error(), "line ") {
+		err = scriptError(cmd.Line, "%s", err.Error())
+	}
+	return err
+}
+
+func (r *Runner) command(cmd *Command) error {
+	words := cmd.Words
+	args := words[1:]
+
+	// every command but load needs something loaded
+	switch words[0] {
+	case "load", "output-file", "compare-to", "output-list", "echo", "clear-echo", "repeat", "while":
+	default:
+		if r.target == nil {
+			return errors.New("no program or chip loaded")
+		}
+	}
+
+	switch words[0] {
+	case "load":
+		if len(args) != 1 {
+			return errors.New("load expects a file")
+		}
+		return r.load(args[0])
+
+	case "output-file":
+		if len(args) != 1 {
+			return errors.New("output-file expects a file")
+		}
+		r.OutputFile = r.path(args[0])
+
+	case "compare-to":
+		if len(args) != 1 {
+			return errors.New("compare-to expects a file")
+		}
+		data, err := ioutil.ReadFile(r.path(args[0]))
+		if err != nil {
+			return err
+		}
+		r.compare = strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+		r.lines = 0
+
+	case "output-list":
+		r.columns = nil
+		for _, arg := range args {
+			col, err := parseColumn(arg)
+			if err != nil {
+				return err
+			}
+			r.columns = append(r.columns, col)
+		}
+		return r.header()
+
+	case "output":
+		return r.output()
+
+	case "set":
+		if len(args) != 2 {
+			return errors.New("set expects a variable and a value")
+		}
+		name, index, err := splitVariable(args[0])
+		if err != nil {
+			return err
+		}
+		value, err := parseValue(args[1])
+		if err != nil {
+			return err
+		}
+		return r.target.Set(name, index, value)
+
+	case "tick":
+		r.target.Tick()
+		r.half = true
+
+	case "tock":
+		r.target.Tock()
+		r.time++
+		r.half = false
+
+	case "ticktock":
+		r.target.Tick()
+		r.target.Tock()
+		r.time++
+		r.half = false
+
+	case "eval":
+		r.target.Eval()
+
+	case "echo":
+		if r.Echo != nil {
+			r.Echo(strings.Trim(strings.Join(args, " "), "\""))
+		}
+
+	case "clear-echo":
+
+	case "repeat":
+		// a repeat without a count runs forever, headless that is never useful
+		if len(args) != 1 {
+			return errors.New("repeat expects a count")
+		}
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < 0 {
+			return errors.New(fmt.Sprintf("invalid repeat count: %s", args[0]))
+		}
+		for i := 0; i < n; i++ {
+			if err := r.Run(cmd.Body); err != nil {
+				return err
+			}
+		}
+
+	case "while":
+		for i := 0; ; i++ {
+			ok, err := r.condition(args)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				break
+			}
+			if i >= r.MaxLoop {
+				return errors.New(fmt.Sprintf("while loop did not end after %d iterations", r.MaxLoop))
+			}
+			if err := r.Run(cmd.Body); err != nil {
+				return err
+			}
+		}
+
+	default:
+		// <part> load <file>, eg ROM32K load Add.hack
+		if len(args) == 2 && args[0] == "load" {
+			program, err := r.program(args[1])
+			if err != nil {
+				return err
+			}
+			return r.target.Load(program)
+		}
+		return errors.New(fmt.Sprintf("unknown command: %s", cmd.String()))
+	}
+
+	return nil
+}
+
+// load selects a built in chip for .hdl files, otherwise it loads the
+// program into the cpu emulator
+func (r *Runner) load(file string) error {
+	r.time = 0
+	r.half = false
+
+	if filepath.Ext(file) == ".hdl" {
+		chip, err := newChip(strings.TrimSuffix(filepath.Base(file), ".hdl"))
+		if err != nil {
+			return err
+		}
+		r.target = chip
+		return nil
+	}
+
+	program, err := r.program(file)
+	if err != nil {
+		return err
+	}
+
+	m := hack.New()
+	if err := m.Load(program); err != nil {
+		return err
+	}
+	r.target = &emulator{m}
+
+	return nil
+}
+
+func (r *Runner) program(file string) ([]uint16, error) {
+	data, err := ioutil.ReadFile(r.path(file))
+	if err != nil {
+		return nil, err
+	}
+	return hack.ParseProgram(file, string(data))
+}
+
+// condition => <variable | value> <op> <variable | value>
+func (r *Runner) condition(args []string) (bool, error) {
+	if len(args) != 3 {
+		return false, errors.New(fmt.Sprintf("invalid condition: %s", strings.Join(args, " ")))
+	}
+
+	a, err := r.operand(args[0])
+	if err != nil {
+		return false, err
+	}
+	b, err := r.operand(args[2])
+	if err != nil {
+		return false, err
+	}
+
+	switch args[1] {
+	case "=":
+		return a == b, nil
+	case "<>":
+		return a != b, nil
+	case "<":
+		return a < b, nil
+	case ">":
+		return a > b, nil
+	case "<=":
+		return a <= b, nil
+	case ">=":
+		return a >= b, nil
+	}
+	return false, errors.New(fmt.Sprintf("invalid comparison: %s", args[1]))
+}
+
+func (r *Runner) operand(s string) (int16, error) {
+	if v, err := parseValue(s); err == nil {
+		return v, nil
+	}
+	if r.target == nil {
+		return 0, errors.New("no program or chip loaded")
+	}
+	name, index, err := splitVariable(s)
+	if err != nil {
+		return 0, err
+	}
+	return r.target.Get(name, index)
+}
+
+// ---------------------------------------------------------------------------------
+// Output --------------------------------------------------------------------------
+// ---------------------------------------------------------------------------------
+
+func (r *Runner) timeString() string {
+	if r.half {
+		return strconv.Itoa(r.time) + "+"
+	}
+	return strconv.Itoa(r.time)
+}
+
+func (r *Runner) header() error {
+	var sb strings.Builder
+	sb.WriteString("|")
+	for _, col := range r.columns {
+		sb.WriteString(col.Header())
+		sb.WriteString("|")
+	}
+	return r.writeLine(sb.String())
+}
+
+func (r *Runner) output() error {
+	var sb strings.Builder
+	sb.WriteString("|")
+	for _, col := range r.columns {
+		if col.Name == "time" {
+			sb.WriteString(col.String(r.timeString()))
+		} else {
+			name, index, err := splitVariable(col.Name)
+			if err != nil {
+				return err
+			}
+			v, err := r.target.Get(name, index)
+			if err != nil {
+				return err
+			}
+			sb.WriteString(col.Value(v))
+		}
+		sb.WriteString("|")
+	}
+	return r.writeLine(sb.String())
+}
+
+// writeLine adds a line to the output and checks it against the compare
+// file, * in the compare file matches any character
+func (r *Runner) writeLine(line string) error {
+	r.Output.WriteString(line)
+	r.Output.WriteString("\n")
+
+	if r.compare == nil {
+		return nil
+	}
+
+	r.lines++
+	if r.lines > len(r.compare) {
+		return errors.New(fmt.Sprintf("comparison failure at line %d: compare file ended, got: %s", r.lines, line))
+	}
+
+	expected := strings.TrimRight(r.compare[r.lines-1], " \t")
+	if !matches(expected, strings.TrimRight(line, " \t")) {
+		return errors.New(fmt.Sprintf("comparison failure at line %d\nexpected: %s\ngot:      %s", r.lines, expected, line))
+	}
+
+	return nil
+}
+
+func matches(pattern, s string) bool {
+	if len(pattern) != len(s) {
+		return false
+	}
+	for i := 0; i < len(pattern); i++ {
+		if pattern[i] != '*' && pattern[i] != s[i] {
+			return false
+		}
+	}
+	return true
+}
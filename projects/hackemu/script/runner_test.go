@@ -0,0 +1,116 @@
+package script
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func assert(t *testing.T, n string, a, b interface{}) {
+	if a != b {
+		t.Fatalf("%s : expected: %v <%T>   got: %v <%T>", n, a, a, b, b)
+	}
+}
+
+func runScripts(t *testing.T, pattern string) {
+	files, err := filepath.Glob(pattern)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if len(files) == 0 {
+		t.Fatalf("no scripts match %s", pattern)
+	}
+
+	for _, file := range files {
+		// VME scripts are for the vm emulator
+		if strings.HasSuffix(file, "VME.tst") {
+			continue
+		}
+
+		// the projects/08 programs only exist once translated
+		program := strings.TrimSuffix(file, ".tst") + ".asm"
+		if _, err := os.Stat(program); strings.Contains(file, "08") && err != nil {
+			t.Logf("%s : skipped, %s not translated", file, filepath.Base(program))
+			continue
+		}
+
+		r := New(filepath.Dir(file))
+		r.Echo = func(msg string) {
+			// hold down the key the memory test asks for
+			for _, key := range []string{"'K'", "'Y'"} {
+				if strings.Contains(msg, key) {
+					r.SetKeyboard(int16(key[1]))
+				}
+			}
+		}
+
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		if err := r.RunScript(string(data)); err != nil {
+			t.Errorf("%s : %s", file, err.Error())
+		}
+	}
+}
+
+func TestProject05(t *testing.T) {
+	runScripts(t, "../../05/*.tst")
+}
+
+func TestProject07(t *testing.T) {
+	runScripts(t, "../../07/*/*/*.tst")
+}
+
+func TestProject08(t *testing.T) {
+	runScripts(t, "../../08/*/*/*.tst")
+}
+
+func TestColumns(t *testing.T) {
+	tests := []struct {
+		spec   string
+		header string
+		value  int16
+		out    string
+	}{
+		{"RAM[0]%D2.6.2", "  RAM[0]  ", 266, "     266  "},
+		{"RAM[256]%D2.6.2", " RAM[256] ", -1, "      -1  "},
+		{"DRegister[]%D1.6.1", "DRegiste", 11111, "  11111 "},
+		{"reset%B2.1.2", "reset", 1, "  1  "},
+		{"address%B1.15.1", "     address     ", 0x2000, " 010000000000000 "},
+		{"instruction%B0.16.0", "  instruction   ", -1, "1111111111111111"},
+		{"pc%X1.4.1", "  pc  ", 255, " 00FF "},
+		{"out", "       out        ", 5, " 0000000000000101 "},
+	}
+
+	for _, test := range tests {
+		col, err := parseColumn(test.spec)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		assert(t, test.spec, test.header, col.Header())
+		assert(t, test.spec, test.out, col.Value(test.value))
+	}
+}
+
+func TestScriptErrors(t *testing.T) {
+	tests := []string{
+		"set RAM[0] 1;",
+		"load Nope.hdl;",
+		"load CPU.hdl, set nope 1;",
+		"load CPU.hdl, set inM %Q1;",
+		"load Memory.hdl, while out <> 1 { tick, tock; }",
+		"load CPU.hdl, repeat 2 { tick;",
+		"load CPU.hdl, tick",
+		"/* never closed",
+	}
+
+	for _, test := range tests {
+		r := New(".")
+		r.MaxLoop = 100
+		if err := r.RunScript(test); err == nil {
+			t.Errorf("expected error running: %s", test)
+		}
+	}
+}
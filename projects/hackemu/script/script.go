@@ -0,0 +1,172 @@
+package script
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Command is a single script command, its words up to the terminating , ;
+// or !. repeat and while commands hold the commands of their block in Body.
+type Command struct {
+	Line  int
+	Words []string
+	Body  []*Command
+}
+
+func (c *Command) String() string {
+	return strings.Join(c.Words, " ")
+}
+
+func scriptError(line int, format string, args ...interface{}) error {
+	return errors.New(fmt.Sprintf("line %d: %s", line, fmt.Sprintf(format, args...)))
+}
+
+type token struct {
+	line  int
+	value string
+}
+
+const punctuation = ",;!{}"
+
+// tokenize splits a script into words, strings and punctuation, dropping
+// comments
+func tokenize(source string) ([]token, error) {
+	var tokens []token
+	line := 1
+
+	for i := 0; i < len(source); {
+		ch := source[i]
+		switch {
+		case ch == '\n':
+			line++
+			i++
+
+		case ch == ' ' || ch == '\t' || ch == '\r':
+			i++
+
+		case strings.HasPrefix(source[i:], "//"):
+			for i < len(source) && source[i] != '\n' {
+				i++
+			}
+
+		case strings.HasPrefix(source[i:], "/*"):
+			end := strings.Index(source[i+2:], "*/")
+			if end < 0 {
+				return nil, scriptError(line, "unterminated comment")
+			}
+			line += strings.Count(source[i:i+2+end], "\n")
+			i += end + 4
+
+		case ch == '"':
+			end := strings.IndexAny(source[i+1:], "\"\n")
+			if end < 0 || source[i+1+end] != '"' {
+				return nil, scriptError(line, "unterminated string")
+			}
+			tokens = append(tokens, token{line, source[i : i+end+2]})
+			i += end + 2
+
+		case strings.IndexByte(punctuation, ch) >= 0:
+			tokens = append(tokens, token{line, string(ch)})
+			i++
+
+		default:
+			start := i
+			for i < len(source) && !strings.ContainsRune(" \t\r\n\""+punctuation, rune(source[i])) {
+				i++
+			}
+			tokens = append(tokens, token{line, source[start:i]})
+		}
+	}
+
+	return tokens, nil
+}
+
+// Parse reads a test script into its commands
+func Parse(source string) ([]*Command, error) {
+	tokens, err := tokenize(source)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	commands, err := p.parseBlock()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, scriptError(p.tokens[p.pos].line, "unexpected %s", p.tokens[p.pos].value)
+	}
+
+	return commands, nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+// parseBlock => {<command>} up to a closing } or the end of the script
+func (p *parser) parseBlock() ([]*Command, error) {
+	var commands []*Command
+
+	for p.pos < len(p.tokens) && p.tokens[p.pos].value != "}" {
+		cmd, err := p.parseCommand()
+		if err != nil {
+			return nil, err
+		}
+		if cmd != nil {
+			commands = append(commands, cmd)
+		}
+	}
+
+	return commands, nil
+}
+
+// parseCommand => <word> {<word>} <, | ; | !> | <repeat | while> ... { <block> }
+func (p *parser) parseCommand() (*Command, error) {
+	cmd := &Command{Line: p.tokens[p.pos].line}
+
+	for p.pos < len(p.tokens) {
+		tok := p.tokens[p.pos]
+		p.pos++
+
+		switch tok.value {
+		case ",", ";", "!":
+			if len(cmd.Words) == 0 {
+				return nil, nil
+			}
+			return cmd, nil
+
+		case "}":
+			// the last command of a block may leave out its terminator
+			p.pos--
+			return cmd, nil
+
+		case "{":
+			if len(cmd.Words) == 0 || (cmd.Words[0] != "repeat" && cmd.Words[0] != "while") {
+				return nil, scriptError(tok.line, "unexpected {")
+			}
+
+			body, err := p.parseBlock()
+			if err != nil {
+				return nil, err
+			}
+			if p.pos >= len(p.tokens) {
+				return nil, scriptError(cmd.Line, "unterminated %s block", cmd.Words[0])
+			}
+			p.pos++
+
+			cmd.Body = body
+			return cmd, nil
+
+		default:
+			cmd.Words = append(cmd.Words, tok.value)
+		}
+	}
+
+	if len(cmd.Words) == 0 {
+		return nil, nil
+	}
+	return nil, scriptError(cmd.Line, "missing terminator after: %s", cmd.String())
+}
@@ -0,0 +1,361 @@
+package script
+
+import (
+	"errors"
+	"fmt"
+	"hackemu/hack"
+)
+
+// Target is what a script drives, either the cpu emulator running a program
+// or one of the built in chips of projects/05. Indexed variables such as
+// RAM[5] are passed as name and index, index is -1 for plain names and [].
+type Target interface {
+	Get(name string, index int) (int16, error)
+	Set(name string, index int, value int16) error
+	Tick()
+	Tock()
+	Eval()
+	// Load loads a program into the target's rom, "<part> load <file>"
+	Load(program []uint16) error
+}
+
+func unknownVariable(name string) error {
+	return errors.New(fmt.Sprintf("unknown variable: %s", name))
+}
+
+func readOnly(name string) error {
+	return errors.New(fmt.Sprintf("variable is read only: %s", name))
+}
+
+func indexError(name string, index, size int) error {
+	return errors.New(fmt.Sprintf("%s[%d] out of range 0..%d", name, index, size-1))
+}
+
+// newChip returns the built in model of a projects/05 chip
+func newChip(name string) (Target, error) {
+	switch name {
+	case "CPU":
+		return &cpuChip{}, nil
+	case "Memory":
+		return &memoryChip{}, nil
+	case "Computer":
+		return &computerChip{}, nil
+	}
+	return nil, errors.New(fmt.Sprintf("unsupported chip: %s", name))
+}
+
+// ---------------------------------------------------------------------------------
+// CPU emulator --------------------------------------------------------------------
+// ---------------------------------------------------------------------------------
+
+// emulator runs a whole instruction every tick, like the course cpu emulator
+type emulator struct {
+	*hack.Machine
+}
+
+func (e *emulator) Get(name string, index int) (int16, error) {
+	switch name {
+	case "RAM":
+		if index < 0 || index >= hack.RAMSize {
+			return 0, indexError(name, index, hack.RAMSize)
+		}
+		return e.RAM[index], nil
+	case "ROM":
+		if index < 0 || index >= hack.ROMSize {
+			return 0, indexError(name, index, hack.ROMSize)
+		}
+		return int16(e.ROM[index]), nil
+	case "A":
+		return e.A, nil
+	case "D":
+		return e.D, nil
+	case "PC":
+		return int16(e.PC), nil
+	case "Keyboard":
+		return e.RAM[hack.Keyboard], nil
+	}
+	return 0, unknownVariable(name)
+}
+
+func (e *emulator) Set(name string, index int, value int16) error {
+	switch name {
+	case "RAM":
+		if index < 0 || index >= hack.RAMSize {
+			return indexError(name, index, hack.RAMSize)
+		}
+		e.RAM[index] = value
+	case "ROM":
+		if index < 0 || index >= hack.ROMSize {
+			return indexError(name, index, hack.ROMSize)
+		}
+		e.ROM[index] = uint16(value)
+	case "A":
+		e.A = value
+	case "D":
+		e.D = value
+	case "PC":
+		e.PC = uint16(value)
+	case "Keyboard":
+		e.RAM[hack.Keyboard] = value
+	default:
+		return unknownVariable(name)
+	}
+	return nil
+}
+
+func (e *emulator) Tick() { e.Step() }
+func (e *emulator) Tock() {}
+func (e *emulator) Eval() {}
+
+// ---------------------------------------------------------------------------------
+// Chips ---------------------------------------------------------------------------
+// ---------------------------------------------------------------------------------
+
+// the chips are clocked, tick computes the next state from the inputs and
+// tock commits it. Their registers show the next state as soon as it is
+// computed, their outputs only change on tock.
+
+// memory is the data memory chip, 16K of ram, the screen and the keyboard
+type memory struct {
+	ram      [hack.Keyboard]int16
+	keyboard int16
+
+	write   bool
+	address int
+	value   int16
+}
+
+func (m *memory) read(address int) int16 {
+	switch {
+	case address < hack.Keyboard:
+		return m.ram[address]
+	case address == hack.Keyboard:
+		return m.keyboard
+	}
+	return 0
+}
+
+func (m *memory) tick(load bool, address int, value int16) {
+	m.write = load && address < hack.Keyboard
+	m.address = address
+	m.value = value
+}
+
+func (m *memory) tock() {
+	if m.write {
+		m.ram[m.address] = m.value
+		m.write = false
+	}
+}
+
+func address15(v int16) int {
+	return int(uint16(v) & 0x7fff)
+}
+
+func boolValue(b bool) int16 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// memoryChip => Memory(in, load, address) -> out
+type memoryChip struct {
+	memory
+	in, load, address int16
+}
+
+func (c *memoryChip) Get(name string, index int) (int16, error) {
+	switch name {
+	case "in":
+		return c.in, nil
+	case "load":
+		return c.load, nil
+	case "address":
+		return c.address, nil
+	case "out":
+		return c.read(address15(c.address)), nil
+	case "Keyboard":
+		return c.keyboard, nil
+	}
+	return 0, unknownVariable(name)
+}
+
+func (c *memoryChip) Set(name string, index int, value int16) error {
+	switch name {
+	case "in":
+		c.in = value
+	case "load":
+		c.load = value
+	case "address":
+		c.address = value
+	case "Keyboard":
+		c.keyboard = value
+	case "out":
+		return readOnly(name)
+	default:
+		return unknownVariable(name)
+	}
+	return nil
+}
+
+func (c *memoryChip) Tick() { c.tick(c.load != 0, address15(c.address), c.in) }
+func (c *memoryChip) Tock() { c.tock() }
+func (c *memoryChip) Eval() {}
+func (c *memoryChip) Load(program []uint16) error {
+	return errors.New("the Memory chip has no rom")
+}
+
+// cpuChip => CPU(inM, instruction, reset) -> outM, writeM, addressM, pc
+type cpuChip struct {
+	inM, instruction, reset int16
+
+	state hack.State
+	next  hack.State
+}
+
+func (c *cpuChip) outputs() (outM int16, writeM bool) {
+	_, outM, writeM = c.state.Execute(uint16(c.instruction), c.inM)
+	return outM, writeM
+}
+
+func (c *cpuChip) Get(name string, index int) (int16, error) {
+	switch name {
+	case "inM":
+		return c.inM, nil
+	case "instruction":
+		return c.instruction, nil
+	case "reset":
+		return c.reset, nil
+	case "outM":
+		outM, _ := c.outputs()
+		return outM, nil
+	case "writeM":
+		_, writeM := c.outputs()
+		return boolValue(writeM), nil
+	case "addressM":
+		return int16(address15(c.state.A)), nil
+	case "pc":
+		return int16(c.state.PC), nil
+	case "ARegister":
+		return c.next.A, nil
+	case "DRegister":
+		return c.next.D, nil
+	case "PC":
+		return int16(c.next.PC), nil
+	}
+	return 0, unknownVariable(name)
+}
+
+func (c *cpuChip) Set(name string, index int, value int16) error {
+	switch name {
+	case "inM":
+		c.inM = value
+	case "instruction":
+		c.instruction = value
+	case "reset":
+		c.reset = value
+	case "outM", "writeM", "addressM", "pc", "ARegister", "DRegister", "PC":
+		return readOnly(name)
+	default:
+		return unknownVariable(name)
+	}
+	return nil
+}
+
+func (c *cpuChip) Tick() {
+	c.next, _, _ = c.state.Execute(uint16(c.instruction), c.inM)
+	if c.reset != 0 {
+		c.next.PC = 0
+	}
+}
+
+func (c *cpuChip) Tock() { c.state = c.next }
+func (c *cpuChip) Eval() {}
+func (c *cpuChip) Load(program []uint16) error {
+	return errors.New("the CPU chip has no rom")
+}
+
+// computerChip => Computer(reset), a cpu wired to ROM32K and Memory
+type computerChip struct {
+	memory
+	rom   [hack.ROMSize]uint16
+	reset int16
+
+	state hack.State
+	next  hack.State
+}
+
+func (c *computerChip) Get(name string, index int) (int16, error) {
+	switch name {
+	case "reset":
+		return c.reset, nil
+	case "ARegister":
+		return c.next.A, nil
+	case "DRegister":
+		return c.next.D, nil
+	case "PC":
+		return int16(c.next.PC), nil
+	case "RAM16K":
+		if index < 0 || index >= 16384 {
+			return 0, indexError(name, index, 16384)
+		}
+		return c.ram[index], nil
+	case "ROM32K":
+		if index < 0 || index >= hack.ROMSize {
+			return 0, indexError(name, index, hack.ROMSize)
+		}
+		return int16(c.rom[index]), nil
+	case "Keyboard":
+		return c.keyboard, nil
+	}
+	return 0, unknownVariable(name)
+}
+
+func (c *computerChip) Set(name string, index int, value int16) error {
+	switch name {
+	case "reset":
+		c.reset = value
+	case "RAM16K":
+		if index < 0 || index >= 16384 {
+			return indexError(name, index, 16384)
+		}
+		c.ram[index] = value
+	case "ROM32K":
+		if index < 0 || index >= hack.ROMSize {
+			return indexError(name, index, hack.ROMSize)
+		}
+		c.rom[index] = uint16(value)
+	case "Keyboard":
+		c.keyboard = value
+	default:
+		return unknownVariable(name)
+	}
+	return nil
+}
+
+func (c *computerChip) Tick() {
+	address := address15(c.state.A)
+	next, outM, writeM := c.state.Execute(c.rom[c.state.PC%hack.ROMSize], c.read(address))
+	if c.reset != 0 {
+		next.PC = 0
+	}
+	c.next = next
+	c.tick(writeM, address, outM)
+}
+
+func (c *computerChip) Tock() {
+	c.state = c.next
+	c.tock()
+}
+
+func (c *computerChip) Eval() {}
+
+func (c *computerChip) Load(program []uint16) error {
+	if len(program) > hack.ROMSize {
+		return errors.New(fmt.Sprintf("program of %d words does not fit in rom", len(program)))
+	}
+	c.rom = [hack.ROMSize]uint16{}
+	copy(c.rom[:], program)
+	return nil
+}
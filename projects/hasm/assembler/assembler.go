@@ -0,0 +1,95 @@
+package assembler
+
+import (
+	"errors"
+	"fmt"
+	"hasm/code"
+	"hasm/parser"
+	"hasm/symbols"
+	"strings"
+)
+
+func assembleError(inst parser.Instruction, format string, args ...interface{}) error {
+	return errors.New(fmt.Sprintf("line %d: %s", inst.Line(), fmt.Sprintf(format, args...)))
+}
+
+// Assemble parses the source and assembles it into hack machine words
+func Assemble(source string) ([]uint16, error) {
+	instructions, err := parser.Parse(source)
+	if err != nil {
+		return nil, err
+	}
+	return AssembleInstructions(instructions)
+}
+
+// AssembleInstructions resolves symbols and encodes every instruction, the
+// first pass binds labels to rom addresses and the second encodes a and c
+// instructions, allocating variables as they are first seen
+func AssembleInstructions(instructions []parser.Instruction) ([]uint16, error) {
+	table := symbols.New()
+
+	// first pass, labels
+	address := 0
+	for _, inst := range instructions {
+		l, ok := inst.(*parser.LInstruction)
+		if !ok {
+			address++
+			continue
+		}
+
+		if symbols.Predefined(l.Label) {
+			return nil, assembleError(l, "label redefines predefined symbol: %s", l.Label)
+		}
+		if table.Contains(l.Label) {
+			return nil, assembleError(l, "duplicate label: %s", l.Label)
+		}
+		table.AddLabel(l.Label, address)
+	}
+
+	// second pass, encode
+	words := make([]uint16, 0, address)
+	for _, inst := range instructions {
+		switch i := inst.(type) {
+		case *parser.AInstruction:
+			value := i.Value
+			if i.Symbol != "" {
+				value = table.Address(i.Symbol)
+			}
+			words = append(words, uint16(value))
+
+		case *parser.CInstruction:
+			word, err := encodeC(i)
+			if err != nil {
+				return nil, err
+			}
+			words = append(words, word)
+		}
+	}
+
+	return words, nil
+}
+
+func encodeC(inst *parser.CInstruction) (uint16, error) {
+	c, ok := code.Comp(inst.Comp)
+	if !ok {
+		return 0, assembleError(inst, "invalid comp: %s", inst.Comp)
+	}
+	d, ok := code.Dest(inst.Dest)
+	if !ok {
+		return 0, assembleError(inst, "invalid dest: %s", inst.Dest)
+	}
+	j, ok := code.Jump(inst.Jump)
+	if !ok {
+		return 0, assembleError(inst, "invalid jump: %s", inst.Jump)
+	}
+	return code.C(c, d, j), nil
+}
+
+// Hack formats machine words as a .hack file, one 16 bit binary word per line
+func Hack(words []uint16) string {
+	var sb strings.Builder
+	for _, w := range words {
+		sb.WriteString(fmt.Sprintf("%016b\n", w))
+	}
+	return sb.String()
+}
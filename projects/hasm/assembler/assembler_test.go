@@ -0,0 +1,99 @@
+package assembler
+
+import (
+	"io/ioutil"
+	"testing"
+)
+
+func assert(t *testing.T, n string, a, b interface{}) {
+	if a != b {
+		t.Fatalf("%s : expected: %v <%T>   got: %v <%T>", n, a, a, b, b)
+	}
+}
+
+func readFile(t *testing.T, path string) string {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	return string(data)
+}
+
+func assemble(t *testing.T, path string) string {
+	words, err := Assemble(readFile(t, path))
+	if err != nil {
+		t.Fatalf("%s : %s", path, err.Error())
+	}
+	return Hack(words)
+}
+
+func TestAssembleProjects(t *testing.T) {
+	tests := []struct {
+		asm  string
+		hack string
+	}{
+		{"../../06/add/Add.asm", "../../05/Add.hack"},
+		{"../../06/max/Max.asm", "../../05/Max.hack"},
+		{"../../06/max/MaxL.asm", "../../05/Max.hack"},
+		{"../../06/rect/Rect.asm", "../../05/Rect.hack"},
+		{"../../06/rect/RectL.asm", "../../05/Rect.hack"},
+	}
+
+	for _, test := range tests {
+		assert(t, test.asm, readFile(t, test.hack), assemble(t, test.asm))
+	}
+}
+
+func TestAssemblePong(t *testing.T) {
+	// PongL is Pong with every symbol already resolved
+	assert(t, "Pong", assemble(t, "../../06/pong/PongL.asm"), assemble(t, "../../06/pong/Pong.asm"))
+}
+
+func TestAssembleSymbols(t *testing.T) {
+	input := `
+		@i      // 16
+		M=1
+		(LOOP)
+		@sum    // 17
+		@LOOP   // 2
+		0;JMP
+		@i
+		@SCREEN
+		@R15
+		(END)
+		@END    // 8
+	`
+
+	expected := []uint16{16, 0b1110111111001000, 17, 2, 0b1110101010000111, 16, 16384, 15, 8}
+
+	words, err := Assemble(input)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	assert(t, "Symbols", len(expected), len(words))
+	for i := range expected {
+		assert(t, "Symbols", expected[i], words[i])
+	}
+}
+
+func TestAssembleErrors(t *testing.T) {
+	tests := []string{
+		"@32768",
+		"@1x",
+		"D=Q",
+		"X=D",
+		"0;JMPS",
+		"(LOOP",
+		"(LOOP)\n(LOOP)",
+		"(SP)",
+		"=D",
+		"D;",
+	}
+
+	for _, test := range tests {
+		if _, err := Assemble(test); err == nil {
+			t.Errorf("expected error assembling: %s", test)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package code
+
+// binary fields of a c instruction: 111a cccc ccdd djjj
+
+var dest = map[string]uint16{
+	"":    0b000,
+	"M":   0b001,
+	"D":   0b010,
+	"MD":  0b011,
+	"A":   0b100,
+	"AM":  0b101,
+	"AD":  0b110,
+	"AMD": 0b111,
+}
+
+var jump = map[string]uint16{
+	"":    0b000,
+	"JGT": 0b001,
+	"JEQ": 0b010,
+	"JGE": 0b011,
+	"JLT": 0b100,
+	"JNE": 0b101,
+	"JLE": 0b110,
+	"JMP": 0b111,
+}
+
+// comp includes the a bit, a=1 reads M instead of A
+var comp = map[string]uint16{
+	"0":   0b0101010,
+	"1":   0b0111111,
+	"-1":  0b0111010,
+	"D":   0b0001100,
+	"A":   0b0110000,
+	"M":   0b1110000,
+	"!D":  0b0001101,
+	"!A":  0b0110001,
+	"!M":  0b1110001,
+	"-D":  0b0001111,
+	"-A":  0b0110011,
+	"-M":  0b1110011,
+	"D+1": 0b0011111,
+	"A+1": 0b0110111,
+	"M+1": 0b1110111,
+	"D-1": 0b0001110,
+	"A-1": 0b0110010,
+	"M-1": 0b1110010,
+	"D+A": 0b0000010,
+	"D+M": 0b1000010,
+	"D-A": 0b0010011,
+	"D-M": 0b1010011,
+	"A-D": 0b0000111,
+	"M-D": 0b1000111,
+	"D&A": 0b0000000,
+	"D&M": 0b1000000,
+	"D|A": 0b0010101,
+	"D|M": 0b1010101,
+}
+
+// Dest returns the 3 dest bits of a mnemonic
+func Dest(s string) (uint16, bool) {
+	d, ok := dest[s]
+	return d, ok
+}
+
+// Jump returns the 3 jump bits of a mnemonic
+func Jump(s string) (uint16, bool) {
+	j, ok := jump[s]
+	return j, ok
+}
+
+// Comp returns the a bit and 6 comp bits of a mnemonic
+func Comp(s string) (uint16, bool) {
+	c, ok := comp[s]
+	return c, ok
+}
+
+// C builds a full c instruction word
+func C(c, d, j uint16) uint16 {
+	return 0b111<<13 | c<<6 | d<<3 | j
+}
@@ -0,0 +1,3 @@
+module hasm
+
+go 1.17
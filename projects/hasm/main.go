@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"hasm/assembler"
+	"io/fs"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+func main() {
+
+	// check args
+	if len(os.Args) != 2 {
+		fmt.Println("Error: No file name provided")
+		fmt.Println("useage: hasm <path>")
+		os.Exit(2)
+	}
+
+	path := os.Args[1]
+
+	if isFile(path) {
+		if !checkExt(path) {
+			fmt.Printf("Invalid file type, expected: '.asm', got: '%v'\n", filepath.Ext(path))
+			os.Exit(2)
+		}
+
+		if err := assembleFile(path); err != nil {
+			fmt.Printf("Error: assembling %v\n", path)
+			fmt.Println(err.Error())
+			os.Exit(1)
+		}
+
+	} else if isDir(path) {
+
+		if err := assembleDir(path); err != nil {
+			os.Exit(1)
+		}
+
+	} else {
+		fmt.Printf("Error: could not find file: %v\n", path)
+		os.Exit(2)
+	}
+}
+
+func removeExt(file string) string {
+	ext := filepath.Ext(file)
+	return file[0 : len(file)-len(ext)]
+}
+
+func replaceExt(file, newExt string) string {
+	return removeExt(file) + newExt
+}
+
+func checkExt(file string) bool {
+	return filepath.Ext(file) == ".asm"
+}
+
+func writeFile(filePath, data string) {
+	message := []byte(data)
+	err := ioutil.WriteFile(filePath, message, fs.ModePerm)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func isFile(filename string) bool {
+	info, err := os.Stat(filename)
+	if os.IsNotExist(err) {
+		return false
+	}
+	return !info.IsDir()
+}
+
+func isDir(filename string) bool {
+	info, err := os.Stat(filename)
+	if os.IsNotExist(err) {
+		return false
+	}
+	return info.IsDir()
+}
+
+func assembleFile(path string) error {
+	fileOutPath := replaceExt(path, ".hack")
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	words, err := assembler.Assemble(string(data))
+	if err != nil {
+		return err
+	}
+
+	writeFile(fileOutPath, assembler.Hack(words))
+	fmt.Println("Success!!")
+	fmt.Printf("output file: %v\n", fileOutPath)
+
+	return nil
+}
+
+// assembleDir assembles every .asm file in a directory, carrying on past
+// bad files so every error is reported
+func assembleDir(dir string) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.asm"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var failed error
+	for _, file := range files {
+		if err := assembleFile(file); err != nil {
+			fmt.Printf("Error: assembling %v\n", file)
+			fmt.Println(err.Error())
+			failed = err
+		}
+	}
+
+	return failed
+}
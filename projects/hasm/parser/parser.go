@@ -0,0 +1,164 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Instruction is a single line of hack assembly
+type Instruction interface {
+	// Line is the source line the instruction was read from
+	Line() int
+	String() string
+}
+
+// AInstruction => @<value | symbol>
+type AInstruction struct {
+	line   int
+	Value  int
+	Symbol string
+}
+
+func (a *AInstruction) Line() int { return a.line }
+func (a *AInstruction) String() string {
+	if a.Symbol != "" {
+		return "@" + a.Symbol
+	}
+	return "@" + strconv.Itoa(a.Value)
+}
+
+// CInstruction => <dest>=<comp>;<jump>, dest and jump are optional
+type CInstruction struct {
+	line int
+	Dest string
+	Comp string
+	Jump string
+}
+
+func (c *CInstruction) Line() int { return c.line }
+func (c *CInstruction) String() string {
+	s := c.Comp
+	if c.Dest != "" {
+		s = c.Dest + "=" + s
+	}
+	if c.Jump != "" {
+		s = s + ";" + c.Jump
+	}
+	return s
+}
+
+// LInstruction => (<label>), a pseudo instruction naming the next address
+type LInstruction struct {
+	line  int
+	Label string
+}
+
+func (l *LInstruction) Line() int      { return l.line }
+func (l *LInstruction) String() string { return "(" + l.Label + ")" }
+
+// a symbol is letters, digits, _ . $ and : not starting with a digit
+var symbolRe = regexp.MustCompile(`^[A-Za-z_.$:][A-Za-z0-9_.$:]*$`)
+
+// MaxValue is the largest constant an a instruction can load
+const MaxValue = 1<<15 - 1
+
+func parseError(line int, format string, args ...interface{}) error {
+	return errors.New(fmt.Sprintf("line %d: %s", line, fmt.Sprintf(format, args...)))
+}
+
+// Parse reads every instruction in the source, comments and blank lines
+// are dropped
+func Parse(source string) ([]Instruction, error) {
+	var instructions []Instruction
+
+	for i, line := range strings.Split(source, "\n") {
+		inst, err := ParseLine(line, i+1)
+		if err != nil {
+			return nil, err
+		}
+		if inst != nil {
+			instructions = append(instructions, inst)
+		}
+	}
+
+	return instructions, nil
+}
+
+// ParseLine parses a single line, returning nil for blank or comment lines
+func ParseLine(line string, n int) (Instruction, error) {
+	// strip comments and all whitespace
+	if i := strings.Index(line, "//"); i >= 0 {
+		line = line[:i]
+	}
+	line = strings.Join(strings.Fields(line), "")
+
+	switch {
+	case line == "":
+		return nil, nil
+
+	case strings.HasPrefix(line, "@"):
+		return parseA(line[1:], n)
+
+	case strings.HasPrefix(line, "("):
+		if !strings.HasSuffix(line, ")") {
+			return nil, parseError(n, "unterminated label: %s", line)
+		}
+		label := line[1 : len(line)-1]
+		if !symbolRe.MatchString(label) {
+			return nil, parseError(n, "invalid label: %s", label)
+		}
+		return &LInstruction{line: n, Label: label}, nil
+
+	default:
+		return parseC(line, n)
+	}
+}
+
+func parseA(value string, n int) (Instruction, error) {
+	if value == "" {
+		return nil, parseError(n, "missing value after @")
+	}
+
+	if value[0] >= '0' && value[0] <= '9' {
+		v, err := strconv.Atoi(value)
+		if err != nil || v > MaxValue {
+			return nil, parseError(n, "invalid constant: %s, expected 0..%d", value, MaxValue)
+		}
+		return &AInstruction{line: n, Value: v}, nil
+	}
+
+	if !symbolRe.MatchString(value) {
+		return nil, parseError(n, "invalid symbol: %s", value)
+	}
+	return &AInstruction{line: n, Symbol: value}, nil
+}
+
+func parseC(line string, n int) (Instruction, error) {
+	c := &CInstruction{line: n}
+
+	if i := strings.Index(line, "="); i >= 0 {
+		c.Dest = line[:i]
+		line = line[i+1:]
+		if c.Dest == "" {
+			return nil, parseError(n, "missing dest before =")
+		}
+	}
+
+	if i := strings.Index(line, ";"); i >= 0 {
+		c.Jump = line[i+1:]
+		line = line[:i]
+		if c.Jump == "" {
+			return nil, parseError(n, "missing jump after ;")
+		}
+	}
+
+	if line == "" {
+		return nil, parseError(n, "missing comp")
+	}
+	c.Comp = line
+
+	return c, nil
+}
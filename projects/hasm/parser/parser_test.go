@@ -0,0 +1,62 @@
+package parser
+
+import "testing"
+
+func assert(t *testing.T, n string, a, b interface{}) {
+	if a != b {
+		t.Fatalf("%s : expected: %v <%T>   got: %v <%T>", n, a, a, b, b)
+	}
+}
+
+func TestParseLine(t *testing.T) {
+	tests := []struct {
+		input string
+		exp   string
+	}{
+		{"@21", "@21"},
+		{"  @R0   // comment", "@R0"},
+		{"@ponggame.0", "@ponggame.0"},
+		{"(math.multiply$if_true0)", "(math.multiply$if_true0)"},
+		{"D=M", "D=M"},
+		{"AM = M - 1", "AM=M-1"},
+		{"D;JGT", "D;JGT"},
+		{"0;JMP", "0;JMP"},
+		{"MD=D+1;JNE", "MD=D+1;JNE"},
+	}
+
+	for _, test := range tests {
+		inst, err := ParseLine(test.input, 1)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		assert(t, test.input, test.exp, inst.String())
+	}
+}
+
+func TestParseSkipsBlankLines(t *testing.T) {
+	instructions, err := Parse("// header\n\n   @2\r\n   D=A // two\n\n(END)\n")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	assert(t, "Parse", 3, len(instructions))
+	assert(t, "Parse", 3, instructions[0].Line())
+	assert(t, "Parse", 4, instructions[1].Line())
+	assert(t, "Parse", "END", instructions[2].(*LInstruction).Label)
+}
+
+func TestParseLineErrors(t *testing.T) {
+	tests := []string{
+		"@1abc",
+		"@is-eq-1",
+		"(is-eq-1)",
+		"(END",
+		"@32768",
+	}
+
+	for _, input := range tests {
+		if _, err := ParseLine(input, 1); err == nil {
+			t.Errorf("%s : expected an error", input)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package symbols
+
+import "strconv"
+
+// VariableBase is the first ram address handed out to variables
+const VariableBase = 16
+
+var predefined = map[string]int{
+	"SP":     0,
+	"LCL":    1,
+	"ARG":    2,
+	"THIS":   3,
+	"THAT":   4,
+	"SCREEN": 16384,
+	"KBD":    24576,
+}
+
+func init() {
+	for i := 0; i < 16; i++ {
+		predefined["R"+strconv.Itoa(i)] = i
+	}
+}
+
+// Table maps symbols to addresses, labels point into rom and variables are
+// allocated in ram from VariableBase as they are first seen
+type Table struct {
+	symbols map[string]int
+	next    int
+}
+
+func New() *Table {
+	t := &Table{symbols: map[string]int{}, next: VariableBase}
+	for k, v := range predefined {
+		t.symbols[k] = v
+	}
+	return t
+}
+
+// Predefined reports whether a name is one of the built in symbols
+func Predefined(name string) bool {
+	_, ok := predefined[name]
+	return ok
+}
+
+// Contains reports whether a name is already in the table
+func (t *Table) Contains(name string) bool {
+	_, ok := t.symbols[name]
+	return ok
+}
+
+// AddLabel binds a label to a rom address
+func (t *Table) AddLabel(name string, address int) {
+	t.symbols[name] = address
+}
+
+// Address returns the address of a symbol, allocating the next free ram
+// address if it has not been seen before
+func (t *Table) Address(name string) int {
+	if a, ok := t.symbols[name]; ok {
+		return a
+	}
+	a := t.next
+	t.symbols[name] = a
+	t.next++
+	return a
+}
@@ -10,6 +10,7 @@ import (
 type Node interface {
 	TokenLiteral() string
 	String() string
+	Pos() token.Pos
 }
 
 
@@ -19,33 +20,6 @@ type ExpressionNode interface {
 	Expression()
 }
 
-// Expression is a tree like structure to denote any combination of expressions
-type Expression struct {
-	Term ExpressionNode
-	Tail ExpressionNode
-	Op token.Token
-}
-
-func (e *Expression) TokenLiteral() string { return e.Term.TokenLiteral() }
-func (e *Expression) Expression(){}
-func (e *Expression) String() string {
-	var sb strings.Builder
-
-	sb.WriteString("(")
-	if e.Op != (token.Token{}) {
-		sb.WriteString(e.Op.Literal)
-	}
-	sb.WriteString(e.Term.String())
-
-	if e.Tail != nil {
-		sb.WriteString(" ")
-		sb.WriteString(e.Tail.String())
-	}
-	sb.WriteString(")")
-
-	return sb.String()
-}
-
 // Identifier denotes the name of a var or function
 type Identifier struct {
 	Token token.Token
@@ -53,6 +27,7 @@ type Identifier struct {
 }
 
 func (i *Identifier) TokenLiteral() string { return i.Token.Literal }
+func (i *Identifier) Pos() token.Pos { return i.Token.Pos }
 func (i *Identifier) String() string { return i.Name }
 func (i *Identifier) Expression() { }
 
@@ -64,6 +39,7 @@ type IndexIdentifier struct {
 }
 
 func (ii *IndexIdentifier) TokenLiteral() string { return ii.Token.Literal }
+func (ii *IndexIdentifier) Pos() token.Pos { return ii.Token.Pos }
 func (ii *IndexIdentifier) String() string { 
 	return fmt.Sprintf("%s[%s]", ii.Name, ii.Index.String())
 }
@@ -76,6 +52,7 @@ type StringLiteral struct {
 }
 
 func (sl *StringLiteral) TokenLiteral() string { return sl.Token.Literal }
+func (sl *StringLiteral) Pos() token.Pos { return sl.Token.Pos }
 func (sl *StringLiteral) String() string { return sl.Value }
 func (sl *StringLiteral) Expression() { }
 
@@ -86,6 +63,7 @@ type IntLiteral struct {
 }
 
 func (il *IntLiteral) TokenLiteral() string { return il.Token.Literal }
+func (il *IntLiteral) Pos() token.Pos { return il.Token.Pos }
 func (il *IntLiteral) String() string { return il.Token.Literal }
 func (il *IntLiteral) Expression() { }
 
@@ -97,6 +75,7 @@ type KeywordConstant struct {
 }
 
 func (kc *KeywordConstant) TokenLiteral() string { return kc.Token.Literal }
+func (kc *KeywordConstant) Pos() token.Pos { return kc.Token.Pos }
 func (kc *KeywordConstant) Expression(){}
 func (kc *KeywordConstant) String() string { return kc.Value }
 
@@ -109,6 +88,7 @@ type SubroutineCall struct {
 }
 
 func (sc *SubroutineCall) TokenLiteral() string { return sc.Token.Literal }
+func (sc *SubroutineCall) Pos() token.Pos { return sc.Token.Pos }
 func (sc *SubroutineCall) Expression(){}
 func (sc *SubroutineCall) String() string {
 	var sb strings.Builder
@@ -132,6 +112,22 @@ func (sc *SubroutineCall) String() string {
 	return sb.String()
 }
 
+// BinaryExpression => <left> <op> <right>
+type BinaryExpression struct {
+	Token token.Token
+	Left ExpressionNode
+	Op token.Token
+	Right ExpressionNode
+}
+
+func (be *BinaryExpression) TokenLiteral() string { return be.Token.Literal }
+func (be *BinaryExpression) Pos() token.Pos { return be.Left.Pos() }
+func (be *BinaryExpression) Expression() {}
+func (be *BinaryExpression) String() string {
+	return "(" + be.Left.String() + " " + be.Op.Literal + " " + be.Right.String() + ")"
+}
+
+// UnaryExpression => <- | ~> <term>
 type UnaryExpression struct {
 	Token token.Token
 	Prefix token.Token
@@ -139,17 +135,20 @@ type UnaryExpression struct {
 }
 
 func (ue *UnaryExpression) TokenLiteral() string { return ue.Token.Literal }
+func (ue *UnaryExpression) Pos() token.Pos { return ue.Token.Pos }
 func (ue *UnaryExpression) Expression() {}
 func (ue *UnaryExpression) String() string {
 	return ue.Prefix.Literal + ue.Term.String()
 }
 
+// ParenExpression => ( <expression> )
 type ParenExpression struct {
 	Token token.Token
 	Term ExpressionNode
 }
 
 func (pe *ParenExpression) TokenLiteral() string { return pe.Token.Literal }
+func (pe *ParenExpression) Pos() token.Pos { return pe.Token.Pos }
 func (pe *ParenExpression) Expression() {}
 func (pe *ParenExpression) String() string { return "(" + pe.Term.String() + ")"}	
 
@@ -175,6 +174,10 @@ func (td *TypeDeclaration) TokenLiteral() string {
 	return td.Token.Literal
 }
 
+func (td *TypeDeclaration) Pos() token.Pos {
+	return td.Token.Pos
+}
+
 func (td *TypeDeclaration) String() string {
 	var sb strings.Builder
 	sb.WriteString(td.Declaration.Literal)
@@ -202,6 +205,10 @@ func (pd *ParamDeclaration) TokenLiteral() string {
 	return pd.Token.Literal
 }
 
+func (pd *ParamDeclaration) Pos() token.Pos {
+	return pd.Token.Pos
+}
+
 func (pd *ParamDeclaration) String() string {
 	var sb strings.Builder
 	sb.WriteString(pd.Type.Literal)
@@ -217,6 +224,10 @@ type SubroutineDeclaration struct {
 	Name *Identifier
 	Parameters []*ParamDeclaration
 	Body []StatementNode
+	// End is the position of the closing brace of the body
+	End token.Pos
+	// Doc is the text of the doc comment before the declaration
+	Doc string
 }
 
 func (sd *SubroutineDeclaration) Statement() {}
@@ -224,6 +235,10 @@ func (sd *SubroutineDeclaration) TokenLiteral() string {
 	return sd.Token.Literal
 }
 
+func (sd *SubroutineDeclaration) Pos() token.Pos {
+	return sd.Token.Pos
+}
+
 func (sd *SubroutineDeclaration) String() string {
 	var sb strings.Builder
 	sb.WriteString(sd.Decelration.Literal)
@@ -253,6 +268,10 @@ type ClassDeclaration struct {
 	Token token.Token
 	Name *Identifier
 	Body []StatementNode
+	// End is the position of the closing brace of the class
+	End token.Pos
+	// Doc is the text of the doc comment before the class
+	Doc string
 }
 
 func (cd *ClassDeclaration) Statement(){}
@@ -260,6 +279,10 @@ func (cd *ClassDeclaration) TokenLiteral() string {
 	return cd.Token.Literal
 }
 
+func (cd *ClassDeclaration) Pos() token.Pos {
+	return cd.Token.Pos
+}
+
 func (cd *ClassDeclaration) String() string {
 	var sb strings.Builder
 
@@ -291,6 +314,10 @@ func (ls *LetStatement) TokenLiteral() string {
 	return ls.Token.Literal
 }
 
+func (ls *LetStatement) Pos() token.Pos {
+	return ls.Token.Pos
+}
+
 func (ls *LetStatement) String() string {
 	var sb strings.Builder
 	sb.WriteString(ls.Token.Literal)
@@ -315,6 +342,10 @@ func (rs *ReturnStatement) TokenLiteral() string {
 	return rs.Token.Literal
 }
 
+func (rs *ReturnStatement) Pos() token.Pos {
+	return rs.Token.Pos
+}
+
 func (rs *ReturnStatement) String() string {
 	if rs.Value == nil {
 		return fmt.Sprintf("%s;\n", rs.TokenLiteral())
@@ -330,6 +361,7 @@ type DoStatement struct {
 
 func (ds *DoStatement) Statement(){}
 func (ds *DoStatement) TokenLiteral() string { return ds.Token.Literal }
+func (ds *DoStatement) Pos() token.Pos { return ds.Token.Pos }
 
 func (ds *DoStatement) String() string {
 	return fmt.Sprintf("%s %s;\n", ds.TokenLiteral(), ds.Expression.String())
@@ -341,10 +373,12 @@ type WhileStatement struct {
 	Token token.Token
 	Expression ExpressionNode
 	Statements []StatementNode
+	End token.Pos
 }
 
 func (ws *WhileStatement) Statement(){}
 func (ws *WhileStatement) TokenLiteral() string { return ws.Token.Literal }
+func (ws *WhileStatement) Pos() token.Pos { return ws.Token.Pos }
 
 func (ws *WhileStatement) String() string {
 	var sb strings.Builder
@@ -367,11 +401,15 @@ type IfStatement struct {
 	Token token.Token
 	Expression ExpressionNode
 	Statements []StatementNode
+	End token.Pos
+	Else token.Token
 	ElseStatements []StatementNode
+	ElseEnd token.Pos
 }
 
 func (is *IfStatement) Statement() {}
 func (is *IfStatement) TokenLiteral() string { return is.Token.Literal }
+func (is *IfStatement) Pos() token.Pos { return is.Token.Pos }
 
 func (is *IfStatement) String() string {
 	var sb strings.Builder
@@ -0,0 +1,341 @@
+// Package check finds the mistakes in a jack program that compile but fail
+// once it runs: variables that are not declared, classes and subroutines
+// that do not exist, calls passing the wrong number of arguments, methods
+// called and fields used without an object and returns that do not match
+// their subroutine.
+// The classes of a program are checked together so calls between them can
+// be resolved.
+package check
+
+import (
+	"errors"
+	"fmt"
+	"jack/ast"
+	"jack/symbols"
+	"jack/token"
+	"strings"
+)
+
+// Subroutine is what the checker knows of a subroutine
+type Subroutine struct {
+	// Kind is token.CONSTRUCTOR, token.FUNCTION or token.METHOD
+	Kind       token.Type
+	ReturnType string
+	// Params holds the type of each parameter
+	Params []string
+}
+
+// Class is a class and its subroutines by name
+type Class struct {
+	Name        string
+	Subroutines map[string]*Subroutine
+}
+
+// Diagnostics holds every problem found, in the order of the classes
+type Diagnostics []error
+
+func (ds Diagnostics) Error() string {
+	msgs := make([]string, len(ds))
+	for i, d := range ds {
+		msgs[i] = d.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+type Options struct {
+	// External holds the classes defined outside the program, such as the
+	// os, that may be used without being defined
+	External map[string]*Class
+}
+
+// Check validates the classes of a program. It returns nil or the
+// Diagnostics found.
+func Check(classes []*ast.ClassDeclaration, opts Options) error {
+	c := checker{classes: map[string]*Class{}, external: opts.External}
+
+	for _, class := range classes {
+		c.declare(class)
+	}
+	for _, class := range classes {
+		c.checkClass(class)
+	}
+
+	if len(c.diags) > 0 {
+		return c.diags
+	}
+	return nil
+}
+
+type checker struct {
+	classes  map[string]*Class
+	external map[string]*Class
+	diags    Diagnostics
+
+	// the class and subroutine being checked
+	class   *Class
+	sub     *ast.SubroutineDeclaration
+	symbols *symbols.Table
+}
+
+func (c *checker) errorf(pos token.Pos, format string, args ...interface{}) {
+	c.diags = append(c.diags, errors.New(fmt.Sprintf("%s: %s", pos, fmt.Sprintf(format, args...))))
+}
+
+// lookup finds a class of the program or, failing that, an external class
+func (c *checker) lookup(name string) (*Class, bool) {
+	if class, ok := c.classes[name]; ok {
+		return class, true
+	}
+	class, ok := c.external[name]
+	return class, ok
+}
+
+// declare adds the signatures of a class to the class table
+func (c *checker) declare(class *ast.ClassDeclaration) {
+	name := class.Name.Name
+	if _, ok := c.classes[name]; ok {
+		c.errorf(class.Name.Pos(), "class %s is already defined", name)
+		return
+	}
+
+	t := &Class{Name: name, Subroutines: map[string]*Subroutine{}}
+	for _, stmt := range class.Body {
+		sd, ok := stmt.(*ast.SubroutineDeclaration)
+		if !ok {
+			continue
+		}
+		if _, ok := t.Subroutines[sd.Name.Name]; ok {
+			c.errorf(sd.Name.Pos(), "%s.%s is already defined", name, sd.Name.Name)
+			continue
+		}
+
+		s := &Subroutine{Kind: sd.Decelration.Type, ReturnType: sd.ReturnType.Literal}
+		for _, param := range sd.Parameters {
+			s.Params = append(s.Params, param.Type.Literal)
+		}
+		t.Subroutines[sd.Name.Name] = s
+	}
+	c.classes[name] = t
+}
+
+// builtin holds the types that are not classes
+var builtin = map[string]bool{"int": true, "char": true, "boolean": true}
+
+// typ checks that a declared type is a builtin type or a known class
+func (c *checker) typ(tok token.Token) {
+	if tok.Type != token.IDENT {
+		return
+	}
+	if _, ok := c.lookup(tok.Literal); !ok {
+		c.errorf(tok.Pos, "unknown class %s", tok.Literal)
+	}
+}
+
+func (c *checker) checkClass(class *ast.ClassDeclaration) {
+	c.class = c.classes[class.Name.Name]
+
+	classTable, err := symbols.Class(class)
+	if err != nil {
+		c.diags = append(c.diags, err)
+		return
+	}
+
+	for _, stmt := range class.Body {
+		switch s := stmt.(type) {
+		case *ast.TypeDeclaration:
+			c.typ(s.Type)
+		case *ast.SubroutineDeclaration:
+			c.subroutine(classTable, class, s)
+		}
+	}
+}
+
+func (c *checker) subroutine(classTable *symbols.Table, class *ast.ClassDeclaration, sd *ast.SubroutineDeclaration) {
+	c.sub = sd
+	c.typ(sd.ReturnType)
+	for _, param := range sd.Parameters {
+		c.typ(param.Type)
+	}
+
+	var err error
+	if c.symbols, err = symbols.Subroutine(classTable, class.Name.Name, sd); err != nil {
+		c.diags = append(c.diags, err)
+		return
+	}
+
+	c.statements(sd.Body)
+
+	if !returns(sd.Body) {
+		c.errorf(sd.End, "missing return at end of %s", c.name())
+	}
+}
+
+// name is the full name of the subroutine being checked
+func (c *checker) name() string {
+	return c.class.Name + "." + c.sub.Name.Name
+}
+
+// returns reports whether a block always ends in a return statement
+func returns(stmts []ast.StatementNode) bool {
+	if len(stmts) == 0 {
+		return false
+	}
+	switch s := stmts[len(stmts)-1].(type) {
+	case *ast.ReturnStatement:
+		return true
+	case *ast.IfStatement:
+		return s.Else.Type == token.ELSE && returns(s.Statements) && returns(s.ElseStatements)
+	}
+	return false
+}
+
+func (c *checker) statements(stmts []ast.StatementNode) {
+	for _, stmt := range stmts {
+		c.statement(stmt)
+	}
+}
+
+func (c *checker) statement(stmt ast.StatementNode) {
+	switch s := stmt.(type) {
+	case *ast.TypeDeclaration:
+		c.typ(s.Type)
+
+	case *ast.LetStatement:
+		c.expression(s.Name)
+		c.expression(s.Value)
+
+	case *ast.DoStatement:
+		c.expression(s.Expression)
+
+	case *ast.ReturnStatement:
+		void := c.sub.ReturnType.Type == token.VOID
+		if s.Value != nil {
+			c.expression(s.Value)
+			if void {
+				c.errorf(s.Pos(), "void %s returns a value", c.name())
+			}
+		} else if !void {
+			c.errorf(s.Pos(), "%s must return %s", c.name(), article(c.sub.ReturnType.Literal))
+		}
+
+	case *ast.WhileStatement:
+		c.expression(s.Expression)
+		c.statements(s.Statements)
+
+	case *ast.IfStatement:
+		c.expression(s.Expression)
+		c.statements(s.Statements)
+		c.statements(s.ElseStatements)
+	}
+}
+
+// article puts a or an before a type name
+func article(typ string) string {
+	if strings.ContainsRune("aeiouAEIOU", rune(typ[0])) {
+		return "an " + typ
+	}
+	return "a " + typ
+}
+
+// function reports whether the subroutine being checked is a function,
+// which has no current object for fields and this to refer to
+func (c *checker) function() bool {
+	return c.sub.Decelration.Type == token.FUNCTION
+}
+
+func (c *checker) variable(pos token.Pos, name string) {
+	v, ok := c.symbols.Lookup(name)
+	if !ok {
+		c.errorf(pos, "undeclared variable: %s", name)
+		return
+	}
+	if v.Kind == symbols.FIELD && c.function() {
+		c.errorf(pos, "field %s used in function %s", name, c.name())
+	}
+}
+
+func (c *checker) expression(exp ast.ExpressionNode) {
+	switch e := exp.(type) {
+	case *ast.BinaryExpression:
+		c.expression(e.Left)
+		c.expression(e.Right)
+	case *ast.UnaryExpression:
+		c.expression(e.Term)
+	case *ast.ParenExpression:
+		c.expression(e.Term)
+	case *ast.KeywordConstant:
+		if e.Token.Type == token.THIS && c.function() {
+			c.errorf(e.Pos(), "this used in function %s", c.name())
+		}
+	case *ast.Identifier:
+		c.variable(e.Pos(), e.Name)
+	case *ast.IndexIdentifier:
+		c.variable(e.Pos(), e.Name)
+		c.expression(e.Index)
+	case *ast.SubroutineCall:
+		c.call(e)
+		for _, arg := range e.Arguments {
+			c.expression(arg)
+		}
+	}
+}
+
+// call checks that a call names a subroutine that exists, on an object when
+// it is a method, with the number of arguments it declares
+func (c *checker) call(sc *ast.SubroutineCall) {
+	var class *Class
+	// object is set when the call passes an object to a method
+	object := true
+
+	switch {
+	case sc.Class == nil:
+		// a call on the current object
+		class = c.class
+		if c.function() {
+			if s, ok := class.Subroutines[sc.Name.Name]; ok && s.Kind == token.METHOD {
+				c.errorf(sc.Pos(), "method %s.%s called from function %s without an object", class.Name, sc.Name.Name, c.name())
+				return
+			}
+		}
+
+	default:
+		if v, ok := c.symbols.Lookup(sc.Class.Name); ok {
+			// a call on an object stored in a variable
+			c.variable(sc.Class.Pos(), v.Name)
+			if class, ok = c.lookup(v.Type); !ok {
+				// a class that does not exist is reported where it is used
+				// as a type
+				if builtin[v.Type] {
+					c.errorf(sc.Pos(), "%s is %s, not an object", v.Name, article(v.Type))
+				}
+				return
+			}
+		} else if class, ok = c.lookup(sc.Class.Name); ok {
+			object = false
+		} else {
+			c.errorf(sc.Pos(), "unknown class %s", sc.Class.Name)
+			return
+		}
+	}
+
+	s, ok := class.Subroutines[sc.Name.Name]
+	if !ok {
+		c.errorf(sc.Name.Pos(), "undefined subroutine %s.%s", class.Name, sc.Name.Name)
+		return
+	}
+
+	name := class.Name + "." + sc.Name.Name
+	kind := strings.ToLower(string(s.Kind))
+	switch {
+	case s.Kind == token.METHOD && !object:
+		c.errorf(sc.Pos(), "method %s called without an object", name)
+	case s.Kind != token.METHOD && sc.Class == nil:
+		c.errorf(sc.Pos(), "%s %s must be called as %s", kind, name, name)
+	case s.Kind != token.METHOD && object:
+		c.errorf(sc.Pos(), "%s %s called on an object", kind, name)
+	}
+
+	if len(sc.Arguments) != len(s.Params) {
+		c.errorf(sc.Pos(), "%s called with %d argument(s), expects %d", name, len(sc.Arguments), len(s.Params))
+	}
+}
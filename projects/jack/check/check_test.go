@@ -0,0 +1,155 @@
+package check
+
+import (
+	"io/ioutil"
+	"jack/ast"
+	"jack/lexer"
+	"jack/parser"
+	"path/filepath"
+	"testing"
+)
+
+func parse(t *testing.T, file, input string) *ast.ClassDeclaration {
+	class, err := parser.New(lexer.NewFile(file, input)).ParseClass()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return class
+}
+
+func TestCheck(t *testing.T) {
+	main := `class Main {
+	field int f;
+	field Foo g;
+
+	function void main() {
+		var Foo foo;
+		var Bar bar;
+		var int n;
+		let foo = Foo.new(1);
+		do foo.get(2);
+		do Foo.get();
+		do get();
+		do Foo.make();
+		do Output.printInt(x);
+		do n.get();
+		do Baz.new();
+		let n = f;
+		let foo = this;
+		do g.get();
+		return 1;
+	}
+
+	method int get() {
+		if (true) { return 1; } else { return; }
+	}
+
+	function int loop() {
+		while (true) { return 1; }
+	}
+}`
+	foo := `class Foo {
+	constructor Foo new(int n) { return this; }
+	method int get() { return 0; }
+	method void put() { do get(); do new(1); return; }
+}`
+
+	classes := []*ast.ClassDeclaration{parse(t, "Main.jack", main), parse(t, "Foo.jack", foo)}
+	err := Check(classes, Options{External: OS})
+
+	ds, ok := err.(Diagnostics)
+	if !ok {
+		t.Fatalf("expected: Diagnostics, got: %T %v", err, err)
+	}
+
+	expected := []string{
+		"Main.jack:7:7: unknown class Bar",
+		"Main.jack:10:6: Foo.get called with 1 argument(s), expects 0",
+		"Main.jack:11:6: method Foo.get called without an object",
+		"Main.jack:12:6: method Main.get called from function Main.main without an object",
+		"Main.jack:13:10: undefined subroutine Foo.make",
+		"Main.jack:14:22: undeclared variable: x",
+		"Main.jack:15:6: n is an int, not an object",
+		"Main.jack:16:6: unknown class Baz",
+		"Main.jack:17:11: field f used in function Main.main",
+		"Main.jack:18:13: this used in function Main.main",
+		"Main.jack:19:6: field g used in function Main.main",
+		"Main.jack:20:3: void Main.main returns a value",
+		"Main.jack:24:34: Main.get must return an int",
+		"Main.jack:29:2: missing return at end of Main.loop",
+		"Foo.jack:4:35: constructor Foo.new must be called as Foo.new",
+	}
+
+	if len(ds) != len(expected) {
+		t.Fatalf("expected %d diagnostics, got: %d\n%v", len(expected), len(ds), err)
+	}
+	for i := range expected {
+		if ds[i].Error() != expected[i] {
+			t.Errorf("expected: %v, got: %v", expected[i], ds[i].Error())
+		}
+	}
+}
+
+func TestCheck_Defined(t *testing.T) {
+	classes := []*ast.ClassDeclaration{
+		parse(t, "Main.jack", `class Main {
+	function void main() { return; }
+	function void main() { return; }
+}`),
+		parse(t, "Other.jack", `class Main { }`),
+	}
+
+	expected := "Main.jack:3:16: Main.main is already defined\nOther.jack:1:7: class Main is already defined"
+	if err := Check(classes, Options{}); err == nil || err.Error() != expected {
+		t.Errorf("expected: %v, got: %v", expected, err)
+	}
+}
+
+func TestCheck_External(t *testing.T) {
+	classes := []*ast.ClassDeclaration{parse(t, "Main.jack", `class Main {
+	function void main() {
+		var String s;
+		let s = String.new(5);
+		do s.appendChar(65);
+		do Output.printString(s);
+		return;
+	}
+}`)}
+
+	if err := Check(classes, Options{External: OS}); err != nil {
+		t.Errorf("expected no diagnostics, got:\n%v", err)
+	}
+
+	// the os is only known when asked for
+	if err := Check(classes, Options{}); err == nil {
+		t.Errorf("expected String to be unknown")
+	}
+}
+
+// the programs of the projects have no problems
+func TestCheck_Projects(t *testing.T) {
+	dirs, err := filepath.Glob("../../11/*")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	for _, dir := range dirs {
+		files, err := filepath.Glob(filepath.Join(dir, "*.jack"))
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+
+		var classes []*ast.ClassDeclaration
+		for _, file := range files {
+			data, err := ioutil.ReadFile(file)
+			if err != nil {
+				t.Fatalf(err.Error())
+			}
+			classes = append(classes, parse(t, file, string(data)))
+		}
+
+		if err := Check(classes, Options{External: OS}); err != nil {
+			t.Errorf("%s : %v", dir, err)
+		}
+	}
+}
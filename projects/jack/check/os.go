@@ -0,0 +1,27 @@
+package check
+
+import (
+	"jack/osapi"
+	"jack/token"
+)
+
+// OS holds the classes of the jack os, programs call them without defining
+// them unless the os is compiled with them
+var OS = external(osapi.Classes)
+
+// external turns the classes of an api into classes the checker knows
+func external(api map[string]*osapi.Class) map[string]*Class {
+	classes := map[string]*Class{}
+	for name, c := range api {
+		class := &Class{Name: name, Subroutines: map[string]*Subroutine{}}
+		for _, s := range c.Subroutines {
+			sub := &Subroutine{Kind: token.LookupIdent(s.Kind), ReturnType: s.ReturnType}
+			for _, p := range s.Params {
+				sub.Params = append(sub.Params, p.Type)
+			}
+			class.Subroutines[s.Name] = sub
+		}
+		classes[name] = class
+	}
+	return classes
+}
@@ -0,0 +1,482 @@
+package compiler
+
+import (
+	"errors"
+	"fmt"
+	"jack/ast"
+	"jack/osapi"
+	"jack/symbols"
+	"jack/token"
+	"path/filepath"
+	"strings"
+)
+
+type Compiler struct {
+	strings.Builder
+	class   string
+	symbols *symbols.Table
+	labels  int
+	os      map[string]*osapi.Class
+
+	// pos is the statement being compiled, lines the jack line of each vm
+	// line written so far
+	pos   token.Pos
+	lines []int
+}
+
+func (c *Compiler) Writeln(s string, args ...interface{}) {
+	if len(args) > 0 {
+		c.WriteString(fmt.Sprintf(s, args...))
+	} else {
+		c.WriteString(s)
+	}
+	c.WriteString("\n")
+	c.lines = append(c.lines, c.pos.Line)
+}
+
+// LineMap ties each line of compiled vm code to the jack line it came from,
+// Lines[i] is the jack line of vm line i+1
+type LineMap struct {
+	File  string `json:"file"`
+	Lines []int  `json:"lines"`
+}
+
+// Compile takes a parsed class and builds the vm code string.
+func Compile(class *ast.ClassDeclaration) (string, error) {
+	code, _, err := CompileLineMap(class)
+	return code, err
+}
+
+// CompileLineMap compiles a class like Compile and also returns the jack
+// line each vm line was compiled from
+func CompileLineMap(class *ast.ClassDeclaration) (string, *LineMap, error) {
+	return CompileWith(class, Options{})
+}
+
+type Options struct {
+	// OS holds the os classes, by name, that calls are checked against.
+	// Leave out the os classes the program defines itself.
+	OS map[string]*osapi.Class
+}
+
+// CompileWith compiles a class like CompileLineMap, checking that the os
+// subroutines it calls exist and are passed the right number of arguments
+func CompileWith(class *ast.ClassDeclaration, opts Options) (string, *LineMap, error) {
+	c := &Compiler{class: class.Name.Name, os: opts.OS}
+
+	if err := c.compileClass(class); err != nil {
+		return "", nil, err
+	}
+
+	lm := &LineMap{Lines: c.lines}
+	if file := class.Pos().File; file != "" {
+		lm.File = filepath.Base(file)
+	}
+	return c.String(), lm, nil
+}
+
+// error helpers
+func compileError(pos token.Pos, format string, args ...interface{}) error {
+	return errors.New(fmt.Sprintf("%s: %s", pos, fmt.Sprintf(format, args...)))
+}
+
+func (c *Compiler) lookup(name string) (*symbols.Symbol, bool) {
+	return c.symbols.Lookup(name)
+}
+
+func (c *Compiler) newLabel(prefix string) string {
+	return fmt.Sprintf("%s%d", prefix, c.labels)
+}
+
+// ---------------------------------------------------------------------------------
+// Declarations --------------------------------------------------------------------
+// ---------------------------------------------------------------------------------
+
+// compileClass => class <name> { <class vars> <subroutines> }
+func (c *Compiler) compileClass(class *ast.ClassDeclaration) error {
+	classTable, err := symbols.Class(class)
+	if err != nil {
+		return err
+	}
+
+	for _, stmt := range class.Body {
+		switch s := stmt.(type) {
+		case *ast.TypeDeclaration:
+			continue
+		case *ast.SubroutineDeclaration:
+			if err := c.compileSubroutine(classTable, s); err != nil {
+				return err
+			}
+		default:
+			return compileError(s.Pos(), "unexpected statement in class %s: %s", c.class, s.TokenLiteral())
+		}
+	}
+
+	return nil
+}
+
+// compileSubroutine => function <class>.<name> <nlocals> <body>
+func (c *Compiler) compileSubroutine(classTable *symbols.Table, sd *ast.SubroutineDeclaration) error {
+	var err error
+	if c.symbols, err = symbols.Subroutine(classTable, c.class, sd); err != nil {
+		return err
+	}
+	c.labels = 0
+	c.pos = sd.Pos()
+
+	c.Writeln("function %s.%s %d", c.class, sd.Name.Name, c.symbols.Count(symbols.LOCAL))
+
+	switch sd.Decelration.Type {
+	case token.CONSTRUCTOR:
+		c.Writeln("push constant %d", classTable.Count(symbols.FIELD))
+		c.Writeln("call Memory.alloc 1")
+		c.Writeln("pop pointer 0")
+	case token.METHOD:
+		c.Writeln("push argument 0")
+		c.Writeln("pop pointer 0")
+	}
+
+	return c.compileStatements(sd.Body)
+}
+
+// ---------------------------------------------------------------------------------
+// Statements ----------------------------------------------------------------------
+// ---------------------------------------------------------------------------------
+
+func (c *Compiler) compileStatements(stmts []ast.StatementNode) error {
+	for _, stmt := range stmts {
+		if err := c.compileStatement(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Compiler) compileStatement(stmt ast.StatementNode) error {
+	// code written after a nested statement belongs to this one again
+	pos := c.pos
+	c.pos = stmt.Pos()
+	defer func() { c.pos = pos }()
+
+	switch s := stmt.(type) {
+	case *ast.LetStatement:
+		return c.compileLetStatement(s)
+	case *ast.DoStatement:
+		return c.compileDoStatement(s)
+	case *ast.ReturnStatement:
+		return c.compileReturnStatement(s)
+	case *ast.WhileStatement:
+		return c.compileWhileStatement(s)
+	case *ast.IfStatement:
+		return c.compileIfStatement(s)
+	case *ast.TypeDeclaration:
+		// locals are defined before the subroutine body is compiled
+		return nil
+	default:
+		return compileError(stmt.Pos(), "unexpected statement: %s", stmt.TokenLiteral())
+	}
+}
+
+// compileLetStatement => let <name>[<exp>?] = <exp>;
+func (c *Compiler) compileLetStatement(ls *ast.LetStatement) error {
+	switch name := ls.Name.(type) {
+	case *ast.Identifier:
+		v, ok := c.lookup(name.Name)
+		if !ok {
+			return compileError(name.Pos(), "undeclared variable: %s", name.Name)
+		}
+		if err := c.compileExpression(ls.Value); err != nil {
+			return err
+		}
+		c.Writeln("pop %s %d", v.Segment(), v.Index)
+
+	case *ast.IndexIdentifier:
+		if err := c.compileIndexAddress(name); err != nil {
+			return err
+		}
+		if err := c.compileExpression(ls.Value); err != nil {
+			return err
+		}
+		c.Writeln("pop temp 0")
+		c.Writeln("pop pointer 1")
+		c.Writeln("push temp 0")
+		c.Writeln("pop that 0")
+
+	default:
+		return compileError(ls.Name.Pos(), "invalid assignment target: %s", ls.Name.String())
+	}
+
+	return nil
+}
+
+// compileDoStatement => do <subroutine call>;
+func (c *Compiler) compileDoStatement(ds *ast.DoStatement) error {
+	sc, ok := ds.Expression.(*ast.SubroutineCall)
+	if !ok {
+		return compileError(ds.Expression.Pos(), "do expects a subroutine call, got: %s", ds.Expression.String())
+	}
+
+	if err := c.compileSubroutineCall(sc); err != nil {
+		return err
+	}
+	c.Writeln("pop temp 0")
+
+	return nil
+}
+
+// compileReturnStatement => return <exp?>;
+func (c *Compiler) compileReturnStatement(rs *ast.ReturnStatement) error {
+	if rs.Value == nil {
+		c.Writeln("push constant 0")
+	} else if err := c.compileExpression(rs.Value); err != nil {
+		return err
+	}
+	c.Writeln("return")
+	return nil
+}
+
+// compileWhileStatement => while (<exp>) {<statements>}
+func (c *Compiler) compileWhileStatement(ws *ast.WhileStatement) error {
+	exp := c.newLabel("WHILE_EXP")
+	end := c.newLabel("WHILE_END")
+	c.labels++
+
+	c.Writeln("label %s", exp)
+	if err := c.compileExpression(ws.Expression); err != nil {
+		return err
+	}
+	c.Writeln("not")
+	c.Writeln("if-goto %s", end)
+
+	if err := c.compileStatements(ws.Statements); err != nil {
+		return err
+	}
+
+	c.Writeln("goto %s", exp)
+	c.Writeln("label %s", end)
+
+	return nil
+}
+
+// compileIfStatement => if (<exp>) {<statements>} ?else {<statements>}
+func (c *Compiler) compileIfStatement(is *ast.IfStatement) error {
+	isTrue := c.newLabel("IF_TRUE")
+	isFalse := c.newLabel("IF_FALSE")
+	end := c.newLabel("IF_END")
+	c.labels++
+
+	if err := c.compileExpression(is.Expression); err != nil {
+		return err
+	}
+	c.Writeln("if-goto %s", isTrue)
+	c.Writeln("goto %s", isFalse)
+	c.Writeln("label %s", isTrue)
+
+	if err := c.compileStatements(is.Statements); err != nil {
+		return err
+	}
+
+	if len(is.ElseStatements) == 0 {
+		c.Writeln("label %s", isFalse)
+		return nil
+	}
+
+	c.Writeln("goto %s", end)
+	c.Writeln("label %s", isFalse)
+
+	if err := c.compileStatements(is.ElseStatements); err != nil {
+		return err
+	}
+
+	c.Writeln("label %s", end)
+
+	return nil
+}
+
+// ---------------------------------------------------------------------------------
+// Expressions ---------------------------------------------------------------------
+// ---------------------------------------------------------------------------------
+
+// compileExpression pushes the value of the expression onto the stack,
+// binary expressions push both operands before applying their op
+func (c *Compiler) compileExpression(node ast.ExpressionNode) error {
+	switch n := node.(type) {
+	case *ast.BinaryExpression:
+		if err := c.compileExpression(n.Left); err != nil {
+			return err
+		}
+		if err := c.compileExpression(n.Right); err != nil {
+			return err
+		}
+		return c.compileBinaryOp(n.Op)
+
+	case *ast.UnaryExpression:
+		if err := c.compileExpression(n.Term); err != nil {
+			return err
+		}
+		return c.compileUnaryOp(n.Prefix)
+
+	case *ast.ParenExpression:
+		return c.compileExpression(n.Term)
+
+	case *ast.IntLiteral:
+		c.Writeln("push constant %d", n.Value)
+
+	case *ast.StringLiteral:
+		c.Writeln("push constant %d", len(n.Value))
+		c.Writeln("call String.new 1")
+		for i := 0; i < len(n.Value); i++ {
+			c.Writeln("push constant %d", n.Value[i])
+			c.Writeln("call String.appendChar 2")
+		}
+
+	case *ast.KeywordConstant:
+		return c.compileKeywordConstant(n)
+
+	case *ast.Identifier:
+		v, ok := c.lookup(n.Name)
+		if !ok {
+			return compileError(n.Pos(), "undeclared variable: %s", n.Name)
+		}
+		c.Writeln("push %s %d", v.Segment(), v.Index)
+
+	case *ast.IndexIdentifier:
+		if err := c.compileIndexAddress(n); err != nil {
+			return err
+		}
+		c.Writeln("pop pointer 1")
+		c.Writeln("push that 0")
+
+	case *ast.SubroutineCall:
+		return c.compileSubroutineCall(n)
+
+	default:
+		return compileError(node.Pos(), "unexpected expression: %s", node.String())
+	}
+
+	return nil
+}
+
+func (c *Compiler) compileUnaryOp(op token.Token) error {
+	switch op.Type {
+	case token.MINUS:
+		c.Writeln("neg")
+	case token.NOT:
+		c.Writeln("not")
+	default:
+		return compileError(op.Pos, "invalid unary operator: %s", op.Literal)
+	}
+	return nil
+}
+
+func (c *Compiler) compileBinaryOp(op token.Token) error {
+	switch op.Type {
+	case token.PLUS:
+		c.Writeln("add")
+	case token.MINUS:
+		c.Writeln("sub")
+	case token.ASTERISK:
+		c.Writeln("call Math.multiply 2")
+	case token.SLASH:
+		c.Writeln("call Math.divide 2")
+	case token.AND:
+		c.Writeln("and")
+	case token.OR:
+		c.Writeln("or")
+	case token.LT:
+		c.Writeln("lt")
+	case token.GT:
+		c.Writeln("gt")
+	case token.EQ:
+		c.Writeln("eq")
+	default:
+		return compileError(op.Pos, "invalid binary operator: %s", op.Literal)
+	}
+	return nil
+}
+
+// compileKeywordConstant => true | false | null | this
+func (c *Compiler) compileKeywordConstant(kc *ast.KeywordConstant) error {
+	switch kc.Token.Type {
+	case token.TRUE:
+		c.Writeln("push constant 0")
+		c.Writeln("not")
+	case token.FALSE, token.NULL:
+		c.Writeln("push constant 0")
+	case token.THIS:
+		c.Writeln("push pointer 0")
+	default:
+		return compileError(kc.Pos(), "invalid keyword constant: %s", kc.Value)
+	}
+	return nil
+}
+
+// compileIndexAddress pushes the address of <name>[<exp>] onto the stack
+func (c *Compiler) compileIndexAddress(ii *ast.IndexIdentifier) error {
+	v, ok := c.lookup(ii.Name)
+	if !ok {
+		return compileError(ii.Pos(), "undeclared variable: %s", ii.Name)
+	}
+
+	c.Writeln("push %s %d", v.Segment(), v.Index)
+	if err := c.compileExpression(ii.Index); err != nil {
+		return err
+	}
+	c.Writeln("add")
+
+	return nil
+}
+
+// compileSubroutineCall => <class or var?>.<name>(<expression list>)
+func (c *Compiler) compileSubroutineCall(sc *ast.SubroutineCall) error {
+	var name string
+	nargs := len(sc.Arguments)
+
+	if sc.Class == nil {
+		// method call on the current object
+		c.Writeln("push pointer 0")
+		name = c.class + "." + sc.Name.Name
+		nargs++
+	} else if v, ok := c.lookup(sc.Class.Name); ok {
+		// method call on an object stored in a variable
+		if err := c.checkOS(sc, v.Type); err != nil {
+			return err
+		}
+		c.Writeln("push %s %d", v.Segment(), v.Index)
+		name = v.Type + "." + sc.Name.Name
+		nargs++
+	} else {
+		// function or constructor call
+		if err := c.checkOS(sc, sc.Class.Name); err != nil {
+			return err
+		}
+		name = sc.Class.Name + "." + sc.Name.Name
+	}
+
+	for _, arg := range sc.Arguments {
+		if err := c.compileExpression(arg); err != nil {
+			return err
+		}
+	}
+
+	c.Writeln("call %s %d", name, nargs)
+
+	return nil
+}
+
+// checkOS checks a call to a subroutine of an os class against the os api
+func (c *Compiler) checkOS(sc *ast.SubroutineCall, class string) error {
+	api, ok := c.os[class]
+	if !ok || class == c.class {
+		return nil
+	}
+
+	s, ok := api.Subroutine(sc.Name.Name)
+	if !ok {
+		return compileError(sc.Name.Pos(), "undefined os subroutine: %s.%s", class, sc.Name.Name)
+	}
+	if len(sc.Arguments) != len(s.Params) {
+		return compileError(sc.Pos(), "%s.%s expects %d argument(s), got: %d", class, s.Name, len(s.Params), len(sc.Arguments))
+	}
+	return nil
+}
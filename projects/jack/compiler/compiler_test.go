@@ -0,0 +1,371 @@
+package compiler
+
+import (
+	"io/ioutil"
+	"jack/lexer"
+	"jack/osapi"
+	"jack/parser"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func compile(t *testing.T, input string) []string {
+	p := parser.New(lexer.New(input))
+	class, err := p.ParseClass()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	code, err := Compile(class)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	return strings.Split(strings.TrimSpace(code), "\n")
+}
+
+func expectLines(t *testing.T, n string, expected, actual []string) {
+	if len(actual) != len(expected) {
+		t.Fatalf("%s : line count mismatch, expected: %v, got: %v\n%s", n, len(expected), len(actual), strings.Join(actual, "\n"))
+	}
+
+	for i := range actual {
+		if actual[i] != expected[i] {
+			t.Errorf("%s : line %d expected: %v, got: %v", n, i, expected[i], actual[i])
+		}
+	}
+}
+
+func TestCompileSeven(t *testing.T) {
+	test := `
+		class Main {
+			function void main() {
+				do Output.printInt(1 + (2 * 3));
+				return;
+			}
+		}
+	`
+
+	expected := []string{
+		"function Main.main 0",
+		"push constant 1",
+		"push constant 2",
+		"push constant 3",
+		"call Math.multiply 2",
+		"add",
+		"call Output.printInt 1",
+		"pop temp 0",
+		"push constant 0",
+		"return",
+	}
+
+	expectLines(t, "Seven", expected, compile(t, test))
+}
+
+func TestCompileConstructorAndMethod(t *testing.T) {
+	test := `
+		class Point {
+			field int x, y;
+			static int count;
+
+			constructor Point new(int ax, int ay) {
+				let x = ax;
+				let y = ay;
+				let count = count + 1;
+				return this;
+			}
+
+			method int sum(Point other) {
+				return x + other.getX();
+			}
+		}
+	`
+
+	expected := []string{
+		"function Point.new 0",
+		"push constant 2",
+		"call Memory.alloc 1",
+		"pop pointer 0",
+		"push argument 0",
+		"pop this 0",
+		"push argument 1",
+		"pop this 1",
+		"push static 0",
+		"push constant 1",
+		"add",
+		"pop static 0",
+		"push pointer 0",
+		"return",
+		"function Point.sum 0",
+		"push argument 0",
+		"pop pointer 0",
+		"push this 0",
+		"push argument 1",
+		"call Point.getX 1",
+		"add",
+		"return",
+	}
+
+	expectLines(t, "ConstructorAndMethod", expected, compile(t, test))
+}
+
+func TestCompileArrays(t *testing.T) {
+	test := `
+		class Main {
+			function void main() {
+				var Array a;
+				let a[1] = a[2];
+				return;
+			}
+		}
+	`
+
+	expected := []string{
+		"function Main.main 1",
+		"push local 0",
+		"push constant 1",
+		"add",
+		"push local 0",
+		"push constant 2",
+		"add",
+		"pop pointer 1",
+		"push that 0",
+		"pop temp 0",
+		"pop pointer 1",
+		"push temp 0",
+		"pop that 0",
+		"push constant 0",
+		"return",
+	}
+
+	expectLines(t, "Arrays", expected, compile(t, test))
+}
+
+func TestCompileControlFlow(t *testing.T) {
+	test := `
+		class Main {
+			function int main(boolean b) {
+				var int i;
+				while (~(i = 3)) {
+					let i = -i;
+				}
+				if (b) {
+					do draw();
+				} else {
+					return "hi";
+				}
+				return true;
+			}
+		}
+	`
+
+	expected := []string{
+		"function Main.main 1",
+		"label WHILE_EXP0",
+		"push local 0",
+		"push constant 3",
+		"eq",
+		"not",
+		"not",
+		"if-goto WHILE_END0",
+		"push local 0",
+		"neg",
+		"pop local 0",
+		"goto WHILE_EXP0",
+		"label WHILE_END0",
+		"push argument 0",
+		"if-goto IF_TRUE1",
+		"goto IF_FALSE1",
+		"label IF_TRUE1",
+		"push pointer 0",
+		"call Main.draw 1",
+		"pop temp 0",
+		"goto IF_END1",
+		"label IF_FALSE1",
+		"push constant 2",
+		"call String.new 1",
+		"push constant 104",
+		"call String.appendChar 2",
+		"push constant 105",
+		"call String.appendChar 2",
+		"return",
+		"label IF_END1",
+		"push constant 0",
+		"not",
+		"return",
+	}
+
+	expectLines(t, "ControlFlow", expected, compile(t, test))
+}
+
+func TestCompileLineMap(t *testing.T) {
+	test := `class Main {
+	function int main() {
+		var int i;
+		while (i < 3) {
+			let i = i + 1;
+		}
+		return i;
+	}
+}`
+
+	p := parser.New(lexer.NewFile("Main.jack", test))
+	class, err := p.ParseClass()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	code, lm, err := CompileLineMap(class)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	// function, the while condition, the let, the while jump back, the return
+	expected := []int{ 2, 4, 4, 4, 4, 4, 4, 5, 5, 5, 5, 4, 4, 7, 7 }
+	lines := strings.Split(strings.TrimSpace(code), "\n")
+
+	if lm.File != "Main.jack" {
+		t.Errorf("expected file: Main.jack, got: %s", lm.File)
+	}
+	if len(lm.Lines) != len(lines) || len(lines) != len(expected) {
+		t.Fatalf("expected %d lines, got: %d vm lines and %d mapped\n%s", len(expected), len(lines), len(lm.Lines), code)
+	}
+	for i := range expected {
+		if lm.Lines[i] != expected[i] {
+			t.Errorf("%s : expected line %d, got: %d", lines[i], expected[i], lm.Lines[i])
+		}
+	}
+}
+
+func TestCompileOperatorOrder(t *testing.T) {
+	test := `
+		class Main {
+			function int f(int a, int b) {
+				return a - -b + 2 * a;
+			}
+		}
+	`
+
+	expected := []string{
+		"function Main.f 0",
+		"push argument 0",
+		"push argument 1",
+		"neg",
+		"sub",
+		"push constant 2",
+		"add",
+		"push argument 0",
+		"call Math.multiply 2",
+		"return",
+	}
+
+	expectLines(t, "OperatorOrder", expected, compile(t, test))
+
+	p := parser.New(lexer.New(test))
+	p.Precedence = true
+	class, err := p.ParseClass()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	code, err := Compile(class)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	expected = []string{
+		"function Main.f 0",
+		"push argument 0",
+		"push argument 1",
+		"neg",
+		"sub",
+		"push constant 2",
+		"push argument 0",
+		"call Math.multiply 2",
+		"add",
+		"return",
+	}
+
+	expectLines(t, "OperatorPrecedence", expected, strings.Split(strings.TrimSpace(code), "\n"))
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []string{
+		"class Main { function void main() { let x = 1; return; } }",
+		"class Main { function void main() { var int x, x; return; } }",
+		"class Main { function void main() { do x; return; } }",
+	}
+
+	for _, test := range tests {
+		p := parser.New(lexer.New(test))
+		class, err := p.ParseClass()
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+
+		if _, err := Compile(class); err == nil {
+			t.Errorf("expected error compiling: %s", test)
+		}
+	}
+}
+
+func TestCompileOS(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"class Main { function void main() { do Output.printString(\"a\"); return; } }", ""},
+		{"class Main { function void main() { var String s; do s.appendChar(65); return; } }", ""},
+		{"class Main { function void main() { do Output.printString(); return; } }", "1:40: Output.printString expects 1 argument(s), got: 0"},
+		{"class Main { function void main() { do Math.pow(2, 3); return; } }", "1:45: undefined os subroutine: Math.pow"},
+		{"class Main { function void main() { var String s; do s.appendChar(); return; } }", "1:54: String.appendChar expects 1 argument(s), got: 0"},
+		// a class of the os calling itself is not checked
+		{"class Math { function int pow(int x, int y) { return Math.pow(x); } }", ""},
+	}
+
+	opts := Options{OS: osapi.Classes}
+	for _, tt := range tests {
+		class, err := parser.New(lexer.New(tt.input)).ParseClass()
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+
+		_, _, err = CompileWith(class, opts)
+		if tt.expected == "" && err != nil {
+			t.Errorf("expected no error compiling: %s, got: %v", tt.input, err)
+		}
+		if tt.expected != "" && (err == nil || err.Error() != tt.expected) {
+			t.Errorf("expected: %s, got: %v", tt.expected, err)
+		}
+
+		// without the os api calls are not checked
+		if _, err := Compile(class); err != nil {
+			t.Errorf("expected no error compiling: %s, got: %v", tt.input, err)
+		}
+	}
+}
+
+func TestCompileProjects(t *testing.T) {
+	files, err := filepath.Glob("../../11/*/*.jack")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+
+		p := parser.New(lexer.New(string(data)))
+		class, err := p.ParseClass()
+		if err != nil {
+			t.Fatalf("%s : %s", file, err.Error())
+		}
+
+		if _, err := Compile(class); err != nil {
+			t.Errorf("%s : %s", file, err.Error())
+		}
+	}
+}
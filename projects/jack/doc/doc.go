@@ -0,0 +1,182 @@
+// Package doc renders the doc comments of jack classes as an api reference
+package doc
+
+import (
+	"fmt"
+	"html"
+	"jack/ast"
+	"strings"
+)
+
+type Writer struct {
+	strings.Builder
+}
+
+func (w *Writer) Writeln(s string, args ...interface{}) {
+	if len(args) > 0 {
+		w.WriteString(fmt.Sprintf(s, args...))
+	} else {
+		w.WriteString(s)
+	}
+	w.WriteString("\n")
+}
+
+// Subroutines returns the subroutines declared in a class in source order
+func Subroutines(class *ast.ClassDeclaration) []*ast.SubroutineDeclaration {
+	var subs []*ast.SubroutineDeclaration
+	for _, stmt := range class.Body {
+		if sub, ok := stmt.(*ast.SubroutineDeclaration); ok {
+			subs = append(subs, sub)
+		}
+	}
+	return subs
+}
+
+// Signature is a subroutine's declaration without its body
+func Signature(sub *ast.SubroutineDeclaration) string {
+	return fmt.Sprintf("%s %s %s(%s)", sub.Decelration.Literal, sub.ReturnType.Literal, sub.Name.Name, params(sub))
+}
+
+func params(sub *ast.SubroutineDeclaration) string {
+	ps := make([]string, len(sub.Parameters))
+	for i, p := range sub.Parameters {
+		ps[i] = p.String()
+	}
+	return strings.Join(ps, ", ")
+}
+
+// paragraphs splits a doc comment at its blank lines
+func paragraphs(doc string) [][]string {
+	var ps [][]string
+	var p []string
+	for _, line := range strings.Split(doc, "\n") {
+		if line == "" {
+			if len(p) > 0 {
+				ps = append(ps, p)
+			}
+			p = nil
+			continue
+		}
+		p = append(p, line)
+	}
+	if len(p) > 0 {
+		ps = append(ps, p)
+	}
+	return ps
+}
+
+// ---------------------------------------------------------------------------------
+// Markdown ------------------------------------------------------------------------
+// ---------------------------------------------------------------------------------
+
+// Markdown renders the api reference of the classes as markdown
+func Markdown(title string, classes []*ast.ClassDeclaration) string {
+	var w Writer
+
+	w.Writeln("# %s", title)
+	w.Writeln("")
+	for _, class := range classes {
+		w.Writeln("- [%s](#%s)", class.Name.Name, strings.ToLower(class.Name.Name))
+	}
+
+	for _, class := range classes {
+		w.Writeln("")
+		w.Writeln("## %s", class.Name.Name)
+		w.markdownDoc(class.Doc)
+
+		for _, sub := range Subroutines(class) {
+			w.Writeln("")
+			w.Writeln("### %s.%s", class.Name.Name, sub.Name.Name)
+			w.Writeln("")
+			w.Writeln("```")
+			w.Writeln(Signature(sub))
+			w.Writeln("```")
+			w.Writeln("")
+			w.Writeln("- Kind: %s", sub.Decelration.Literal)
+			if len(sub.Parameters) == 0 {
+				w.Writeln("- Parameters: none")
+			} else {
+				w.Writeln("- Parameters:")
+				for _, p := range sub.Parameters {
+					w.Writeln("  - `%s` %s", p.Name.Name, p.Type.Literal)
+				}
+			}
+			w.Writeln("- Returns: %s", sub.ReturnType.Literal)
+			w.markdownDoc(sub.Doc)
+		}
+	}
+
+	return w.String()
+}
+
+// markdownDoc writes a doc comment keeping its line breaks
+func (w *Writer) markdownDoc(doc string) {
+	for _, p := range paragraphs(doc) {
+		w.Writeln("")
+		w.Writeln(strings.Join(p, "  \n"))
+	}
+}
+
+// ---------------------------------------------------------------------------------
+// HTML ----------------------------------------------------------------------------
+// ---------------------------------------------------------------------------------
+
+// HTML renders the api reference of the classes as a html page
+func HTML(title string, classes []*ast.ClassDeclaration) string {
+	var w Writer
+
+	w.Writeln("<!DOCTYPE html>")
+	w.Writeln("<html>")
+	w.Writeln("<head>")
+	w.Writeln("<meta charset=\"utf-8\">")
+	w.Writeln("<title>%s</title>", html.EscapeString(title))
+	w.Writeln("</head>")
+	w.Writeln("<body>")
+	w.Writeln("<h1>%s</h1>", html.EscapeString(title))
+
+	w.Writeln("<ul>")
+	for _, class := range classes {
+		name := html.EscapeString(class.Name.Name)
+		w.Writeln("<li><a href=\"#%s\">%s</a></li>", name, name)
+	}
+	w.Writeln("</ul>")
+
+	for _, class := range classes {
+		name := html.EscapeString(class.Name.Name)
+		w.Writeln("<h2 id=\"%s\">%s</h2>", name, name)
+		w.htmlDoc(class.Doc)
+
+		for _, sub := range Subroutines(class) {
+			w.Writeln("<h3 id=\"%s.%s\">%s.%s</h3>", name, sub.Name.Name, name, html.EscapeString(sub.Name.Name))
+			w.Writeln("<pre><code>%s</code></pre>", html.EscapeString(Signature(sub)))
+			w.Writeln("<dl>")
+			w.Writeln("<dt>Kind</dt><dd>%s</dd>", sub.Decelration.Literal)
+			w.Writeln("<dt>Parameters</dt>")
+			if len(sub.Parameters) == 0 {
+				w.Writeln("<dd>none</dd>")
+			}
+			for _, p := range sub.Parameters {
+				w.Writeln("<dd><code>%s</code> %s</dd>", html.EscapeString(p.Name.Name), html.EscapeString(p.Type.Literal))
+			}
+			w.Writeln("<dt>Returns</dt><dd>%s</dd>", html.EscapeString(sub.ReturnType.Literal))
+			w.Writeln("</dl>")
+			w.htmlDoc(sub.Doc)
+		}
+	}
+
+	w.Writeln("</body>")
+	w.Writeln("</html>")
+
+	return w.String()
+}
+
+// htmlDoc writes a doc comment keeping its line breaks
+func (w *Writer) htmlDoc(doc string) {
+	for _, p := range paragraphs(doc) {
+		lines := make([]string, len(p))
+		for i, line := range p {
+			lines[i] = html.EscapeString(line)
+		}
+		w.Writeln("<p>%s</p>", strings.Join(lines, "<br>\n"))
+	}
+}
@@ -0,0 +1,89 @@
+package doc
+
+import (
+	"jack/ast"
+	"jack/lexer"
+	"jack/parser"
+	"strings"
+	"testing"
+)
+
+const source = `/** Shapes & sizes. */
+class Shape {
+	/** Makes a shape
+	 *  of a size.
+	 *
+	 *  Sizes are < 100. */
+	constructor Shape new(int size, boolean filled) { return this; }
+
+	method int area() { return 0; }
+}`
+
+func parse(t *testing.T) []*ast.ClassDeclaration {
+	class, err := parser.New(lexer.New(source)).ParseClass()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	return []*ast.ClassDeclaration{ class }
+}
+
+func TestMarkdown(t *testing.T) {
+	expected := "# Shapes API\n" +
+		"\n" +
+		"- [Shape](#shape)\n" +
+		"\n" +
+		"## Shape\n" +
+		"\n" +
+		"Shapes & sizes.\n" +
+		"\n" +
+		"### Shape.new\n" +
+		"\n" +
+		"```\n" +
+		"constructor Shape new(int size, boolean filled)\n" +
+		"```\n" +
+		"\n" +
+		"- Kind: constructor\n" +
+		"- Parameters:\n" +
+		"  - `size` int\n" +
+		"  - `filled` boolean\n" +
+		"- Returns: Shape\n" +
+		"\n" +
+		"Makes a shape  \n" +
+		"of a size.\n" +
+		"\n" +
+		"Sizes are < 100.\n" +
+		"\n" +
+		"### Shape.area\n" +
+		"\n" +
+		"```\n" +
+		"method int area()\n" +
+		"```\n" +
+		"\n" +
+		"- Kind: method\n" +
+		"- Parameters: none\n" +
+		"- Returns: int\n"
+
+	actual := Markdown("Shapes API", parse(t))
+	if actual != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, actual)
+	}
+}
+
+func TestHTML(t *testing.T) {
+	actual := HTML("Shapes API", parse(t))
+
+	for _, expected := range []string{
+		"<title>Shapes API</title>",
+		"<li><a href=\"#Shape\">Shape</a></li>",
+		"<h2 id=\"Shape\">Shape</h2>",
+		"<p>Shapes &amp; sizes.</p>",
+		"<pre><code>constructor Shape new(int size, boolean filled)</code></pre>",
+		"<dd><code>filled</code> boolean</dd>",
+		"<p>Makes a shape<br>\nof a size.</p>\n<p>Sizes are &lt; 100.</p>",
+		"<dt>Returns</dt><dd>int</dd>",
+	} {
+		if !strings.Contains(actual, expected) {
+			t.Errorf("expected %q in:\n%s", expected, actual)
+		}
+	}
+}
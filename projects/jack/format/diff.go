@@ -0,0 +1,153 @@
+package format
+
+import (
+	"fmt"
+	"strings"
+)
+
+// context is the number of unchanged lines shown around a change
+const context = 3
+
+// edit is a line kept (' '), removed ('-') or added ('+')
+type edit struct {
+	op   byte
+	line string
+}
+
+// Diff returns the changes from a to b as a unified diff, it is empty when
+// they are the same
+func Diff(name, a, b string) string {
+	if a == b {
+		return ""
+	}
+
+	edits := diffLines(lines(a), lines(b))
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s.orig\n", name)
+	fmt.Fprintf(&sb, "+++ %s\n", name)
+
+	for start := 0; start < len(edits); {
+		// find the next change
+		first := start
+		for first < len(edits) && edits[first].op == ' ' {
+			first++
+		}
+		if first == len(edits) {
+			break
+		}
+
+		// take in the changes close enough to share their context
+		end := first
+		for i := first; i < len(edits) && i <= end+2*context; i++ {
+			if edits[i].op != ' ' {
+				end = i
+			}
+		}
+
+		from := first - context
+		if from < start {
+			from = start
+		}
+		to := end + context + 1
+		if to > len(edits) {
+			to = len(edits)
+		}
+		writeHunk(&sb, edits, from, to)
+		start = to
+	}
+
+	return sb.String()
+}
+
+// writeHunk writes the edits from..to with a header giving the lines they
+// cover in a and b
+func writeHunk(sb *strings.Builder, edits []edit, from, to int) {
+	aLine, bLine := 1, 1
+	for _, e := range edits[:from] {
+		if e.op != '+' {
+			aLine++
+		}
+		if e.op != '-' {
+			bLine++
+		}
+	}
+
+	aLen, bLen := 0, 0
+	for _, e := range edits[from:to] {
+		if e.op != '+' {
+			aLen++
+		}
+		if e.op != '-' {
+			bLen++
+		}
+	}
+
+	// an empty range is given by the line before it
+	if aLen == 0 {
+		aLine--
+	}
+	if bLen == 0 {
+		bLine--
+	}
+
+	fmt.Fprintf(sb, "@@ -%d,%d +%d,%d @@\n", aLine, aLen, bLine, bLen)
+	for _, e := range edits[from:to] {
+		sb.WriteByte(e.op)
+		sb.WriteString(e.line)
+		sb.WriteString("\n")
+	}
+}
+
+// lines splits text into lines, a final newline does not start another line
+func lines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// diffLines finds the edits turning a into b using the longest common
+// subsequence of their lines
+func diffLines(a, b []string) []edit {
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var edits []edit
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			edits = append(edits, edit{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			edits = append(edits, edit{'-', a[i]})
+			i++
+		default:
+			edits = append(edits, edit{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		edits = append(edits, edit{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		edits = append(edits, edit{'+', b[j]})
+	}
+	return edits
+}
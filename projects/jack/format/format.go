@@ -0,0 +1,314 @@
+// Package format prints jack syntax trees back out as jack source with a
+// consistent layout, keeping the comments of the source it was parsed from
+package format
+
+import (
+	"jack/ast"
+	"jack/lexer"
+	"jack/parser"
+	"jack/token"
+	"math"
+	"strings"
+)
+
+// indent is written once for each level of nesting
+const indent = "    "
+
+// Source parses the jack source of a class and returns it formatted, file is
+// the name used in the positions of syntax errors
+func Source(file, src string) (string, error) {
+	l := lexer.NewFile(file, src)
+	class, err := parser.New(l).ParseClass()
+	if err != nil {
+		return "", err
+	}
+	return Class(class, l.Comments()), nil
+}
+
+// Class prints a class as jack source. Each comment is printed before the
+// first node that comes after it in the source, or at the end of the line
+// when it followed code on its line. A blank line in the source between two
+// statements or declarations is kept.
+func Class(class *ast.ClassDeclaration, comments []token.Comment) string {
+	p := printer{comments: comments, trailing: map[int]int{}}
+
+	p.item(class.Pos())
+	p.open("class " + class.Name.Name + " {")
+	for _, stmt := range class.Body {
+		p.statement(stmt)
+	}
+	p.close(class.End)
+
+	// comments after the class
+	p.flush(token.Pos{Line: math.MaxInt})
+	p.align()
+
+	return strings.Join(p.lines, "\n") + "\n"
+}
+
+type printer struct {
+	lines    []string
+	depth    int
+	comments []token.Comment
+
+	// last is the last source line printed so far
+	last int
+	// opened is set while the last line printed opened a block
+	opened bool
+	// lineComment is set while the last line printed ends with a // comment
+	lineComment bool
+	// trailing holds the lines ending with a one line comment after code, by
+	// the length of the code
+	trailing map[int]int
+}
+
+// before reports whether a comes before b in the source
+func before(a, b token.Pos) bool {
+	return a.Line < b.Line || a.Line == b.Line && a.Column < b.Column
+}
+
+// seen records that the source up to a position has been printed
+func (p *printer) seen(pos token.Pos) {
+	if pos.Line > p.last {
+		p.last = pos.Line
+	}
+}
+
+func (p *printer) println(s string) {
+	p.lines = append(p.lines, strings.Repeat(indent, p.depth)+s)
+	p.opened = false
+	p.lineComment = false
+}
+
+// open prints the line starting a block and indents the lines after it
+func (p *printer) open(s string) {
+	p.println(s)
+	p.opened = true
+	p.depth++
+}
+
+// close prints the closing brace of a block at end
+func (p *printer) close(end token.Pos) {
+	p.flush(end)
+	p.depth--
+	p.println("}")
+	p.seen(end)
+}
+
+// space prints a blank line when the source had one before line, blank lines
+// are not kept at the start of a block
+func (p *printer) space(line int) {
+	if len(p.lines) > 0 && !p.opened && line > p.last+1 {
+		p.lines = append(p.lines, "")
+	}
+}
+
+// item starts a statement or declaration at pos
+func (p *printer) item(pos token.Pos) {
+	p.flush(pos)
+	p.space(pos.Line)
+	p.seen(pos)
+}
+
+// flush prints the comments before pos that have not been printed yet
+func (p *printer) flush(pos token.Pos) {
+	for len(p.comments) > 0 && before(p.comments[0].Pos, pos) {
+		c := p.comments[0]
+		p.comments = p.comments[1:]
+
+		lines := strings.Split(c.Text, "\n")
+		for i := 1; i < len(lines); i++ {
+			lines[i] = p.continuation(lines[i])
+		}
+
+		last := len(p.lines) - 1
+		if c.Pos.Line == p.last && last >= 0 && !p.lineComment {
+			// the comment follows code on its line
+			if len(lines) == 1 {
+				p.trailing[last] = len(p.lines[last])
+			}
+			p.lines[last] += " " + lines[0]
+			p.lines = append(p.lines, lines[1:]...)
+		} else {
+			p.space(c.Pos.Line)
+			p.println(lines[0])
+			p.lines = append(p.lines, lines[1:]...)
+		}
+		p.opened = false
+		p.lineComment = strings.HasPrefix(c.Text, "//")
+
+		p.seen(token.Pos{Line: c.Pos.Line + len(lines) - 1})
+	}
+}
+
+// align lines up the comments after code on consecutive lines with the same
+// indent
+func (p *printer) align() {
+	for i := 0; i < len(p.lines); {
+		if _, ok := p.trailing[i]; !ok {
+			i++
+			continue
+		}
+
+		end, width := i, 0
+		for ; end < len(p.lines); end++ {
+			n, ok := p.trailing[end]
+			if !ok || leading(p.lines[end]) != leading(p.lines[i]) {
+				break
+			}
+			if n > width {
+				width = n
+			}
+		}
+
+		for ; i < end; i++ {
+			n := p.trailing[i]
+			code, comment := p.lines[i][:n], p.lines[i][n+1:]
+			p.lines[i] = code + strings.Repeat(" ", width-n) + " " + comment
+		}
+	}
+}
+
+// leading returns the indent of a line
+func leading(line string) string {
+	return line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+}
+
+// continuation re-indents a line of a block comment after the first, lines
+// starting with a * are lined up under the opening /*, the rest are kept
+func (p *printer) continuation(line string) string {
+	line = strings.TrimRight(line, " \t\r")
+	trimmed := strings.TrimLeft(line, " \t")
+	if strings.HasPrefix(trimmed, "*") {
+		return strings.Repeat(indent, p.depth) + " " + trimmed
+	}
+	return line
+}
+
+// ----------------------------------------------------------------------------
+// statements -----------------------------------------------------------------
+// ----------------------------------------------------------------------------
+
+func (p *printer) statement(stmt ast.StatementNode) {
+	p.item(stmt.Pos())
+
+	switch s := stmt.(type) {
+	case *ast.TypeDeclaration:
+		names := make([]string, len(s.Names))
+		for i, name := range s.Names {
+			names[i] = p.expression(name)
+		}
+		p.println(s.Declaration.Literal + " " + s.Type.Literal + " " + strings.Join(names, ", ") + ";")
+
+	case *ast.SubroutineDeclaration:
+		params := make([]string, len(s.Parameters))
+		for i, param := range s.Parameters {
+			p.seen(param.Name.Pos())
+			params[i] = param.Type.Literal + " " + param.Name.Name
+		}
+		p.seen(s.Name.Pos())
+		p.open(s.Decelration.Literal + " " + s.ReturnType.Literal + " " + s.Name.Name + "(" + strings.Join(params, ", ") + ") {")
+		p.block(s.Body, s.End)
+
+	case *ast.LetStatement:
+		p.println("let " + p.expression(s.Name) + " = " + p.expression(s.Value) + ";")
+
+	case *ast.DoStatement:
+		p.println("do " + p.expression(s.Expression) + ";")
+
+	case *ast.ReturnStatement:
+		if s.Value == nil {
+			p.println("return;")
+		} else {
+			p.println("return " + p.expression(s.Value) + ";")
+		}
+
+	case *ast.WhileStatement:
+		p.open("while (" + p.expression(s.Expression) + ") {")
+		p.block(s.Statements, s.End)
+
+	case *ast.IfStatement:
+		p.open("if (" + p.expression(s.Expression) + ") {")
+		p.block(s.Statements, s.End)
+
+		if s.Else.Type == token.ELSE {
+			// the else joins the closing brace unless comments come between them
+			n := len(p.comments)
+			p.flush(s.Else.Pos)
+			p.seen(s.Else.Pos)
+			if len(p.comments) == n {
+				p.lines = p.lines[:len(p.lines)-1]
+				p.open("} else {")
+			} else {
+				p.open("else {")
+			}
+			p.block(s.ElseStatements, s.ElseEnd)
+		}
+	}
+}
+
+// block prints the statements of an opened block and closes it
+func (p *printer) block(stmts []ast.StatementNode, end token.Pos) {
+	for _, stmt := range stmts {
+		p.statement(stmt)
+	}
+	p.close(end)
+}
+
+// ----------------------------------------------------------------------------
+// expressions ----------------------------------------------------------------
+// ----------------------------------------------------------------------------
+
+// expression returns the source of an expression, binary ops are spaced and
+// the parentheses of the source are kept so it parses back the same way
+func (p *printer) expression(exp ast.ExpressionNode) string {
+	p.seen(exp.Pos())
+
+	switch e := exp.(type) {
+	case *ast.Identifier:
+		return e.Name
+
+	case *ast.IndexIdentifier:
+		return e.Name + "[" + p.expression(e.Index) + "]"
+
+	case *ast.StringLiteral:
+		return "\"" + e.Value + "\""
+
+	case *ast.IntLiteral:
+		return e.Token.Literal
+
+	case *ast.KeywordConstant:
+		return e.Value
+
+	case *ast.SubroutineCall:
+		var sb strings.Builder
+		if e.Class != nil {
+			sb.WriteString(e.Class.Name)
+			sb.WriteString(".")
+		}
+		p.seen(e.Name.Pos())
+		sb.WriteString(e.Name.Name)
+		sb.WriteString("(")
+		for i, arg := range e.Arguments {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			sb.WriteString(p.expression(arg))
+		}
+		sb.WriteString(")")
+		return sb.String()
+
+	case *ast.BinaryExpression:
+		left := p.expression(e.Left)
+		p.seen(e.Op.Pos)
+		return left + " " + e.Op.Literal + " " + p.expression(e.Right)
+
+	case *ast.UnaryExpression:
+		return e.Prefix.Literal + p.expression(e.Term)
+
+	case *ast.ParenExpression:
+		return "(" + p.expression(e.Term) + ")"
+	}
+
+	return exp.String()
+}
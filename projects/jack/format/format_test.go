@@ -0,0 +1,176 @@
+package format
+
+import (
+	"io/ioutil"
+	"jack/compiler"
+	"jack/lexer"
+	"jack/parser"
+	"jack/token"
+	"path/filepath"
+	"testing"
+)
+
+func TestSource(t *testing.T) {
+	input := `// header
+
+/** Doc
+  * more */
+class Foo { // the class
+   field int x,y;   // coords
+   static boolean b;
+
+
+   constructor Foo new(int ax,int ay) {
+      let x=ax; let y = -ay;
+      if (x<(y*2)) { // small
+         do Output.printString("hi");
+      } // end if
+      else {
+         let b=~b;
+         // last in else
+      }
+      while (~(x=0)) { let x = x - 1; }
+      if (b) { return this; } else { return null; }
+   }
+}
+// tail`
+
+	expected := `// header
+
+/** Doc
+ * more */
+class Foo { // the class
+    field int x, y; // coords
+    static boolean b;
+
+    constructor Foo new(int ax, int ay) {
+        let x = ax;
+        let y = -ay;
+        if (x < (y * 2)) { // small
+            do Output.printString("hi");
+        } // end if
+        else {
+            let b = ~b;
+            // last in else
+        }
+        while (~(x = 0)) {
+            let x = x - 1;
+        }
+        if (b) {
+            return this;
+        } else {
+            return null;
+        }
+    }
+}
+// tail
+`
+
+	actual, err := Source("Foo.jack", input)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if actual != expected {
+		t.Errorf("format wrong.\nexpected:\n%s\ngot:\n%s", expected, actual)
+	}
+}
+
+func TestSourceError(t *testing.T) {
+	if _, err := Source("Foo.jack", "class Foo { let }"); err == nil {
+		t.Errorf("expected a syntax error")
+	}
+}
+
+// every class of the projects formats to code that compiles the same and
+// keeps its comments, and formatting it again changes nothing
+func TestProjects(t *testing.T) {
+	var files []string
+	for _, pattern := range []string{"../../09/*/*.jack", "../../10/*/*.jack", "../../11/*/*.jack", "../../12/*.jack", "../../12/*/*.jack"} {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		files = append(files, matches...)
+	}
+	if len(files) == 0 {
+		t.Fatalf("no jack files found")
+	}
+
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+
+		formatted, err := Source(file, string(data))
+		if err != nil {
+			t.Errorf("%s : %s", file, err.Error())
+			continue
+		}
+
+		again, err := Source(file, formatted)
+		if err != nil {
+			t.Errorf("%s : formatted code does not parse: %s", file, err.Error())
+			continue
+		}
+		if again != formatted {
+			t.Errorf("%s : formatting is not idempotent\n%s", file, Diff(file, formatted, again))
+		}
+
+		if compile(t, string(data)) != compile(t, formatted) {
+			t.Errorf("%s : formatted code compiles differently", file)
+		}
+
+		if comments(string(data)) != comments(formatted) {
+			t.Errorf("%s : expected %d comments, got: %d", file, comments(string(data)), comments(formatted))
+		}
+	}
+}
+
+func compile(t *testing.T, src string) string {
+	class, err := parser.New(lexer.New(src)).ParseClass()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	code, err := compiler.Compile(class)
+	if err != nil {
+		// the classes of project 10 are only parsed, not compiled
+		return err.Error()
+	}
+	return code
+}
+
+func comments(src string) int {
+	l := lexer.New(src)
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+	}
+	return len(l.Comments())
+}
+
+func TestDiff(t *testing.T) {
+	a := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\n"
+	b := "a\nB\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\n"
+
+	expected := `--- x.orig
++++ x
+@@ -1,5 +1,5 @@
+ a
+-b
++B
+ c
+ d
+ e
+@@ -9,3 +9,4 @@
+ i
+ j
+ k
++l
+`
+	if actual := Diff("x", a, b); actual != expected {
+		t.Errorf("diff wrong.\nexpected:\n%s\ngot:\n%s", expected, actual)
+	}
+
+	if actual := Diff("x", a, a); actual != "" {
+		t.Errorf("expected no diff, got:\n%s", actual)
+	}
+}
@@ -1,21 +1,46 @@
 package lexer
 
-import "jack/token"
+import (
+	"jack/token"
+	"strconv"
+	"strings"
+)
 
 type Lexer struct {
+	file         string
 	input        string
 	position     int  // current position in input (points to current char)
 	readPosition int  // current reading position in input (after current char)
 	ch           byte // current char under examination
+	line         int  // line of the current char
+	column       int  // column of the current char
+	doc          string // text of the last doc comment, given to the next token
+	comments     []token.Comment
 }
 
 func New(input string) *Lexer {
-	l := &Lexer{input: input}
+	return NewFile("", input)
+}
+
+// NewFile creates a lexer whose token positions refer to the given file
+func NewFile(file, input string) *Lexer {
+	l := &Lexer{file: file, input: input, line: 1}
 	l.readChar()
 	return l
 }
 
+func (l *Lexer) pos() token.Pos {
+	return token.Pos{File: l.file, Line: l.line, Column: l.column}
+}
+
 func (l *Lexer) readChar() {
+	if l.ch == '\n' {
+		l.line++
+		l.column = 1
+	} else {
+		l.column++
+	}
+
 	if l.readPosition >= len(l.input) {
 		l.ch = 0
 	} else {
@@ -26,7 +51,7 @@ func (l *Lexer) readChar() {
 }
 
 func (l *Lexer) peekChar() byte {
-	if l.readPosition >= len(l.input) - 1 {
+	if l.readPosition >= len(l.input) {
 		return 0
 	} else {
 		return l.input[l.readPosition]
@@ -35,10 +60,12 @@ func (l *Lexer) peekChar() byte {
 
 func (l *Lexer) NextToken() token.Token {
 	var tok token.Token
+	var pos token.Pos
 	ok := false
 
 	for !ok {
 		l.skipWhitespace()
+		pos = l.pos()
 		switch l.ch {
 		case '{':
 			ok = true
@@ -78,9 +105,21 @@ func (l *Lexer) NextToken() token.Token {
 			tok = token.New(token.ASTERISK, l.ch)
 		case '/':
 			if  l.peekChar() == '/' {
+				start := l.position
 				l.skipLine()
+				l.comment(pos, l.input[start:l.position])
 			} else if l.peekChar() == '*' {
-				l.skipComment()
+				start := l.position
+				if !l.skipComment() {
+					tok = illegal(token.UnterminatedComment, l.input[start:])
+					tok.Pos = pos
+					return tok
+				}
+				comment := l.input[start:l.position]
+				if isDoc(comment) {
+					l.doc = docText(comment)
+				}
+				l.comment(pos, comment)
 			} else {
 				tok = token.New(token.SLASH, l.ch)
 				ok = true
@@ -103,56 +142,128 @@ func (l *Lexer) NextToken() token.Token {
 		case '~':
 			ok = true
 			tok = token.New(token.NOT, l.ch)
-			case '"': 
+		case '"':
 			ok = true
-			tok.Type = token.STRING
-			tok.Literal = l.readString()
+			str, reason := l.readString()
+			if reason != "" {
+				tok = illegal(reason, str)
+			} else {
+				tok.Type = token.STRING
+				tok.Literal = str
+			}
 		case 0:
 			ok = true
-			tok.Literal = ""
-			tok.Type = token.EOF
-			
+			if l.atEOF() {
+				tok.Literal = ""
+				tok.Type = token.EOF
+			} else {
+				tok = illegal(token.UnexpectedByte, string(l.ch))
+			}
+
 		default:
 			if isLetter(l.ch) {
 				tok.Literal = l.readIdentifier()
 				tok.Type = token.LookupIdent(tok.Literal)
-				} else if isDigit(l.ch) {
-					tok.Literal = l.readNumber()
-					tok.Type = token.INT
-				} else {
-					tok = token.New(token.ILLEGAL, l.ch)
+			} else if isDigit(l.ch) {
+				tok.Literal = l.readNumber()
+				tok.Type = token.INT
+				if n, err := strconv.Atoi(tok.Literal); err != nil || n > token.MaxInt {
+					tok = illegal(token.IntOutOfRange, tok.Literal)
 				}
+			} else {
+				tok = illegal(token.UnexpectedByte, string(l.ch))
+				l.readChar()
+			}
+			tok.Pos = pos
+			tok.Doc = l.takeDoc()
 			return tok
 		}
 	}
 	l.readChar()
+	tok.Pos = pos
+	tok.Doc = l.takeDoc()
 	return tok
 }
 
+// takeDoc hands the last doc comment to the token being returned
+func (l *Lexer) takeDoc() string {
+	doc := l.doc
+	l.doc = ""
+	return doc
+}
+
+// Comments returns the comments read so far in the order they appear
+func (l *Lexer) Comments() []token.Comment {
+	return l.comments
+}
+
+func (l *Lexer) comment(pos token.Pos, text string) {
+	text = strings.TrimRight(text, " \t\r")
+	l.comments = append(l.comments, token.Comment{Pos: pos, Text: text})
+}
+
 func (l *Lexer) skipWhitespace() {
 	for l.ch == ' ' || l.ch == '\t' || l.ch == '\n' || l.ch == '\r' {
 		l.readChar()
 	}
 }
 
+// atEOF reports whether the whole input has been read, a 0 byte before the
+// end is not the end
+func (l *Lexer) atEOF() bool {
+	return l.position >= len(l.input)
+}
+
+func illegal(reason, literal string) token.Token {
+	return token.Token{Type: token.ILLEGAL, Literal: literal, Reason: reason}
+}
+
 func (l *Lexer) skipLine() {
-	for l.ch != '\n' {
+	for l.ch != '\n' && !l.atEOF() {
 		l.readChar()
 	}
 }
 
-func (l *Lexer) skipComment() {
-	prev := l.ch
-	for !(prev == '*' && l.ch == '/') || l.ch == 0 {
-		prev = l.ch
+// skipComment skips a /* */ comment, it returns false when the input ends
+// before the comment does
+func (l *Lexer) skipComment() bool {
+	// the opening /* can not also close the comment
+	l.readChar()
+	l.readChar()
+
+	for !(l.ch == '*' && l.peekChar() == '/') {
+		if l.atEOF() {
+			return false
+		}
 		l.readChar()
 	}
 	l.readChar()
+	l.readChar()
+	return true
+}
+
+// isDoc reports whether a block comment is a /** doc comment, /**/ is not
+func isDoc(comment string) bool {
+	return strings.HasPrefix(comment, "/**") && comment != "/**/"
+}
+
+// docText strips the comment markers, and the * starting each line, from a
+// doc comment
+func docText(comment string) string {
+	comment = strings.TrimSuffix(strings.TrimPrefix(comment, "/**"), "*/")
+
+	lines := strings.Split(comment, "\n")
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		line = strings.TrimPrefix(line, "*")
+		lines[i] = strings.TrimSpace(line)
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
 }
 
 func (l *Lexer) readIdentifier() string {
 	position := l.position
-	for isLetter(l.ch) {
+	for isLetter(l.ch) || isDigit(l.ch) {
 		l.readChar()
 	}
 	return l.input[position:l.position]
@@ -166,15 +277,21 @@ func (l *Lexer) readNumber() string {
 	return l.input[position:l.position]
 }
 
-func (l *Lexer) readString() string {
+// readString reads a string constant, the reason is set when it is not
+// closed before the end of the line
+func (l *Lexer) readString() (string, string) {
 	position := l.position + 1
 	for {
 		l.readChar()
-		if l.ch == '"' {
-			break
+		switch {
+		case l.ch == '"':
+			return l.input[position:l.position], ""
+		case l.atEOF():
+			return l.input[position:], token.UnterminatedString
+		case l.ch == '\n' || l.ch == '\r':
+			return l.input[position:l.position], token.NewlineInString
 		}
 	}
-	return l.input[position:l.position]
 }
 
 
@@ -73,4 +73,185 @@ func TestLexer(t *testing.T) {
 			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q", i, tt.Literal, tok.Literal)
 		}
 	}
-}
\ No newline at end of file
+}
+
+func TestLexerIdentifierDigits(t *testing.T) {
+	l := New("let x1 = y2z;")
+
+	expected := []token.Token{
+		{Type: token.LET, Literal: "let"},
+		{Type: token.IDENT, Literal: "x1"},
+		{Type: token.EQ, Literal: "="},
+		{Type: token.IDENT, Literal: "y2z"},
+		{Type: token.SEMICOLON, Literal: ";"},
+	}
+
+	for i, tt := range expected {
+		tok := l.NextToken()
+
+		if tok.Type != tt.Type || tok.Literal != tt.Literal {
+			t.Fatalf("tests[%d] - expected=%v, got=%v", i, tt, tok)
+		}
+	}
+}
+
+func TestLexerPositions(t *testing.T) {
+	const input = "class Foo {\n\t// comment\n  let x = \"a b\";\n}"
+
+	expected := []token.Pos{
+		{File: "Foo.jack", Line: 1, Column: 1},
+		{File: "Foo.jack", Line: 1, Column: 7},
+		{File: "Foo.jack", Line: 1, Column: 11},
+		{File: "Foo.jack", Line: 3, Column: 3},
+		{File: "Foo.jack", Line: 3, Column: 7},
+		{File: "Foo.jack", Line: 3, Column: 9},
+		{File: "Foo.jack", Line: 3, Column: 11},
+		{File: "Foo.jack", Line: 3, Column: 16},
+		{File: "Foo.jack", Line: 4, Column: 1},
+	}
+
+	l := NewFile("Foo.jack", input)
+
+	for i, pos := range expected {
+		tok := l.NextToken()
+
+		if tok.Pos != pos {
+			t.Fatalf("tests[%d] - position of %q wrong. expected=%v, got=%v", i, tok.Literal, pos, tok.Pos)
+		}
+	}
+
+	if s := expected[3].String(); s != "Foo.jack:3:3" {
+		t.Fatalf("position string wrong. expected=Foo.jack:3:3, got=%s", s)
+	}
+}
+
+func TestLexerIllegal(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected token.Token
+		// next is the token after the illegal one
+		next token.Type
+	}{
+		{"\"abc", token.Token{Type: token.ILLEGAL, Literal: "abc", Reason: token.UnterminatedString}, token.EOF},
+		{"\"ab\nc", token.Token{Type: token.ILLEGAL, Literal: "ab", Reason: token.NewlineInString}, token.IDENT},
+		{"/* abc", token.Token{Type: token.ILLEGAL, Literal: "/* abc", Reason: token.UnterminatedComment}, token.EOF},
+		{"/*/ x", token.Token{Type: token.ILLEGAL, Literal: "/*/ x", Reason: token.UnterminatedComment}, token.EOF},
+		{"32768;", token.Token{Type: token.ILLEGAL, Literal: "32768", Reason: token.IntOutOfRange}, token.SEMICOLON},
+		{"99999999999999999999", token.Token{Type: token.ILLEGAL, Literal: "99999999999999999999", Reason: token.IntOutOfRange}, token.EOF},
+		{"#x", token.Token{Type: token.ILLEGAL, Literal: "#", Reason: token.UnexpectedByte}, token.IDENT},
+		{"\x00x", token.Token{Type: token.ILLEGAL, Literal: "\x00", Reason: token.UnexpectedByte}, token.IDENT},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+
+		tok := l.NextToken()
+		tok.Pos = token.Pos{}
+		if tok != tt.expected {
+			t.Errorf("%q - expected=%+v, got=%+v", tt.input, tt.expected, tok)
+		}
+		if next := l.NextToken(); next.Type != tt.next {
+			t.Errorf("%q - next token wrong. expected=%q, got=%q", tt.input, tt.next, next.Type)
+		}
+	}
+}
+
+func TestLexerComments(t *testing.T) {
+	// a line comment at the very end of the input and an empty block comment
+	l := New("x /**/ y // the end")
+
+	for _, expected := range []token.Type{token.IDENT, token.IDENT, token.EOF} {
+		if tok := l.NextToken(); tok.Type != expected {
+			t.Fatalf("expected=%q, got=%q", expected, tok.Type)
+		}
+	}
+
+	expected := []token.Comment{
+		{Pos: token.Pos{Line: 1, Column: 3}, Text: "/**/"},
+		{Pos: token.Pos{Line: 1, Column: 10}, Text: "// the end"},
+	}
+	comments := l.Comments()
+	if len(comments) != len(expected) {
+		t.Fatalf("expected %d comments, got=%v", len(expected), comments)
+	}
+	for i, c := range expected {
+		if comments[i] != c {
+			t.Errorf("comment %d wrong. expected=%v, got=%v", i, c, comments[i])
+		}
+	}
+}
+
+func TestLexerMaxInt(t *testing.T) {
+	tok := New("32767").NextToken()
+	if tok.Type != token.INT || tok.Literal != "32767" {
+		t.Fatalf("expected=INT 32767, got=%+v", tok)
+	}
+}
+
+func FuzzNextToken(f *testing.F) {
+	for _, seed := range []string{
+		"class Foo { function void main() { let x = \"a\"; return; } }",
+		"\"abc", "/* abc", "// abc", "/*/", "32768", "#", "\x00", "/", "\"\n",
+	} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		l := New(input)
+
+		// every token but EOF reads at least one byte
+		for i := 0; ; i++ {
+			if i > len(input) {
+				t.Fatalf("no EOF after %d tokens of %q", i, input)
+			}
+			tok := l.NextToken()
+			if tok.Type == token.EOF {
+				break
+			}
+			if tok.Type == token.ILLEGAL && tok.Reason == "" {
+				t.Fatalf("illegal token with no reason: %+v", tok)
+			}
+		}
+
+		// and EOF repeats
+		if tok := l.NextToken(); tok.Type != token.EOF {
+			t.Fatalf("expected EOF after EOF, got: %+v", tok)
+		}
+	})
+}
+
+func TestLexerDocComments(t *testing.T) {
+	const input = `/** A class.
+ *  Second line.
+ */
+class Foo {
+	/* not a doc comment */
+	field int x;
+
+	/** Does bar. */
+	// a line comment between is fine
+	method void bar() {}
+	/**/ function
+}`
+
+	expected := map[string]string{
+		"class":    "A class.\nSecond line.",
+		"field":    "",
+		"method":   "Does bar.",
+		"function": "",
+	}
+
+	l := New(input)
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		doc, ok := expected[tok.Literal]
+		if !ok {
+			if tok.Doc != "" {
+				t.Errorf("%q - expected no doc, got=%q", tok.Literal, tok.Doc)
+			}
+			continue
+		}
+		if tok.Doc != doc {
+			t.Errorf("%q - doc wrong. expected=%q, got=%q", tok.Literal, doc, tok.Doc)
+		}
+	}
+}
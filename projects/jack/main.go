@@ -1,30 +1,91 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"io/fs"
 	"io/ioutil"
+	"jack/ast"
+	"jack/check"
+	"jack/compiler"
+	"jack/doc"
+	"jack/format"
 	"jack/lexer"
+	"jack/osapi"
 	"jack/parser"
+	"jack/xml"
 	"log"
 	"os"
 	"path/filepath"
 )
 
+var xmlOutput = flag.Bool("xml", false, "also write the token (<name>T.xml) and parse tree (<name>.xml) files")
+var maxErrors = flag.Int("maxerrors", parser.DefaultMaxErrors, "number of syntax errors reported per class before giving up, 0 for no limit")
+var sourceMap = flag.Bool("sourcemap", false, "also write a <name>.vm.map json file giving the jack line of each vm line")
+var precedence = flag.Bool("precedence", false, "parse binary ops with C like precedence instead of strictly left to right")
+var checkFirst = flag.Bool("check", true, "check the classes of a directory for undeclared names, bad calls and returns before compiling them")
+
 func main(){
+	flag.Parse()
+
+	if flag.Arg(0) == "doc" {
+		if err := writeDoc(flag.Args()[1:]); err != nil {
+			fmt.Fprintln(os.Stderr, "Error: writing doc")
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+
+	if flag.Arg(0) == "check" {
+		if flag.NArg() != 2 {
+			fmt.Println("useage: jack check <path>")
+			os.Exit(2)
+		}
+		if err := checkPath(flag.Arg(1)); err != nil {
+			fmt.Println(err.Error())
+			os.Exit(1)
+		}
+		fmt.Println("No problems found")
+		return
+	}
+
+	if flag.Arg(0) == "fmt" {
+		if err := formatFiles(flag.Args()[1:]); err != nil {
+			fmt.Fprintln(os.Stderr, "Error: formatting")
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+		return
+	}
 
 	// check args
-	if len(os.Args) != 2 {
+	if flag.NArg() != 1 {
 		fmt.Println("Error: No file name provided")
-		fmt.Println("useage: jack <path>")
+		fmt.Println("useage: jack [-xml] [-maxerrors n] [-precedence] [-sourcemap] [-check=false] <path>")
+		fmt.Println("       jack check <path>")
+		fmt.Println("       jack doc [-html] [-title title] [-o file] <path>")
+		fmt.Println("       jack fmt [-w] [-d] <path>")
+		fmt.Println("       a path of - reads a class from stdin and writes the vm code to stdout")
 		return
 	}
 
-	path := os.Args[1]
+	path := flag.Arg(0)
 
-	if isFile(path){
+	if path == "-" {
+
+		if err := translateStdin(); err != nil {
+			fmt.Fprintln(os.Stderr, "Error: translating stdin")
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+
+	} else if isFile(path){
 		if !checkExt(path) {
-			fmt.Printf("Invalid file type, expected: '.vm', got: '%v'\n", filepath.Ext(path))
+			fmt.Printf("Invalid file type, expected: '.jack', got: '%v'\n", filepath.Ext(path))
 			return
 		}
 		
@@ -95,39 +156,273 @@ func isDir(filename string) bool {
 }
 
 func translateFile(path string) error {
-	fileOutPath := replaceExt(path, ".xml")
+	fileOutPath := replaceExt(path, ".vm")
 
 	// translate code
 	data := readFile(path)
-	lexer := lexer.New(data)
+
+	if *xmlOutput {
+		tokens, err := xml.Tokens(lexer.NewFile(path, data))
+		if err != nil {
+			return err
+		}
+		writeFile(replaceExt(path, "T.xml"), tokens)
+	}
+
+	lexer := lexer.NewFile(path, data)
 	parser := parser.New(lexer)
-	code, err := parser.ParseFile();
+	parser.MaxErrors = *maxErrors
+	parser.Precedence = *precedence
+	class, err := parser.ParseClass()
+	if err != nil {
+		return err
+	}
+
+	if *xmlOutput {
+		writeFile(replaceExt(path, ".xml"), xml.Class(class))
+	}
+
+	code, lines, err := compiler.CompileWith(class, compiler.Options{ OS: osClasses(filepath.Dir(path)) })
 	if err != nil {
 		return err
 	}
 
 	// write file
 	writeFile(fileOutPath, code)
+
+	if *sourceMap {
+		data, err := json.Marshal(lines)
+		if err != nil {
+			return err
+		}
+		writeFile(fileOutPath + ".map", string(data))
+	}
 	fmt.Println("Success!!")
 	fmt.Printf("output file: %v\n", fileOutPath)
 
 	return nil
 }
 
+// osClasses returns the os classes calls are checked against when compiling
+// the classes in dir, leaving out any the directory defines itself
+func osClasses(dir string) map[string]*osapi.Class {
+	classes := map[string]*osapi.Class{}
+	for name, class := range osapi.Classes {
+		if !isFile(filepath.Join(dir, name + ".jack")) {
+			classes[name] = class
+		}
+	}
+	return classes
+}
+
+// translateStdin compiles a class piped in and writes the vm code to stdout
+// so it can be piped on to vmt
+func translateStdin() error {
+	if *xmlOutput || *sourceMap {
+		return errors.New("-xml and -sourcemap need an output file")
+	}
+
+	data, err := ioutil.ReadAll(os.Stdin)
+	if err != nil {
+		return err
+	}
+
+	parser := parser.New(lexer.NewFile("-", string(data)))
+	parser.MaxErrors = *maxErrors
+	parser.Precedence = *precedence
+	class, err := parser.ParseClass()
+	if err != nil {
+		return err
+	}
+
+	code, _, err := compiler.CompileWith(class, compiler.Options{ OS: osapi.Classes })
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(os.Stdout, code)
+	return err
+}
+
+// parseClasses parses the class at path, or every class in it when it is a
+// directory, reporting all the files with errors
+func parseClasses(path string) ([]*ast.ClassDeclaration, error) {
+	files := []string{ path }
+	if isDir(path) {
+		var err error
+		if files, err = filepath.Glob(filepath.Join(path, "*.jack")); err != nil {
+			return nil, err
+		}
+	} else if !isFile(path) {
+		return nil, errors.New(fmt.Sprintf("could not find file: %v", path))
+	}
+
+	var classes []*ast.ClassDeclaration
+	var errs parser.ErrorList
+
+	for _, file := range files {
+		p := parser.New(lexer.NewFile(file, readFile(file)))
+		p.MaxErrors = *maxErrors
+		p.Precedence = *precedence
+		class, err := p.ParseClass()
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		classes = append(classes, class)
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return classes, nil
+}
+
+// checkPath runs the checker over the classes at path, together with the os
+func checkPath(path string) error {
+	classes, err := parseClasses(path)
+	if err != nil {
+		return err
+	}
+	return check.Check(classes, check.Options{ External: check.OS })
+}
+
+// writeDoc renders the doc comments of the classes at a path as a markdown
+// or html api reference
+func writeDoc(args []string) error {
+	flags := flag.NewFlagSet("doc", flag.ExitOnError)
+	asHTML := flags.Bool("html", false, "write html instead of markdown")
+	title := flags.String("title", "", "title of the reference, the name of the directory by default")
+	out := flags.String("o", "", "file to write the reference to instead of stdout")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		return errors.New("useage: jack doc [-html] [-title title] [-o file] <path>")
+	}
+	path := flags.Arg(0)
+
+	classes, err := parseClasses(path)
+	if err != nil {
+		return err
+	}
+
+	if *title == "" {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return err
+		}
+		*title = removeExt(filepath.Base(abs)) + " API"
+	}
+
+	var ref string
+	if *asHTML {
+		ref = doc.HTML(*title, classes)
+	} else {
+		ref = doc.Markdown(*title, classes)
+	}
+
+	if *out == "" {
+		_, err := io.WriteString(os.Stdout, ref)
+		return err
+	}
+	writeFile(*out, ref)
+	return nil
+}
+
+// formatFiles formats the classes at a path, printing them to stdout unless
+// they are written back or diffed
+func formatFiles(args []string) error {
+	flags := flag.NewFlagSet("fmt", flag.ExitOnError)
+	write := flags.Bool("w", false, "write the result back to the files instead of stdout")
+	diff := flags.Bool("d", false, "print a diff of the changes instead of the formatted classes")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		return errors.New("useage: jack fmt [-w] [-d] <path>")
+	}
+	path := flags.Arg(0)
+
+	if path == "-" {
+		data, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+		formatted, err := format.Source(path, string(data))
+		if err != nil {
+			return err
+		}
+		if *diff {
+			formatted = format.Diff(path, string(data), formatted)
+		}
+		_, err = io.WriteString(os.Stdout, formatted)
+		return err
+	}
+
+	files := []string{ path }
+	if isDir(path) {
+		var err error
+		if files, err = filepath.Glob(filepath.Join(path, "*.jack")); err != nil {
+			return err
+		}
+	} else if !isFile(path) {
+		return errors.New(fmt.Sprintf("could not find file: %v", path))
+	}
+
+	// format every class, reporting all the files with errors
+	var errs parser.ErrorList
+
+	for _, file := range files {
+		data := readFile(file)
+		formatted, err := format.Source(file, data)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		if *diff {
+			io.WriteString(os.Stdout, format.Diff(file, data, formatted))
+		}
+		if *write {
+			if formatted != data {
+				writeFile(file, formatted)
+			}
+		} else if !*diff {
+			io.WriteString(os.Stdout, formatted)
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
 func translateDir(dir string) error {
-	// get .vm files
+	// get .jack files
 	files, err := filepath.Glob(filepath.Join(dir, "*.jack"))
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	// translate code
+	// syntax errors are left for the translation to report
+	if *checkFirst {
+		if classes, err := parseClasses(dir); err == nil {
+			if err := check.Check(classes, check.Options{ External: check.OS }); err != nil {
+				return err
+			}
+		}
+	}
+
+	// translate code, carrying on past bad files so every error is reported
+	var errs parser.ErrorList
 
 	for _, file := range files {
 		if err := translateFile(file); err != nil {
-			return err
+			errs = append(errs, err)
 		}
 	}
-	
+
+	if len(errs) > 0 {
+		return errs
+	}
 	return nil
 }
\ No newline at end of file
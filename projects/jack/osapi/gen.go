@@ -0,0 +1,42 @@
+//go:build ignore
+
+// gen writes api.json from the os classes in projects/12
+package main
+
+import (
+	"io/ioutil"
+	"jack/lexer"
+	"jack/osapi"
+	"jack/parser"
+	"log"
+	"path/filepath"
+)
+
+func main() {
+	files, err := filepath.Glob("../../12/*.jack")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var classes []*osapi.Class
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		class, err := parser.New(lexer.NewFile(file, string(data))).ParseClass()
+		if err != nil {
+			log.Fatal(err)
+		}
+		classes = append(classes, osapi.FromClass(class))
+	}
+
+	data, err := osapi.Encode(classes)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := ioutil.WriteFile("api.json", data, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
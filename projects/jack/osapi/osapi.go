@@ -0,0 +1,100 @@
+// Package osapi describes the classes of the jack os: Math, String, Array,
+// Output, Screen, Keyboard, Memory and Sys. The description is read from
+// api.json, which is generated from the os classes in projects/12 so calls
+// to the os can be checked when its sources are not being compiled.
+package osapi
+
+//go:generate go run gen.go
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"jack/ast"
+)
+
+// Class is an os class and its subroutines in the order they are declared
+type Class struct {
+	Name        string        `json:"name"`
+	Doc         string        `json:"doc,omitempty"`
+	Subroutines []*Subroutine `json:"subroutines"`
+}
+
+// Subroutine is the signature of a subroutine of an os class
+type Subroutine struct {
+	// Kind is constructor, function or method
+	Kind       string  `json:"kind"`
+	ReturnType string  `json:"returnType"`
+	Name       string  `json:"name"`
+	Params     []Param `json:"params"`
+	Doc        string  `json:"doc,omitempty"`
+}
+
+type Param struct {
+	Type string `json:"type"`
+	Name string `json:"name"`
+}
+
+//go:embed api.json
+var api []byte
+
+// Classes holds the os classes by name
+var Classes = map[string]*Class{}
+
+func init() {
+	var classes []*Class
+	if err := json.Unmarshal(api, &classes); err != nil {
+		panic(err)
+	}
+	for _, class := range classes {
+		Classes[class.Name] = class
+	}
+}
+
+// Subroutine finds a subroutine of the class by name
+func (c *Class) Subroutine(name string) (*Subroutine, bool) {
+	for _, s := range c.Subroutines {
+		if s.Name == name {
+			return s, true
+		}
+	}
+	return nil, false
+}
+
+// FromClass describes a parsed class the way api.json does
+func FromClass(class *ast.ClassDeclaration) *Class {
+	c := &Class{Name: class.Name.Name, Doc: class.Doc, Subroutines: []*Subroutine{}}
+
+	for _, stmt := range class.Body {
+		sd, ok := stmt.(*ast.SubroutineDeclaration)
+		if !ok {
+			continue
+		}
+
+		s := &Subroutine{
+			Kind:       sd.Decelration.Literal,
+			ReturnType: sd.ReturnType.Literal,
+			Name:       sd.Name.Name,
+			Params:     []Param{},
+			Doc:        sd.Doc,
+		}
+		for _, param := range sd.Parameters {
+			s.Params = append(s.Params, Param{Type: param.Type.Literal, Name: param.Name.Name})
+		}
+		c.Subroutines = append(c.Subroutines, s)
+	}
+
+	return c
+}
+
+// Encode writes classes out as api.json
+func Encode(classes []*Class) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "\t")
+	if err := enc.Encode(classes); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,81 @@
+package osapi
+
+import (
+	"io/ioutil"
+	"jack/lexer"
+	"jack/parser"
+	"path/filepath"
+	"testing"
+)
+
+// api.json matches the os classes it is generated from, run go generate
+// when they change
+func TestGenerated(t *testing.T) {
+	files, err := filepath.Glob("../../12/*.jack")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	var classes []*Class
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		class, err := parser.New(lexer.NewFile(file, string(data))).ParseClass()
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		classes = append(classes, FromClass(class))
+	}
+
+	data, err := Encode(classes)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if string(data) != string(api) {
+		t.Errorf("api.json is out of date with projects/12, run go generate")
+	}
+}
+
+func TestClasses(t *testing.T) {
+	for _, name := range []string{"Math", "String", "Array", "Output", "Screen", "Keyboard", "Memory", "Sys"} {
+		if _, ok := Classes[name]; !ok {
+			t.Errorf("expected os class %s", name)
+		}
+	}
+
+	tests := []struct {
+		class, name string
+		kind        string
+		params      []string
+		returnType  string
+	}{
+		{"Output", "printString", "function", []string{"String"}, "void"},
+		{"Screen", "drawRectangle", "function", []string{"int", "int", "int", "int"}, "void"},
+		{"Memory", "alloc", "function", []string{"int"}, "int"},
+		{"String", "new", "constructor", []string{"int"}, "String"},
+		{"String", "appendChar", "method", []string{"char"}, "String"},
+	}
+
+	for _, tt := range tests {
+		s, ok := Classes[tt.class].Subroutine(tt.name)
+		if !ok {
+			t.Errorf("expected %s.%s", tt.class, tt.name)
+			continue
+		}
+		if s.Kind != tt.kind || s.ReturnType != tt.returnType || len(s.Params) != len(tt.params) {
+			t.Errorf("%s.%s wrong. got: %s %s %v", tt.class, tt.name, s.Kind, s.ReturnType, s.Params)
+			continue
+		}
+		for i, p := range s.Params {
+			if p.Type != tt.params[i] {
+				t.Errorf("%s.%s param %d expected: %s, got: %s", tt.class, tt.name, i, tt.params[i], p.Type)
+			}
+		}
+	}
+
+	if _, ok := Classes["Math"].Subroutine("pow"); ok {
+		t.Errorf("expected no Math.pow")
+	}
+}
@@ -7,16 +7,36 @@ import (
 	"jack/lexer"
 	"jack/token"
 	"strconv"
+	"strings"
 )
 
+// DefaultMaxErrors is the error cap a new parser starts with
+const DefaultMaxErrors = 10
+
 type Parser struct {
 	lexer *lexer.Lexer
 	curToken token.Token
 	peekToken token.Token
+	errors ErrorList
+
+	// afterIllegal is set when illegal tokens were skipped before peekToken,
+	// suppress once that token is reached. Errors are not reported while
+	// suppress is set as they follow from the token skipped, it is cleared
+	// at the end of the statement or declaration.
+	afterIllegal bool
+	suppress bool
+
+	// MaxErrors is the number of errors after which parsing gives up,
+	// 0 means every error is reported
+	MaxErrors int
+
+	// Precedence parses binary ops with C like precedence instead of
+	// evaluating them left to right
+	Precedence bool
 }
 
 func New(l *lexer.Lexer) *Parser {
-	p := &Parser{ lexer: l }
+	p := &Parser{ lexer: l, MaxErrors: DefaultMaxErrors }
 	p.eatToken()
 	p.eatToken()
 
@@ -24,16 +44,62 @@ func New(l *lexer.Lexer) *Parser {
 }
 
 func (p *Parser) ParseFile() (string, error) {
-	class, err := p.parseClassDeclaration()
+	class, err := p.ParseClass()
 	if err == nil {
 		return class.String(), nil
 	}
 	return "", err
 }
 
+// ParseClass parses a single class and returns its syntax tree. Errors
+// inside the class are recovered from and returned together as an ErrorList
+func (p *Parser) ParseClass() (*ast.ClassDeclaration, error) {
+	if !p.expect(token.CLASS) {
+		return nil, append(p.errors, tokenError("class", p.curToken))
+	}
+
+	class, err := p.parseClassDeclaration()
+	if err != nil && err != errEOF && !p.suppress {
+		p.errors = append(p.errors, err)
+	}
+
+	if len(p.errors) > 0 {
+		return nil, p.errors
+	}
+	return class, nil
+}
+
+// Errors returns the errors recovered from so far
+func (p *Parser) Errors() ErrorList {
+	return p.errors
+}
+
 func (p *Parser) eatToken() {
 	p.curToken = p.peekToken
-	p.peekToken = p.lexer.NextToken()
+	if p.afterIllegal {
+		p.suppress = true
+	}
+	p.afterIllegal = false
+	p.peekToken = p.nextToken()
+}
+
+// nextToken reads the next token from the lexer, illegal tokens are
+// reported as errors. An integer out of range is still parsed as one, other
+// illegal tokens are skipped.
+func (p *Parser) nextToken() token.Token {
+	tok := p.lexer.NextToken()
+	for tok.Type == token.ILLEGAL {
+		if p.MaxErrors == 0 || len(p.errors) < p.MaxErrors {
+			p.errors = append(p.errors, illegalError(tok))
+		}
+		if tok.Reason == token.IntOutOfRange {
+			tok.Type, tok.Reason = token.INT, ""
+			return tok
+		}
+		p.afterIllegal = true
+		tok = p.lexer.NextToken()
+	}
+	return tok
 }
 
 func (p *Parser) expect(token token.Type) bool {
@@ -60,106 +126,250 @@ func (p *Parser) peekAndEat(token token.Type) bool {
 	return b
 }
 
+// ErrorList holds every error found while parsing a class
+type ErrorList []error
+
+func (el ErrorList) Error() string {
+	var sb strings.Builder
+	for i, err := range el {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		sb.WriteString(err.Error())
+	}
+	return sb.String()
+}
+
+var errTooManyErrors = errors.New("too many errors")
+
+// errEOF stops parsing once an error at the end of the file is recorded,
+// every enclosing block would otherwise report the same missing }
+var errEOF = errors.New("unexpected end of file")
+
 // error helpers
-func tokenError(exp, got string) error {
-	return errors.New(fmt.Sprintf("unexpected token, expected: %s   got %s", exp, got))
+func parseError(tok token.Token, msg string) error {
+	return errors.New(fmt.Sprintf("%s: %s", tok.Pos, msg))
+}
+
+func illegalError(tok token.Token) error {
+	switch tok.Reason {
+	case token.IntOutOfRange:
+		return parseError(tok, fmt.Sprintf("%s: %s", tok.Reason, tok.Literal))
+	case token.UnexpectedByte:
+		return parseError(tok, fmt.Sprintf("%s: %q", tok.Reason, tok.Literal))
+	}
+	return parseError(tok, tok.Reason)
+}
+
+// literal names a token in errors, the end of the file has no literal
+func literal(tok token.Token) string {
+	if tok.Type == token.EOF {
+		return "EOF"
+	}
+	return tok.Literal
+}
+
+func tokenError(exp string, got token.Token) error {
+	return parseError(got, fmt.Sprintf("unexpected token, expected: %s   got %s", exp, literal(got)))
+}
+
+// ---------------------------------------------------------------------------------
+// Error recovery ------------------------------------------------------------------
+// ---------------------------------------------------------------------------------
+
+// addError records an error, it returns an error once parsing has to stop:
+// when there are too many errors or the error is at the end of the file
+func (p *Parser) addError(err error) error {
+	if err == errTooManyErrors || err == errEOF {
+		return err
+	}
+
+	if !p.suppress {
+		p.errors = append(p.errors, err)
+	}
+
+	if p.MaxErrors > 0 && len(p.errors) >= p.MaxErrors {
+		return errTooManyErrors
+	}
+	if p.expect(token.EOF) {
+		return errEOF
+	}
+	return nil
+}
+
+// syncStatement skips tokens until just after a ; or up to a } or the
+// start of the next statement, blocks opened while skipping are skipped whole
+func (p *Parser) syncStatement() {
+	depth := 0
+
+	for !p.expect(token.EOF) {
+		switch p.curToken.Type {
+		case token.LBRACE:
+			depth++
+		case token.RBRACE:
+			if depth == 0 {
+				return
+			}
+			depth--
+		case token.SEMICOLON:
+			if depth == 0 {
+				p.eatToken()
+				return
+			}
+		case token.LET, token.DO, token.IF, token.WHILE, token.RETURN, token.VAR:
+			if depth == 0 {
+				return
+			}
+		}
+		p.eatToken()
+	}
+}
+
+// syncDeclaration skips tokens until the next class level declaration or
+// the end of the class
+func (p *Parser) syncDeclaration() {
+	depth := 0
+
+	for !p.expect(token.EOF) {
+		switch p.curToken.Type {
+		case token.LBRACE:
+			depth++
+		case token.RBRACE:
+			if depth == 0 {
+				return
+			}
+			depth--
+		case token.STATIC, token.FIELD, token.CONSTRUCTOR, token.FUNCTION, token.METHOD:
+			if depth == 0 {
+				return
+			}
+		}
+		p.eatToken()
+	}
 }
 
 // ---------------------------------------------------------------------------------
 // Expression parser function ------------------------------------------------------
 // ---------------------------------------------------------------------------------
 
+// operator precedences, in left to right mode every binary op is sum so
+// an expression is evaluated strictly left to right as jack specifies
+const (
+	_ int = iota
+	lowest
+	or      // |
+	and     // &
+	equals  // =
+	compare // < >
+	sum     // + -
+	product // * /
+)
+
+var precedences = map[token.Type]int{
+	token.OR:       or,
+	token.AND:      and,
+	token.EQ:       equals,
+	token.LT:       compare,
+	token.GT:       compare,
+	token.PLUS:     sum,
+	token.MINUS:    sum,
+	token.ASTERISK: product,
+	token.SLASH:    product,
+}
+
+// parseExpression => <term> {<op> <term>}
 func (p *Parser) parseExpression() (ast.ExpressionNode, error) {
-	var err error
-	var exp ast.Expression
+	return p.parseBinaryExpression(lowest)
+}
+
+// parseBinaryExpression parses terms joined by ops that bind tighter than
+// the given precedence
+func (p *Parser) parseBinaryExpression(precedence int) (ast.ExpressionNode, error) {
+	left, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
 
-	// parse op
-	if p.expectOp() {
-		exp.Op = p.curToken
+	for p.expectOp() && p.curPrecedence() > precedence {
+		exp := &ast.BinaryExpression{Token: p.curToken, Left: left, Op: p.curToken}
 		p.eatToken()
+
+		if exp.Right, err = p.parseBinaryExpression(p.precedence(exp.Op)); err != nil {
+			return nil, err
+		}
+
+		left = exp
+	}
+
+	return left, nil
+}
+
+func (p *Parser) precedence(op token.Token) int {
+	if !p.Precedence {
+		return sum
 	}
+	return precedences[op.Type]
+}
+
+func (p *Parser) curPrecedence() int {
+	return p.precedence(p.curToken)
+}
 
-	// parse term
+// parseTerm => <constant> | <var> | <var>[<exp>] | <call> | (<exp>) | <unary op> <term>
+func (p *Parser) parseTerm() (ast.ExpressionNode, error) {
 	switch p.curToken.Type {
 		case token.INT:
-			if exp.Term, err = p.parseIntLiteral(); err != nil {
-				return nil, err
-			}
+			return p.parseIntLiteral()
 
 		case token.STRING:
-			if exp.Term, err = p.parseStringLiteral(); err != nil {
-				return nil, err
-			}
+			return p.parseStringLiteral()
 
 		case token.IDENT:
 			switch p.peekToken.Type {
 			case token.DOT: fallthrough
 			case token.LPAREN:
-				if exp.Term, err = p.parseSubroutineCall(); err != nil {
-					return nil, err
-				}
+				return p.parseSubroutineCall()
 			case token.LBRACKET:
-				if exp.Term, err = p.parseIndexIdentifier(); err != nil {
-					return nil, err
-				}
+				return p.parseIndexIdentifier()
 			default:
-				if exp.Term, err = p.parseIdentifier(); err != nil {
-					return nil, err
-				}			
+				return p.parseIdentifier()
 			}
 
 		case token.TRUE: fallthrough
 		case token.FALSE: fallthrough
 		case token.NULL: fallthrough
 		case token.THIS:
-			if exp.Term, err = p.parseKeywordConstant(); err != nil {
-				return nil, err
-			}
+			return p.parseKeywordConstant()
 
 		case token.LPAREN:
-			p.eatToken()
-			if exp.Term, err = p.parseExpression(); err != nil {
-				return nil, err
-			}
-			if !p.expectAndEat(token.RPAREN) {
-				return nil, tokenError(token.RPAREN, p.curToken.Literal)
-			}
+			return p.parseParenExpression()
 
 		case token.NOT: fallthrough
 		case token.MINUS:
-			if exp.Term, err = p.parseExpression(); err != nil {
-				return nil, err
-			}
+			return p.parseUnaryExpression()
 
 		default:
-			return nil, errors.New("error parsing expression")
+			return nil, parseError(p.curToken, "error parsing expression, unexpected token: " + literal(p.curToken))
 	}
-
-	// parse tail
-	if p.expectOp() {
-		if exp.Tail, err = p.parseExpression(); err != nil {
-			return nil, err
-		}
-	}
-
-	return &exp, nil
 }
 
+// expectOp checks for a binary op, ~ is only ever unary
 func (p *Parser) expectOp() bool {
-	if 
-	p.expect(token.PLUS) ||
-	p.expect(token.MINUS) ||
-	p.expect(token.ASTERISK) ||
-	p.expect(token.SLASH) ||
-	p.expect(token.AND) ||
-	p.expect(token.OR) ||
-	p.expect(token.NOT) ||
-	p.expect(token.GT) ||
-	p.expect(token.LT) ||
-	p.expect(token.EQ) {
-		return true
-	}
-	return false
+	_, ok := precedences[p.curToken.Type]
+	return ok
+}
+
+// parseUnaryExpression => <- | ~> <term>
+func (p *Parser) parseUnaryExpression() (*ast.UnaryExpression, error) {
+	var err error
+	ue := &ast.UnaryExpression{Token: p.curToken, Prefix: p.curToken}
+	p.eatToken()
+
+	if ue.Term, err = p.parseTerm(); err != nil {
+		return nil, err
+	}
+
+	return ue, nil
 }
 
 func (p *Parser) parseIdentifier() (*ast.Identifier, error) {
@@ -187,6 +397,23 @@ func (p *Parser) parseIndexIdentifier() (*ast.IndexIdentifier, error) {
 		return ii, nil
 }
 
+// parseParenExpression => ( <expression> )
+func (p *Parser) parseParenExpression() (*ast.ParenExpression, error) {
+	var err error
+	pe := &ast.ParenExpression{Token: p.curToken}
+	p.eatToken()
+
+	if pe.Term, err = p.parseExpression(); err != nil {
+		return nil, err
+	}
+
+	if !p.expectAndEat(token.RPAREN) {
+		return nil, tokenError(token.RPAREN, p.curToken)
+	}
+
+	return pe, nil
+}
+
 func (p *Parser) parseStringLiteral() (*ast.StringLiteral, error) {
 	sl := &ast.StringLiteral{Token: p.curToken}
 	sl.Value = p.curToken.Literal
@@ -198,11 +425,9 @@ func (p *Parser) parseStringLiteral() (*ast.StringLiteral, error) {
 func (p *Parser) parseIntLiteral() (*ast.IntLiteral, error) {
 	il := &ast.IntLiteral{Token: p.curToken}
 
-	if i, err := strconv.Atoi(p.curToken.Literal); err == nil {
-		il.Value = i
-	} else {
-		return nil, err
-	}	
+	// an integer too large to convert is an out of range one the lexer has
+	// already reported
+	il.Value, _ = strconv.Atoi(p.curToken.Literal)
 	p.eatToken()
 	return il, nil
 }
@@ -217,7 +442,7 @@ func (p *Parser) parseKeywordConstant() (*ast.KeywordConstant, error) {
 	p.curToken.Type == token.Type(token.THIS) {
 		kw.Value = p.curToken.Literal
 	} else {
-		return nil, tokenError("true | false | null | this", p.curToken.Literal)
+		return nil, tokenError("true | false | null | this", p.curToken)
 	}
 	p.eatToken()
 	return kw, nil
@@ -252,7 +477,7 @@ func (p *Parser) parseArgumentList() ([]ast.ExpressionNode, error) {
 	var args []ast.ExpressionNode
 
 	if !p.expectAndEat(token.LPAREN) {
-		return nil, tokenError(token.LPAREN, p.curToken.Literal)
+		return nil, tokenError(token.LPAREN, p.curToken)
 	}
 
 	for p.curToken.Type != token.RPAREN {
@@ -300,7 +525,7 @@ func (p *Parser) parseStatement() (ast.StatementNode, error) {
 	case token.CLASS:
 		return p.parseClassDeclaration()
 	default:
-		return nil, errors.New("error reading statement, unexpected token: " + p.curToken.Literal)
+		return nil, parseError(p.curToken, "error reading statement, unexpected token: " + literal(p.curToken))
 	}
 }
 
@@ -316,7 +541,7 @@ func (p *Parser) parseType() (token.Token, error) {
 			p.eatToken()
 			return t, nil
 		default:
-			return token.Token{}, tokenError("int | char | boolean | ident | void", p.curToken.Literal)
+			return token.Token{}, tokenError("int | char | boolean | ident | void", p.curToken)
 	}
 }
 
@@ -332,7 +557,7 @@ func (p *Parser) parseTypeDeclaration() (*ast.TypeDeclaration, error) {
 	
 	for {
 		if !p.expect(token.IDENT) {
-			return nil, tokenError(token.IDENT, p.curToken.Literal)
+			return nil, tokenError(token.IDENT, p.curToken)
 		}
 
 		if i, err := p.parseIdentifier(); err == nil {
@@ -346,7 +571,7 @@ func (p *Parser) parseTypeDeclaration() (*ast.TypeDeclaration, error) {
 		} else if p.expectAndEat(token.SEMICOLON) {
 			break
 		} else {
-			return nil, tokenError(", or ;", p.curToken.Literal)
+			return nil, tokenError(", or ;", p.curToken)
 		}
 	}
 
@@ -360,7 +585,7 @@ func (p *Parser) parseParameterList() ([]*ast.ParamDeclaration, error) {
 	var params []*ast.ParamDeclaration
 
 	if !p.expectAndEat(token.LPAREN) {
-		return nil, tokenError("(", p.peekToken.Literal)
+		return nil, tokenError("(", p.curToken)
 	}
 
 	for p.curToken.Type != token.RPAREN {
@@ -371,7 +596,7 @@ func (p *Parser) parseParameterList() ([]*ast.ParamDeclaration, error) {
 		}
 
 		if param.Name, err = p.parseIdentifier(); err != nil {
-			return nil, tokenError(token.IDENT, p.curToken.Literal)
+			return nil, tokenError(token.IDENT, p.curToken)
 		}
 
 		params = append(params, param)
@@ -381,7 +606,7 @@ func (p *Parser) parseParameterList() ([]*ast.ParamDeclaration, error) {
 		}
 
 		if !p.expectAndEat(token.COMMA) {
-			return nil, tokenError(token.COMMA, p.curToken.Literal)
+			return nil, tokenError(token.COMMA, p.curToken)
 		}
 	}
 
@@ -394,7 +619,7 @@ func (p *Parser) parseParameterList() ([]*ast.ParamDeclaration, error) {
 // parseSubroutineDeclaration => <dec> <returnType> <ident> ( <parameterList> ) <subroutineBody>
 func (p *Parser) parseSubroutineDeclaration() (*ast.SubroutineDeclaration, error) {
 	var err error
-	dec := &ast.SubroutineDeclaration{Token: p.curToken, Decelration: p.curToken}
+	dec := &ast.SubroutineDeclaration{Token: p.curToken, Decelration: p.curToken, Doc: p.curToken.Doc}
 	p.eatToken()
 
 	if dec.ReturnType, err = p.parseType(); err != nil {
@@ -402,7 +627,7 @@ func (p *Parser) parseSubroutineDeclaration() (*ast.SubroutineDeclaration, error
 	}
 
 	if !p.expect(token.IDENT) {
-		return nil, tokenError(token.IDENT, p.curToken.Literal)
+		return nil, tokenError(token.IDENT, p.curToken)
 	}
 
 	if dec.Name, err = p.parseIdentifier(); err != nil {
@@ -414,7 +639,7 @@ func (p *Parser) parseSubroutineDeclaration() (*ast.SubroutineDeclaration, error
 	}
 
 
-	if dec.Body, err = p.parseCodeBlock(); err != nil {
+	if dec.Body, dec.End, err = p.parseCodeBlock(); err != nil {
 		return nil, err
 	}
 
@@ -424,17 +649,17 @@ func (p *Parser) parseSubroutineDeclaration() (*ast.SubroutineDeclaration, error
 // parseClassDeclaration => class <name> { <statements> }
 func (p *Parser) parseClassDeclaration() (*ast.ClassDeclaration, error) {
 	var err error
-	dec := &ast.ClassDeclaration{Token: p.curToken}
+	dec := &ast.ClassDeclaration{Token: p.curToken, Doc: p.curToken.Doc}
 
 	if !p.peekAndEat(token.IDENT) {
-		return nil, tokenError(token.IDENT, p.peekToken.Literal)
+		return nil, tokenError(token.IDENT, p.peekToken)
 	}
 
 	if dec.Name , err = p.parseIdentifier(); err != nil {
 		return nil, err
 	}
 
-	if dec.Body, err = p.parseCodeBlock(); err != nil {
+	if dec.Body, dec.End, err = p.parseBlock(p.syncDeclaration); err != nil {
 		return nil, err
 	}
 
@@ -446,7 +671,7 @@ func (p *Parser) parseLetStatement() (*ast.LetStatement, error) {
 	stmt := &ast.LetStatement{Token: p.curToken}
 
 	if !p.peekAndEat(token.IDENT) {
-		return nil, tokenError(token.IDENT, p.peekToken.Literal)
+		return nil, tokenError(token.IDENT, p.peekToken)
 	}
 
 	if p.expectPeek(token.LBRACKET) {
@@ -465,7 +690,7 @@ func (p *Parser) parseLetStatement() (*ast.LetStatement, error) {
 
 
 	if !p.expectAndEat(token.EQ) {
-		return nil, tokenError(token.EQ, p.peekToken.Literal)
+		return nil, tokenError(token.EQ, p.curToken)
 	}
 
 	if val, err := p.parseExpression(); err == nil {
@@ -475,7 +700,7 @@ func (p *Parser) parseLetStatement() (*ast.LetStatement, error) {
 	}
 
 	if !p.expectAndEat(token.SEMICOLON) {
-		return nil, tokenError(token.SEMICOLON, p.peekToken.Literal)
+		return nil, tokenError(token.SEMICOLON, p.curToken)
 	}
 
 	return stmt, nil
@@ -499,7 +724,7 @@ func (p *Parser) parseReturnStatement() (*ast.ReturnStatement, error) {
 	}
 
 	if !p.expectAndEat(token.SEMICOLON) {
-		return nil, tokenError(token.SEMICOLON, p.curToken.Literal)
+		return nil, tokenError(token.SEMICOLON, p.curToken)
 	}
 
 	return stmt, nil
@@ -518,7 +743,7 @@ func (p *Parser) parseDoStatement() (*ast.DoStatement, error) {
 	}
 
 	if p.curToken.Type != token.SEMICOLON {
-		return nil, tokenError(token.SEMICOLON, p.curToken.Literal)
+		return nil, tokenError(token.SEMICOLON, p.curToken)
 	}
 
 	p.eatToken()
@@ -527,26 +752,50 @@ func (p *Parser) parseDoStatement() (*ast.DoStatement, error) {
 }
 
 // parseCodeBlock => {<statements>}
-func (p *Parser) parseCodeBlock() ([]ast.StatementNode, error) {
+func (p *Parser) parseCodeBlock() ([]ast.StatementNode, token.Pos, error) {
+	return p.parseBlock(p.syncStatement)
+}
+
+// parseBlock => {<statements>}
+// a statement that fails to parse is recorded and skipped over with sync,
+// so the rest of the block is still parsed. It also returns the position of
+// the closing brace.
+func (p *Parser) parseBlock(sync func()) ([]ast.StatementNode, token.Pos, error) {
 	stmts := []ast.StatementNode{}
 
 	if !p.expectAndEat(token.LBRACE)  {
-		return nil, tokenError(token.LBRACE, p.curToken.Literal)
+		return nil, token.Pos{}, tokenError(token.LBRACE, p.curToken)
 	}
 
-	for p.curToken.Type != token.RBRACE {
-		if s, err := p.parseStatement(); err == nil {
+	for !p.expect(token.RBRACE) && !p.expect(token.EOF) {
+		start := p.curToken
+
+		s, err := p.parseStatement()
+		if err == nil {
 			stmts = append(stmts, s)
-		} else {
-			return nil, err
+			p.suppress = false
+			continue
 		}
+
+		if err := p.addError(err); err != nil {
+			return nil, token.Pos{}, err
+		}
+
+		// make sure the parser moves on from the bad token
+		if p.curToken == start {
+			p.eatToken()
+		}
+		sync()
+		p.suppress = false
 	}
 
+	// the loop only stops short of a } at the end of the file
+	end := p.curToken.Pos
 	if !p.expectAndEat(token.RBRACE)  {
-		return nil, tokenError(token.LBRACE, p.curToken.Literal)
+		return nil, token.Pos{}, p.addError(tokenError(token.RBRACE, p.curToken))
 	}
 
-	return stmts, nil
+	return stmts, end, nil
 }
 
 
@@ -556,7 +805,7 @@ func (p *Parser) parseWhileStatement() (*ast.WhileStatement, error) {
 	stmt := &ast.WhileStatement{Token: p.curToken}
 
 	if !p.peekAndEat(token.LPAREN) {
-		return nil, tokenError(token.LPAREN, p.curToken.Literal)
+		return nil, tokenError(token.LPAREN, p.peekToken)
 	}
 	p.eatToken()
 
@@ -565,10 +814,10 @@ func (p *Parser) parseWhileStatement() (*ast.WhileStatement, error) {
 	}
 
 	if !p.expectAndEat(token.RPAREN) {
-		return nil, tokenError(token.RPAREN, p.curToken.Literal)
+		return nil, tokenError(token.RPAREN, p.curToken)
 	}
 	
-	if stmt.Statements, err = p.parseCodeBlock(); err != nil {
+	if stmt.Statements, stmt.End, err = p.parseCodeBlock(); err != nil {
 		return nil, err
 	}
 
@@ -581,7 +830,7 @@ func (p *Parser) parseIfStatement() (*ast.IfStatement, error) {
 	stmt := &ast.IfStatement{Token: p.curToken}
 
 	if !p.peekAndEat(token.LPAREN) {
-		return nil, tokenError(token.LPAREN, p.curToken.Literal)
+		return nil, tokenError(token.LPAREN, p.peekToken)
 	}
 	p.eatToken()
 
@@ -592,18 +841,23 @@ func (p *Parser) parseIfStatement() (*ast.IfStatement, error) {
 	}
 
 	if !p.expectAndEat(token.RPAREN) {
-		return nil, tokenError(token.RPAREN, p.curToken.Literal)
+		return nil, tokenError(token.RPAREN, p.curToken)
 	}
 	
-	if stmts, err := p.parseCodeBlock(); err == nil {
+	if stmts, end, err := p.parseCodeBlock(); err == nil {
 		stmt.Statements = stmts
+		stmt.End = end
 	} else {
 		return nil, err
 	}
 
-	if p.expectAndEat(token.ELSE) {
-		if stmts, err := p.parseCodeBlock(); err == nil {
+	if p.expect(token.ELSE) {
+		stmt.Else = p.curToken
+		p.eatToken()
+
+		if stmts, end, err := p.parseCodeBlock(); err == nil {
 			stmt.ElseStatements = stmts
+			stmt.ElseEnd = end
 		} else {
 			return nil, err
 		}
@@ -4,6 +4,7 @@ import (
 	"jack/ast"
 	"jack/lexer"
 	"jack/token"
+	"strings"
 	"testing"
 )
 
@@ -54,10 +55,10 @@ func TestParseLetStatement(t *testing.T) {
 		expName string
 		expValue interface{}
 	}{
-		{"let sum = sum + a[i];", "sum", "(sum (+a[(i)]))"},
-		{"let foo = bar();", "foo", "(bar())"},
-		{"let x = 8;", "x", "(8)"},
-		{"let y = ~true;", "y", "(~true)"},
+		{"let sum = sum + a[i];", "sum", "(sum + a[i])"},
+		{"let foo = bar();", "foo", "bar()"},
+		{"let x = 8;", "x", "8"},
+		{"let y = ~true;", "y", "~true"},
 	}
 
 	for _, test := range tests {
@@ -89,8 +90,8 @@ func TestParseIndexedLetStatement(t *testing.T) {
 		expIndex string
 		expValue interface{}
 	}{
-		{"let x[0] = 8;", "x", "(0)", "(8)"},
-		{"let y[foo] = true;", "y", "(foo)", "(true)"},
+		{"let x[0] = 8;", "x", "0", "8"},
+		{"let y[foo] = true;", "y", "foo", "true"},
 	}
 
 	for _, test := range tests {
@@ -122,7 +123,7 @@ func TestParseReturnStatement(t *testing.T) {
 		expValue interface{}
 	}{
 		{"return;", nil},
-		{"return 3;", "(3)"},
+		{"return 3;", "3"},
 	}
 
 	for _, test := range tests {
@@ -149,7 +150,7 @@ func TestParseDoStatement(t *testing.T) {
 		input string
 		expValue interface{}
 	}{
-		{"do foobar(1, 2);", "(foobar((1), (2)))"},
+		{"do foobar(1, 2);", "foobar(1, 2)"},
 	}
 
 	for _, test := range tests {
@@ -179,10 +180,10 @@ func TestParseWhileStatement(t *testing.T) {
 		t.Fatalf(err.Error())
 	}
 
-	assert(t, "WhileStatement", "(true)", actual.Expression.String())
+	assert(t, "WhileStatement", "true", actual.Expression.String())
 	assert(t, "WhileStatement", 2, len(actual.Statements))
-	assert(t, "WhileStatement", "let x = (3);", actual.Statements[0].String())
-	assert(t, "WhileStatement", "do (foobar);", actual.Statements[1].String())
+	assert(t, "WhileStatement", "let x = 3;\n", actual.Statements[0].String())
+	assert(t, "WhileStatement", "do foobar;\n", actual.Statements[1].String())
 }
 
 
@@ -204,12 +205,12 @@ func TestParseIfStatement(t *testing.T) {
 		t.Fatalf(err.Error())
 	}
 
-	assert(t, "IfStatement", "(bool)", actual.Expression.String())
+	assert(t, "IfStatement", "bool", actual.Expression.String())
 	assert(t, "IfStatement", 2, len(actual.Statements))
 	assert(t, "IfStatement", 1, len(actual.ElseStatements))
-	assert(t, "IfStatement", "let x = (4);", actual.Statements[0].String())
-	assert(t, "IfStatement", "do (foobar);", actual.Statements[1].String())
-	assert(t, "IfStatement", "let y = (4);", actual.ElseStatements[0].String())
+	assert(t, "IfStatement", "let x = 4;\n", actual.Statements[0].String())
+	assert(t, "IfStatement", "do foobar;\n", actual.Statements[1].String())
+	assert(t, "IfStatement", "let y = 4;\n", actual.ElseStatements[0].String())
 }
 
 func TestParseParamList(t *testing.T) {
@@ -315,16 +316,16 @@ func TestParseExpression(t *testing.T) {
 			input string
 			exp string
 		}{
-			{"a[i]", "(a[(i)])"},
-			{"1 * 2 + 3", "(1 (*2 (+3)))"},
-			{"(4 * 8) - (2 / 3)", "((4 (*8)) (-(2 (/3))))"},
-			{"-1", "(-1)"},
-			{"Vector.norm()", "(Vector.norm())"},
-			{"foo(bar)", "(foo((bar)))"},
-			{"true", "(true)"},
-			{"this", "(this)"},
-			{"~~false", "(~(~false))"},
-			{"-1 + (-3)", "(-1 (+(-3)))"},
+			{"a[i]", "a[i]"},
+			{"1 * 2 + 3", "((1 * 2) + 3)"},
+			{"(4 * 8) - (2 / 3)", "(((4 * 8)) - ((2 / 3)))"},
+			{"-1", "-1"},
+			{"Vector.norm()", "Vector.norm()"},
+			{"foo(bar)", "foo(bar)"},
+			{"true", "true"},
+			{"this", "this"},
+			{"~~false", "~~false"},
+			{"-1 + (-3)", "(-1 + (-3))"},
 	}
 
 	for _, test := range tests {
@@ -342,6 +343,37 @@ func TestParseExpression(t *testing.T) {
 }
 
 
+func TestParseExpressionPrecedence(t *testing.T) {
+	tests := []struct {
+		input       string
+		leftToRight string
+		precedence  string
+	}{
+		{"1 + 2 * 3", "((1 + 2) * 3)", "(1 + (2 * 3))"},
+		{"1 * 2 + 3", "((1 * 2) + 3)", "((1 * 2) + 3)"},
+		{"a - b - c", "((a - b) - c)", "((a - b) - c)"},
+		{"a < b & c = d | e", "((((a < b) & c) = d) | e)", "(((a < b) & (c = d)) | e)"},
+		{"-a * b + ~c", "((-a * b) + ~c)", "((-a * b) + ~c)"},
+	}
+
+	for _, test := range tests {
+		p := New(lexer.New(test.input))
+		actual, err := p.parseExpression()
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		assert(t, "LeftToRight", test.leftToRight, actual.String())
+
+		p = New(lexer.New(test.input))
+		p.Precedence = true
+		actual, err = p.parseExpression()
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		assert(t, "Precedence", test.precedence, actual.String())
+	}
+}
+
 func TestLarge(t *testing.T) {
 	test := `
 		class Main {
@@ -381,4 +413,292 @@ func TestLarge(t *testing.T) {
 	_, err := parser.ParseFile()
 
 	assert(t, "large", nil, err)
-}
\ No newline at end of file
+}
+
+func TestParseErrorPosition(t *testing.T) {
+	tests := []struct {
+		input string
+		pos string
+	}{
+		{"class Main {\n  function void main() {\n    let x = 1\n  }\n}", "Main.jack:4:3"},
+		{"class Main {\n  function void main() {\n    let x 1;\n  }\n}", "Main.jack:3:11"},
+		{"class Main {\n  function void main() {\n    while x {}\n  }\n}", "Main.jack:3:11"},
+		{"class Main {\n  foo\n}", "Main.jack:2:3"},
+	}
+
+	for _, test := range tests {
+		lexer := lexer.NewFile("Main.jack", test.input)
+		parser := New(lexer)
+
+		_, err := parser.ParseClass()
+
+		if err == nil {
+			t.Fatalf("ParseErrorPosition : expected error parsing %q", test.input)
+		}
+
+		if !strings.HasPrefix(err.Error(), test.pos + ": ") {
+			t.Fatalf("ParseErrorPosition : expected position %s, got: %s", test.pos, err.Error())
+		}
+	}
+}
+
+
+func TestParseErrorRecovery(t *testing.T) {
+	test := `class Main {
+		field int x
+		field int y;
+
+		function void main() {
+			let a = ;
+			do foo(;
+			while x { let b = 1; }
+			let c = 2;
+			return;
+		}
+
+		method void bar(int a {
+			return;
+		}
+
+		method int baz() {
+			return 1 1;
+		}
+	}`
+
+	expected := []string{
+		"Main.jack:3:3",
+		"Main.jack:6:12",
+		"Main.jack:7:11",
+		"Main.jack:8:10",
+		"Main.jack:13:25",
+		"Main.jack:18:13",
+	}
+
+	lexer := lexer.NewFile("Main.jack", test)
+	parser := New(lexer)
+
+	_, err := parser.ParseClass()
+
+	errs, ok := err.(ErrorList)
+	if !ok {
+		t.Fatalf("ErrorRecovery : expected ErrorList, got %T", err)
+	}
+
+	for i, e := range errs {
+		t.Log(e)
+		if i < len(expected) && !strings.HasPrefix(e.Error(), expected[i] + ": ") {
+			t.Errorf("ErrorRecovery : expected error at %s, got: %s", expected[i], e.Error())
+		}
+	}
+
+	assert(t, "ErrorRecovery", len(expected), len(errs))
+}
+
+
+func TestParseErrorEOF(t *testing.T) {
+	// a class left open is reported once, not by every enclosing block
+	test := "class A { function void f() { return; }"
+
+	_, err := New(lexer.NewFile("A.jack", test)).ParseClass()
+
+	errs, ok := err.(ErrorList)
+	if !ok {
+		t.Fatalf("ErrorEOF : expected ErrorList, got %T", err)
+	}
+
+	assert(t, "ErrorEOF", 1, len(errs))
+	assert(t, "ErrorEOF", "A.jack:1:40: unexpected token, expected: }   got EOF", errs[0].Error())
+}
+
+
+func TestParseErrorCap(t *testing.T) {
+	test := `class Main {
+		function void main() {
+			let a = ;
+			let b = ;
+			let c = ;
+			let d = ;
+		}
+	}`
+
+	lexer := lexer.New(test)
+	parser := New(lexer)
+	parser.MaxErrors = 2
+
+	_, err := parser.ParseClass()
+
+	errs, ok := err.(ErrorList)
+	if !ok {
+		t.Fatalf("ErrorCap : expected ErrorList, got %T", err)
+	}
+
+	assert(t, "ErrorCap", 3, len(errs))
+	assert(t, "ErrorCap", errTooManyErrors, errs[2])
+}
+
+
+func TestParseIllegalTokens(t *testing.T) {
+	test := "class Main {\n" +
+		"  function void main() {\n" +
+		"    let x = 40000;\n" +
+		"    let y = #1;\n" +
+		"    let s = \"abc;\n" +
+		"    return;\n" +
+		"  }\n" +
+		"}\n" +
+		"/* never closed"
+
+	expected := []string{
+		"Main.jack:3:13: integer out of range 0..32767: 40000",
+		"Main.jack:4:13: unexpected byte: \"#\"",
+		"Main.jack:5:13: newline in string",
+		"Main.jack:9:1: unterminated comment",
+	}
+
+	lexer := lexer.NewFile("Main.jack", test)
+	parser := New(lexer)
+	parser.MaxErrors = 0
+
+	_, err := parser.ParseClass()
+
+	errs, ok := err.(ErrorList)
+	if !ok {
+		t.Fatalf("IllegalTokens : expected ErrorList, got %T", err)
+	}
+
+	// only the lexer errors are reported, the parser does not go on to
+	// report the tokens it skipped
+	if len(errs) != len(expected) {
+		t.Fatalf("IllegalTokens : expected %d errors, got:\n%s", len(expected), err.Error())
+	}
+	for i, e := range expected {
+		if errs[i].Error() != e {
+			t.Errorf("IllegalTokens : expected error %s, got: %s", e, errs[i].Error())
+		}
+	}
+}
+
+func TestParseIllegalSuppress(t *testing.T) {
+	// errors after an illegal token are suppressed up to the end of its
+	// statement only
+	test := "class Main {\n" +
+		"  function void main() {\n" +
+		"    let x = 1 # 2;\n" +
+		"    let y = ;\n" +
+		"    return;\n" +
+		"  }\n" +
+		"}"
+
+	expected := []string{
+		"Main.jack:3:15: unexpected byte: \"#\"",
+		"Main.jack:4:13: error parsing expression, unexpected token: ;",
+	}
+
+	parser := New(lexer.NewFile("Main.jack", test))
+	_, err := parser.ParseClass()
+
+	errs, ok := err.(ErrorList)
+	if !ok {
+		t.Fatalf("IllegalSuppress : expected ErrorList, got %T", err)
+	}
+	if len(errs) != len(expected) {
+		t.Fatalf("IllegalSuppress : expected %d errors, got:\n%s", len(expected), err.Error())
+	}
+	for i, e := range expected {
+		if errs[i].Error() != e {
+			t.Errorf("IllegalSuppress : expected error %s, got: %s", e, errs[i].Error())
+		}
+	}
+}
+
+func TestParseTruncated(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"class A { function void f() { let x = ", []string{
+			"A.jack:1:39: error parsing expression, unexpected token: EOF",
+		}},
+		{"class A { function void f() { let x = 1 #", []string{
+			"A.jack:1:41: unexpected byte: \"#\"",
+		}},
+		{"class A { function void f() { let x = 1 # 2; return; } }", []string{
+			"A.jack:1:41: unexpected byte: \"#\"",
+		}},
+		{"class A { function void f() { let s = \"abc", []string{
+			"A.jack:1:39: unterminated string",
+		}},
+	}
+
+	for _, tt := range tests {
+		_, err := New(lexer.NewFile("A.jack", tt.input)).ParseClass()
+
+		errs, ok := err.(ErrorList)
+		if !ok {
+			t.Fatalf("Truncated : expected ErrorList for %q, got %T", tt.input, err)
+		}
+		if len(errs) != len(tt.expected) {
+			t.Errorf("Truncated : expected %d errors for %q, got:\n%s", len(tt.expected), tt.input, err.Error())
+			continue
+		}
+		for i, e := range tt.expected {
+			if errs[i].Error() != e {
+				t.Errorf("Truncated : expected error %s, got: %s", e, errs[i].Error())
+			}
+		}
+	}
+}
+
+func FuzzParseClass(f *testing.F) {
+	for _, seed := range []string{
+		"class Main { function void main() { let x = 1 + 2; return; } }",
+		"class Main { field int x; method void f(int a) { while (x) { do f(1); } } }",
+		"class Main { function void main() { let s = \"abc; } }",
+		"class Main { /* ", "class", "class Main {", "}}}{{{",
+	} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		for _, precedence := range []bool{false, true} {
+			parser := New(lexer.New(input))
+			parser.MaxErrors = 0
+			parser.Precedence = precedence
+
+			class, err := parser.ParseClass()
+			if (class == nil) == (err == nil) {
+				t.Fatalf("expected a class or an error, got: %v, %v", class, err)
+			}
+		}
+	})
+}
+
+func TestParseDocComments(t *testing.T) {
+	test := `/** Does foo things. */
+	class Foo {
+		/** The x. */
+		field int x;
+
+		/** Makes a Foo. */
+		constructor Foo new() { return this; }
+
+		method void bar() { return; }
+	}`
+
+	class, err := New(lexer.New(test)).ParseClass()
+	if err != nil {
+		t.Fatalf("DocComments : %s", err.Error())
+	}
+
+	assert(t, "DocComments", "Does foo things.", class.Doc)
+
+	var subs []*ast.SubroutineDeclaration
+	for _, stmt := range class.Body {
+		if sub, ok := stmt.(*ast.SubroutineDeclaration); ok {
+			subs = append(subs, sub)
+		}
+	}
+	assert(t, "DocComments", 2, len(subs))
+	assert(t, "DocComments", "Makes a Foo.", subs[0].Doc)
+	assert(t, "DocComments", "", subs[1].Doc)
+}
@@ -0,0 +1,137 @@
+package symbols
+
+import (
+	"jack/ast"
+)
+
+// Resolution maps each variable reference and declaration in a class onto
+// the symbol it names
+type Resolution map[ast.Node]*Symbol
+
+// Resolve builds the class and subroutine tables of a class and resolves
+// every identifier in it. Class and subroutine names are not variables and
+// are left unresolved.
+func Resolve(class *ast.ClassDeclaration) (Resolution, error) {
+	r := Resolution{}
+
+	classTable, err := Class(class)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, stmt := range class.Body {
+		switch s := stmt.(type) {
+		case *ast.TypeDeclaration:
+			r.declare(classTable, s.Names)
+
+		case *ast.SubroutineDeclaration:
+			table, err := Subroutine(classTable, class.Name.Name, s)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, param := range s.Parameters {
+				r.declare(table, []*ast.Identifier{param.Name})
+			}
+
+			if err := r.statements(table, s.Body); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return r, nil
+}
+
+func (r Resolution) declare(t *Table, names []*ast.Identifier) {
+	for _, name := range names {
+		if s, ok := t.Lookup(name.Name); ok {
+			r[name] = s
+		}
+	}
+}
+
+func (r Resolution) reference(t *Table, node ast.Node, name string) error {
+	s, ok := t.Lookup(name)
+	if !ok {
+		return undeclaredError(node.Pos(), name)
+	}
+	r[node] = s
+	return nil
+}
+
+func (r Resolution) statements(t *Table, stmts []ast.StatementNode) error {
+	for _, stmt := range stmts {
+		if err := r.statement(t, stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r Resolution) statement(t *Table, stmt ast.StatementNode) error {
+	switch s := stmt.(type) {
+	case *ast.TypeDeclaration:
+		r.declare(t, s.Names)
+	case *ast.LetStatement:
+		if err := r.expression(t, s.Name); err != nil {
+			return err
+		}
+		return r.expression(t, s.Value)
+	case *ast.DoStatement:
+		return r.expression(t, s.Expression)
+	case *ast.ReturnStatement:
+		if s.Value != nil {
+			return r.expression(t, s.Value)
+		}
+	case *ast.WhileStatement:
+		if err := r.expression(t, s.Expression); err != nil {
+			return err
+		}
+		return r.statements(t, s.Statements)
+	case *ast.IfStatement:
+		if err := r.expression(t, s.Expression); err != nil {
+			return err
+		}
+		if err := r.statements(t, s.Statements); err != nil {
+			return err
+		}
+		return r.statements(t, s.ElseStatements)
+	}
+	return nil
+}
+
+func (r Resolution) expression(t *Table, exp ast.ExpressionNode) error {
+	switch e := exp.(type) {
+	case *ast.BinaryExpression:
+		if err := r.expression(t, e.Left); err != nil {
+			return err
+		}
+		return r.expression(t, e.Right)
+	case *ast.UnaryExpression:
+		return r.expression(t, e.Term)
+	case *ast.ParenExpression:
+		return r.expression(t, e.Term)
+	case *ast.Identifier:
+		return r.reference(t, e, e.Name)
+	case *ast.IndexIdentifier:
+		if err := r.reference(t, e, e.Name); err != nil {
+			return err
+		}
+		return r.expression(t, e.Index)
+	case *ast.SubroutineCall:
+		// only a receiver that names a variable is a reference, otherwise
+		// it is a class name
+		if e.Class != nil {
+			if s, ok := t.Lookup(e.Class.Name); ok {
+				r[e.Class] = s
+			}
+		}
+		for _, arg := range e.Arguments {
+			if err := r.expression(t, arg); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
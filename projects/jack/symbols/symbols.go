@@ -0,0 +1,173 @@
+package symbols
+
+import (
+	"errors"
+	"fmt"
+	"jack/ast"
+	"jack/token"
+)
+
+type Kind string
+
+const (
+	STATIC   Kind = "static"
+	FIELD    Kind = "field"
+	ARGUMENT Kind = "argument"
+	LOCAL    Kind = "local"
+)
+
+// Segment returns the vm memory segment variables of this kind live in
+func (k Kind) Segment() string {
+	if k == FIELD {
+		return "this"
+	}
+	return string(k)
+}
+
+// Symbol is a declared variable with its running index in its kind
+type Symbol struct {
+	Name  string
+	Type  string
+	Kind  Kind
+	Index int
+}
+
+func (s *Symbol) Segment() string { return s.Kind.Segment() }
+func (s *Symbol) String() string {
+	return fmt.Sprintf("%s %s %s (%s %d)", s.Kind, s.Type, s.Name, s.Segment(), s.Index)
+}
+
+// Table holds the symbols of a class or subroutine scope, subroutine
+// tables fall back to their class table when looking up a name
+type Table struct {
+	parent  *Table
+	symbols map[string]*Symbol
+	counts  map[Kind]int
+}
+
+func New(parent *Table) *Table {
+	return &Table{
+		parent:  parent,
+		symbols: map[string]*Symbol{},
+		counts:  map[Kind]int{},
+	}
+}
+
+// error helpers
+func symbolError(pos token.Pos, format string, args ...interface{}) error {
+	return errors.New(fmt.Sprintf("%s: %s", pos, fmt.Sprintf(format, args...)))
+}
+
+func declaredError(name string) error {
+	return errors.New(fmt.Sprintf("%s is already declared", name))
+}
+
+func undeclaredError(pos token.Pos, name string) error {
+	return symbolError(pos, "undeclared variable: %s", name)
+}
+
+// Define adds a name to the table using the next free index of its kind
+func (t *Table) Define(name, typ string, kind Kind) (*Symbol, error) {
+	if _, ok := t.symbols[name]; ok {
+		return nil, declaredError(name)
+	}
+
+	s := &Symbol{Name: name, Type: typ, Kind: kind, Index: t.counts[kind]}
+	t.symbols[name] = s
+	t.counts[kind]++
+
+	return s, nil
+}
+
+// Lookup finds a name in this table or any of its parents
+func (t *Table) Lookup(name string) (*Symbol, bool) {
+	for table := t; table != nil; table = table.parent {
+		if s, ok := table.symbols[name]; ok {
+			return s, true
+		}
+	}
+	return nil, false
+}
+
+// Count returns the number of symbols of a kind declared in this table
+func (t *Table) Count(kind Kind) int {
+	return t.counts[kind]
+}
+
+// Class builds the class level table from its static and field declarations
+func Class(class *ast.ClassDeclaration) (*Table, error) {
+	t := New(nil)
+
+	for _, stmt := range class.Body {
+		dec, ok := stmt.(*ast.TypeDeclaration)
+		if !ok {
+			continue
+		}
+
+		var kind Kind
+		switch dec.Declaration.Type {
+		case token.STATIC:
+			kind = STATIC
+		case token.FIELD:
+			kind = FIELD
+		default:
+			return nil, symbolError(dec.Pos(), "unexpected %s declaration in class %s", dec.Declaration.Literal, class.Name.Name)
+		}
+
+		if err := t.defineAll(dec, kind); err != nil {
+			return nil, err
+		}
+	}
+
+	return t, nil
+}
+
+// Subroutine builds the table of a subroutine's arguments and locals on top
+// of its class table, methods get the implicit this as argument 0
+func Subroutine(class *Table, className string, sd *ast.SubroutineDeclaration) (*Table, error) {
+	t := New(class)
+
+	if sd.Decelration.Type == token.METHOD {
+		t.Define("this", className, ARGUMENT)
+	}
+
+	for _, param := range sd.Parameters {
+		if err := t.declare(param.Name, param.Type.Literal, ARGUMENT); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, stmt := range sd.Body {
+		dec, ok := stmt.(*ast.TypeDeclaration)
+		if !ok {
+			continue
+		}
+
+		if dec.Declaration.Type != token.VAR {
+			return nil, symbolError(dec.Pos(), "unexpected %s declaration in %s.%s", dec.Declaration.Literal, className, sd.Name.Name)
+		}
+
+		if err := t.defineAll(dec, LOCAL); err != nil {
+			return nil, err
+		}
+	}
+
+	return t, nil
+}
+
+// declare defines a declared name, reporting redeclarations at its position
+func (t *Table) declare(name *ast.Identifier, typ string, kind Kind) error {
+	if _, err := t.Define(name.Name, typ, kind); err != nil {
+		return symbolError(name.Pos(), "%s", err.Error())
+	}
+	return nil
+}
+
+func (t *Table) defineAll(dec *ast.TypeDeclaration, kind Kind) error {
+	for _, name := range dec.Names {
+		if err := t.declare(name, dec.Type.Literal, kind); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package symbols
+
+import (
+	"jack/ast"
+	"jack/lexer"
+	"jack/parser"
+	"testing"
+)
+
+func assert(t *testing.T, n string, a, b interface{}) {
+	if a != b {
+		t.Fatalf("%s : expected: %v <%T>   got: %v <%T>", n, a, a, b, b)
+	}
+}
+
+func parse(t *testing.T, input string) *ast.ClassDeclaration {
+	p := parser.New(lexer.New(input))
+	class, err := p.ParseClass()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	return class
+}
+
+const point = `
+	class Point {
+		field int x, y;
+		static Point origin;
+
+		method int dist(Point other, int scale) {
+			var int dx, dy;
+			let dx = x - other.getX();
+			let dy = y - other.getY();
+			return scale * (dx + dy);
+		}
+	}
+`
+
+func TestClassTable(t *testing.T) {
+	class := parse(t, point)
+
+	table, err := Class(class)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	tests := []struct {
+		name    string
+		typ     string
+		kind    Kind
+		segment string
+		index   int
+	}{
+		{"x", "int", FIELD, "this", 0},
+		{"y", "int", FIELD, "this", 1},
+		{"origin", "Point", STATIC, "static", 0},
+	}
+
+	for _, test := range tests {
+		s, ok := table.Lookup(test.name)
+		if !ok {
+			t.Fatalf("ClassTable : %s not found", test.name)
+		}
+
+		assert(t, "ClassTable", test.typ, s.Type)
+		assert(t, "ClassTable", test.kind, s.Kind)
+		assert(t, "ClassTable", test.segment, s.Segment())
+		assert(t, "ClassTable", test.index, s.Index)
+	}
+
+	assert(t, "ClassTable", 2, table.Count(FIELD))
+	assert(t, "ClassTable", 1, table.Count(STATIC))
+}
+
+func TestSubroutineTable(t *testing.T) {
+	class := parse(t, point)
+
+	classTable, err := Class(class)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	sd := class.Body[2].(*ast.SubroutineDeclaration)
+	table, err := Subroutine(classTable, "Point", sd)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	tests := []struct {
+		name    string
+		typ     string
+		segment string
+		index   int
+	}{
+		{"this", "Point", "argument", 0},
+		{"other", "Point", "argument", 1},
+		{"scale", "int", "argument", 2},
+		{"dx", "int", "local", 0},
+		{"dy", "int", "local", 1},
+		{"x", "int", "this", 0},
+	}
+
+	for _, test := range tests {
+		s, ok := table.Lookup(test.name)
+		if !ok {
+			t.Fatalf("SubroutineTable : %s not found", test.name)
+		}
+
+		assert(t, "SubroutineTable", test.typ, s.Type)
+		assert(t, "SubroutineTable", test.segment, s.Segment())
+		assert(t, "SubroutineTable", test.index, s.Index)
+	}
+
+	assert(t, "SubroutineTable", 3, table.Count(ARGUMENT))
+	assert(t, "SubroutineTable", 2, table.Count(LOCAL))
+}
+
+func TestResolve(t *testing.T) {
+	class := parse(t, point)
+
+	r, err := Resolve(class)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	sd := class.Body[2].(*ast.SubroutineDeclaration)
+	let := sd.Body[1].(*ast.LetStatement)
+
+	name := let.Name.(*ast.Identifier)
+	assert(t, "Resolve", "local", r[name].Segment())
+	assert(t, "Resolve", 0, r[name].Index)
+
+	exp := let.Value.(*ast.BinaryExpression)
+	x := exp.Left.(*ast.Identifier)
+	assert(t, "Resolve", "this", r[x].Segment())
+
+	call := exp.Right.(*ast.SubroutineCall)
+	assert(t, "Resolve", "argument", r[call.Class].Segment())
+	assert(t, "Resolve", 1, r[call.Class].Index)
+
+	if _, ok := r[call.Name]; ok {
+		t.Fatalf("Resolve : subroutine name should not resolve")
+	}
+}
+
+func TestResolveErrors(t *testing.T) {
+	tests := []string{
+		"class A { field int a, a; }",
+		"class A { function void f(int a) { var int a; return; } }",
+		"class A { function void f() { let b = 1; return; } }",
+		"class A { function void f() { do b.g(c); return; } }",
+	}
+
+	for _, test := range tests {
+		if _, err := Resolve(parse(t, test)); err == nil {
+			t.Errorf("expected error resolving: %s", test)
+		}
+	}
+}
@@ -1,12 +1,54 @@
 package token
 
+import "fmt"
+
 type Type string
 
+// Pos is the location of a token in its source file, lines and columns
+// start at 1
+type Pos struct {
+	File   string
+	Line   int
+	Column int
+}
+
+func (p Pos) String() string {
+	if p.File == "" {
+		return fmt.Sprintf("%d:%d", p.Line, p.Column)
+	}
+	return fmt.Sprintf("%s:%d:%d", p.File, p.Line, p.Column)
+}
+
 type Token struct {
 	Type    Type
 	Literal string
+	Pos     Pos
+
+	// Reason says why an ILLEGAL token is illegal
+	Reason string
+
+	// Doc is the text of the /** */ comment just before the token
+	Doc string
 }
 
+// Comment is a // or /* */ comment, Text holds the comment markers
+type Comment struct {
+	Pos  Pos
+	Text string
+}
+
+// reasons the lexer gives for ILLEGAL tokens
+const (
+	UnterminatedString  = "unterminated string"
+	NewlineInString     = "newline in string"
+	UnterminatedComment = "unterminated comment"
+	IntOutOfRange       = "integer out of range 0..32767"
+	UnexpectedByte      = "unexpected byte"
+)
+
+// the largest integer constant
+const MaxInt = 32767
+
 func New(t Type, l byte) Token {
 	return Token{Type: t, Literal: string(l)}
 }
@@ -97,4 +139,11 @@ func LookupIdent(ident string) Type {
 		return tok
 	}
 	return IDENT
-}
\ No newline at end of file
+}
+
+// IsKeyword reports whether the token is one of the reserved words, this
+// tells the int type apart from integer literals which share a token type
+func IsKeyword(tok Token) bool {
+	_, ok := keywords[tok.Literal]
+	return ok && tok.Type != STRING
+}
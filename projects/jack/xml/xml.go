@@ -0,0 +1,315 @@
+package xml
+
+import (
+	"errors"
+	"fmt"
+	"jack/ast"
+	"jack/lexer"
+	"jack/token"
+	"strings"
+)
+
+type Writer struct {
+	strings.Builder
+	indent int
+}
+
+func (w *Writer) Writeln(s string, args ...interface{}) {
+	w.WriteString(strings.Repeat("  ", w.indent))
+	if len(args) > 0 {
+		w.WriteString(fmt.Sprintf(s, args...))
+	} else {
+		w.WriteString(s)
+	}
+	w.WriteString("\n")
+}
+
+var escapes = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+func (w *Writer) open(tag string) {
+	w.Writeln("<%s>", tag)
+	w.indent++
+}
+
+func (w *Writer) close(tag string) {
+	w.indent--
+	w.Writeln("</%s>", tag)
+}
+
+func (w *Writer) element(tag, value string) {
+	w.Writeln("<%s> %s </%s>", tag, escapes.Replace(value), tag)
+}
+
+func (w *Writer) token(tok token.Token) {
+	w.element(category(tok), tok.Literal)
+}
+
+func (w *Writer) symbol(s string) { w.element("symbol", s) }
+
+// category returns the xml element name used for a token
+func category(tok token.Token) string {
+	switch {
+	case token.IsKeyword(tok):
+		return "keyword"
+	case tok.Type == token.IDENT:
+		return "identifier"
+	case tok.Type == token.INT:
+		return "integerConstant"
+	case tok.Type == token.STRING:
+		return "stringConstant"
+	default:
+		return "symbol"
+	}
+}
+
+// Tokens reads every token from the lexer and builds the <tokens> xml
+func Tokens(l *lexer.Lexer) (string, error) {
+	var w Writer
+
+	w.Writeln("<tokens>")
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		if tok.Type == token.ILLEGAL {
+			return "", errors.New(fmt.Sprintf("%s: illegal token: %s: %q", tok.Pos, tok.Reason, tok.Literal))
+		}
+		w.token(tok)
+	}
+	w.Writeln("</tokens>")
+
+	return w.String(), nil
+}
+
+// Class builds the parse tree xml of a class
+func Class(class *ast.ClassDeclaration) string {
+	var w Writer
+	w.class(class)
+	return w.String()
+}
+
+// ---------------------------------------------------------------------------------
+// Declarations --------------------------------------------------------------------
+// ---------------------------------------------------------------------------------
+
+// class => class <name> { <classVarDec*> <subroutineDec*> }
+func (w *Writer) class(class *ast.ClassDeclaration) {
+	w.open("class")
+	w.token(class.Token)
+	w.token(class.Name.Token)
+	w.symbol("{")
+
+	for _, stmt := range class.Body {
+		switch s := stmt.(type) {
+		case *ast.TypeDeclaration:
+			w.typeDeclaration("classVarDec", s)
+		case *ast.SubroutineDeclaration:
+			w.subroutineDeclaration(s)
+		}
+	}
+
+	w.symbol("}")
+	w.close("class")
+}
+
+// typeDeclaration => <static|field|var> <type> <name> {, <name>};
+func (w *Writer) typeDeclaration(tag string, dec *ast.TypeDeclaration) {
+	w.open(tag)
+	w.token(dec.Declaration)
+	w.token(dec.Type)
+	for i, name := range dec.Names {
+		if i > 0 {
+			w.symbol(",")
+		}
+		w.token(name.Token)
+	}
+	w.symbol(";")
+	w.close(tag)
+}
+
+// subroutineDeclaration => <dec> <returnType> <name> ( <parameterList> ) <subroutineBody>
+func (w *Writer) subroutineDeclaration(sd *ast.SubroutineDeclaration) {
+	w.open("subroutineDec")
+	w.token(sd.Decelration)
+	w.token(sd.ReturnType)
+	w.token(sd.Name.Token)
+
+	w.symbol("(")
+	w.open("parameterList")
+	for i, param := range sd.Parameters {
+		if i > 0 {
+			w.symbol(",")
+		}
+		w.token(param.Type)
+		w.token(param.Name.Token)
+	}
+	w.close("parameterList")
+	w.symbol(")")
+
+	w.open("subroutineBody")
+	w.symbol("{")
+
+	var stmts []ast.StatementNode
+	for _, stmt := range sd.Body {
+		if dec, ok := stmt.(*ast.TypeDeclaration); ok {
+			w.typeDeclaration("varDec", dec)
+		} else {
+			stmts = append(stmts, stmt)
+		}
+	}
+	w.statements(stmts)
+
+	w.symbol("}")
+	w.close("subroutineBody")
+	w.close("subroutineDec")
+}
+
+// ---------------------------------------------------------------------------------
+// Statements ----------------------------------------------------------------------
+// ---------------------------------------------------------------------------------
+
+func (w *Writer) statements(stmts []ast.StatementNode) {
+	w.open("statements")
+	for _, stmt := range stmts {
+		w.statement(stmt)
+	}
+	w.close("statements")
+}
+
+func (w *Writer) statement(stmt ast.StatementNode) {
+	switch s := stmt.(type) {
+	case *ast.LetStatement:
+		w.open("letStatement")
+		w.token(s.Token)
+		if ii, ok := s.Name.(*ast.IndexIdentifier); ok {
+			w.token(ii.Token)
+			w.symbol("[")
+			w.expression(ii.Index)
+			w.symbol("]")
+		} else {
+			w.token(s.Name.(*ast.Identifier).Token)
+		}
+		w.symbol("=")
+		w.expression(s.Value)
+		w.symbol(";")
+		w.close("letStatement")
+
+	case *ast.IfStatement:
+		w.open("ifStatement")
+		w.token(s.Token)
+		w.symbol("(")
+		w.expression(s.Expression)
+		w.symbol(")")
+		w.symbol("{")
+		w.statements(s.Statements)
+		w.symbol("}")
+		if s.Else != (token.Token{}) {
+			w.token(s.Else)
+			w.symbol("{")
+			w.statements(s.ElseStatements)
+			w.symbol("}")
+		}
+		w.close("ifStatement")
+
+	case *ast.WhileStatement:
+		w.open("whileStatement")
+		w.token(s.Token)
+		w.symbol("(")
+		w.expression(s.Expression)
+		w.symbol(")")
+		w.symbol("{")
+		w.statements(s.Statements)
+		w.symbol("}")
+		w.close("whileStatement")
+
+	case *ast.DoStatement:
+		w.open("doStatement")
+		w.token(s.Token)
+		if sc, ok := s.Expression.(*ast.SubroutineCall); ok {
+			w.subroutineCall(sc)
+		} else {
+			w.expression(s.Expression)
+		}
+		w.symbol(";")
+		w.close("doStatement")
+
+	case *ast.ReturnStatement:
+		w.open("returnStatement")
+		w.token(s.Token)
+		if s.Value != nil {
+			w.expression(s.Value)
+		}
+		w.symbol(";")
+		w.close("returnStatement")
+	}
+}
+
+// ---------------------------------------------------------------------------------
+// Expressions ---------------------------------------------------------------------
+// ---------------------------------------------------------------------------------
+
+// expression => <term> {<op> <term>}
+func (w *Writer) expression(node ast.ExpressionNode) {
+	w.open("expression")
+	w.operands(node)
+	w.close("expression")
+}
+
+// operands flattens a binary expression tree back into its terms and ops,
+// in source order
+func (w *Writer) operands(node ast.ExpressionNode) {
+	if be, ok := node.(*ast.BinaryExpression); ok {
+		w.operands(be.Left)
+		w.token(be.Op)
+		w.operands(be.Right)
+		return
+	}
+	w.term(node)
+}
+
+// term => <int | string | keyword | var | var[expression] | call | (expression) | op term>
+func (w *Writer) term(node ast.ExpressionNode) {
+	w.open("term")
+	switch n := node.(type) {
+	case *ast.IntLiteral:
+		w.token(n.Token)
+	case *ast.StringLiteral:
+		w.token(n.Token)
+	case *ast.KeywordConstant:
+		w.token(n.Token)
+	case *ast.Identifier:
+		w.token(n.Token)
+	case *ast.IndexIdentifier:
+		w.token(n.Token)
+		w.symbol("[")
+		w.expression(n.Index)
+		w.symbol("]")
+	case *ast.SubroutineCall:
+		w.subroutineCall(n)
+	case *ast.ParenExpression:
+		w.symbol("(")
+		w.expression(n.Term)
+		w.symbol(")")
+	case *ast.UnaryExpression:
+		w.token(n.Prefix)
+		w.term(n.Term)
+	}
+	w.close("term")
+}
+
+// subroutineCall => <class or var?>.<name>(<expression list>)
+func (w *Writer) subroutineCall(sc *ast.SubroutineCall) {
+	if sc.Class != nil {
+		w.token(sc.Class.Token)
+		w.symbol(".")
+	}
+	w.token(sc.Name.Token)
+
+	w.symbol("(")
+	w.open("expressionList")
+	for i, arg := range sc.Arguments {
+		if i > 0 {
+			w.symbol(",")
+		}
+		w.expression(arg)
+	}
+	w.close("expressionList")
+	w.symbol(")")
+}
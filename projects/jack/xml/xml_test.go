@@ -0,0 +1,106 @@
+package xml
+
+import (
+	"io/ioutil"
+	"jack/lexer"
+	"jack/parser"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func readFile(t *testing.T, path string) string {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	return string(data)
+}
+
+func expectXml(t *testing.T, n, expected, actual string) {
+	exp := strings.Split(strings.TrimSpace(strings.ReplaceAll(expected, "\r\n", "\n")), "\n")
+	act := strings.Split(strings.TrimSpace(actual), "\n")
+
+	for i := range exp {
+		if i >= len(act) {
+			t.Fatalf("%s : output ended at line %d, expected: %s", n, i+1, exp[i])
+		}
+		if exp[i] != act[i] {
+			t.Fatalf("%s : line %d expected: %s, got: %s", n, i+1, exp[i], act[i])
+		}
+	}
+
+	if len(act) != len(exp) {
+		t.Fatalf("%s : line count mismatch, expected: %d, got: %d", n, len(exp), len(act))
+	}
+}
+
+func TestTokens(t *testing.T) {
+	files, err := filepath.Glob("../../10/*/*.jack")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	for _, file := range files {
+		actual, err := Tokens(lexer.New(readFile(t, file)))
+		if err != nil {
+			t.Fatalf("%s : %s", file, err.Error())
+		}
+
+		expected := readFile(t, strings.TrimSuffix(file, ".jack")+"T.xml")
+		expectXml(t, file, expected, actual)
+	}
+}
+
+func TestClass(t *testing.T) {
+	files, err := filepath.Glob("../../10/*/*.jack")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	for _, file := range files {
+		p := parser.New(lexer.New(readFile(t, file)))
+		class, err := p.ParseClass()
+		if err != nil {
+			t.Fatalf("%s : %s", file, err.Error())
+		}
+
+		expected := readFile(t, strings.TrimSuffix(file, ".jack")+".xml")
+		expectXml(t, file, expected, Class(class))
+	}
+}
+
+func TestExpressions(t *testing.T) {
+	tests := []struct {
+		input string
+		terms []string
+	}{
+		{"let x = a + -b * c;", []string{"a", "+", "-", "b", "*", "c"}},
+		{"let x = ~-a | b;", []string{"~", "-", "a", "|", "b"}},
+		{"let x = (a < b) & c;", []string{"(", "a", "&lt;", "b", ")", "&amp;", "c"}},
+	}
+
+	for _, test := range tests {
+		p := parser.New(lexer.New("class A { function void f() { " + test.input + " return; } }"))
+		class, err := p.ParseClass()
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+
+		var values []string
+		for _, line := range strings.Split(Class(class), "\n") {
+			line = strings.TrimSpace(line)
+			if strings.HasPrefix(line, "<identifier>") || strings.HasPrefix(line, "<symbol>") {
+				values = append(values, strings.Fields(line)[1])
+			}
+		}
+
+		// skip: A { f ( ) { x =
+		values = values[8:]
+		for i, term := range test.terms {
+			if values[i] != term {
+				t.Fatalf("%s : term %d expected: %s, got: %s", test.input, i, term, values[i])
+			}
+		}
+	}
+}
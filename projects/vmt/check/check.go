@@ -0,0 +1,220 @@
+// Package check finds the mistakes in a vm program that would otherwise only
+// show up once it was translated and run: locals past the end of a function's
+// frame, jumps to labels that are not defined, calls to functions that do not
+// exist and calls passing fewer arguments than the function reads. The
+// parser has already rejected unknown segments and indexes past the end of
+// pointer and temp.
+package check
+
+import (
+	"fmt"
+	"jack/osapi"
+	"sort"
+	"strings"
+	cw "vmt/codewriter"
+)
+
+// Diagnostic is a problem at a line of the program
+type Diagnostic struct {
+	Source cw.Source
+	Msg    string
+}
+
+func (d *Diagnostic) Error() string {
+	return fmt.Sprintf("%s: %s", d.Source, d.Msg)
+}
+
+// Diagnostics holds every problem found, in the order of the program
+type Diagnostics []*Diagnostic
+
+func (ds Diagnostics) Error() string {
+	msgs := make([]string, len(ds))
+	for i, d := range ds {
+		msgs[i] = d.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+type Options struct {
+	// External holds the functions defined outside the program, such as the
+	// os, that may be called without being defined
+	External map[string]bool
+}
+
+// OS holds the functions of the jack os, programs compiled from jack call
+// them without defining them unless the os is translated with them. They are
+// read from the same api the jack compiler checks os calls against.
+var OS = external(osapi.Classes)
+
+// external names the subroutines of the classes of an api
+func external(api map[string]*osapi.Class) map[string]bool {
+	functions := map[string]bool{}
+	for _, class := range api {
+		for _, s := range class.Subroutines {
+			functions[class.Name + "." + s.Name] = true
+		}
+	}
+	return functions
+}
+
+// function is what the checker knows of a function
+type function struct {
+	name  string
+	nvars int
+	// args is the number of arguments the function reads, one more than the
+	// largest argument index used
+	args   int
+	labels map[string]bool
+}
+
+// call is the first call made to a function
+type call struct {
+	nargs int
+	at    int
+}
+
+// Check validates the statements of a program, sources holds the line each
+// statement was parsed from. It returns nil or the Diagnostics found.
+func Check(statements []cw.Statement, sources []cw.Source, opts Options) error {
+	c := checker{ sources: sources, functions: map[string]*function{} }
+	c.collect(statements)
+	c.resolve(statements, opts)
+
+	sort.SliceStable(c.diags, func(i, j int) bool { return c.diags[i].at < c.diags[j].at })
+
+	var ds Diagnostics
+	for _, d := range c.diags {
+		ds = append(ds, d.Diagnostic)
+	}
+	if len(ds) > 0 {
+		return ds
+	}
+	return nil
+}
+
+type checker struct {
+	sources   []cw.Source
+	functions map[string]*function
+	// top holds the labels of code outside any function
+	top   *function
+	diags []diagnostic
+}
+
+// diagnostic is a Diagnostic with the index of its statement for sorting
+type diagnostic struct {
+	*Diagnostic
+	at int
+}
+
+func (c *checker) errorf(at int, format string, args ...interface{}) {
+	var source cw.Source
+	if at < len(c.sources) {
+		source = c.sources[at]
+	}
+	c.diags = append(c.diags, diagnostic{ &Diagnostic{ Source: source, Msg: fmt.Sprintf(format, args...) }, at })
+}
+
+// collect records the functions and their labels and checks the local and
+// argument indexes of every push and pop
+func (c *checker) collect(statements []cw.Statement) {
+	c.top = &function{ labels: map[string]bool{} }
+	current := c.top
+
+	for i, statement := range statements {
+		switch s := statement.(type) {
+			case *cw.FunctionStatement:
+				if _, ok := c.functions[s.Name]; ok {
+					c.errorf(i, "function %s is already defined", s.Name)
+				}
+				current = &function{ name: s.Name, nvars: s.Nvars, labels: map[string]bool{} }
+				c.functions[s.Name] = current
+
+			case *cw.LabelStatement:
+				if current.labels[s.Name] {
+					c.errorf(i, "duplicate label %s%s", s.Name, in(current))
+				}
+				current.labels[s.Name] = true
+
+			case *cw.PushLocationStatement:
+				c.location(i, current, &s.LocationStatement)
+
+			case *cw.PopStatement:
+				c.location(i, current, &s.LocationStatement)
+		}
+	}
+}
+
+// location checks a push or pop against the frame of its function
+func (c *checker) location(at int, f *function, s *cw.LocationStatement) {
+	// outside a function nothing is known of the frame
+	if f.name == "" {
+		return
+	}
+	switch s.Location {
+		case "local":
+			if s.Argument >= f.nvars {
+				c.errorf(at, "local %d out of range, %s has %d local(s)", s.Argument, f.name, f.nvars)
+			}
+		case "argument":
+			if s.Argument + 1 > f.args {
+				f.args = s.Argument + 1
+			}
+	}
+}
+
+// resolve checks every goto against the labels of its function and every
+// call against the functions defined
+func (c *checker) resolve(statements []cw.Statement, opts Options) {
+	calls := map[string]call{}
+	current := c.top
+
+	for i, statement := range statements {
+		switch s := statement.(type) {
+			case *cw.FunctionStatement:
+				current = c.functions[s.Name]
+
+			case *cw.GotoStatement:
+				if !current.labels[s.Name] {
+					c.errorf(i, "undefined label %s%s", s.Name, in(current))
+				}
+
+			case *cw.IfGotoStatement:
+				if !current.labels[s.Name] {
+					c.errorf(i, "undefined label %s%s", s.Name, in(current))
+				}
+
+			case *cw.CallStatement:
+				if first, ok := calls[s.Name]; !ok {
+					calls[s.Name] = call{ nargs: s.Nargs, at: i }
+				} else if first.nargs != s.Nargs {
+					c.errorf(i, "%s called with %d argument(s), %s calls it with %d", s.Name, s.Nargs, c.source(first.at), first.nargs)
+				}
+
+				f, ok := c.functions[s.Name]
+				if !ok {
+					if !opts.External[s.Name] {
+						c.errorf(i, "undefined function %s", s.Name)
+					}
+					continue
+				}
+				if s.Nargs < f.args {
+					c.errorf(i, "%s called with %d argument(s) but reads %d", s.Name, s.Nargs, f.args)
+				}
+		}
+	}
+}
+
+func (c *checker) source(at int) string {
+	if at < len(c.sources) {
+		return c.sources[at].String()
+	}
+	return fmt.Sprintf("statement %d", at)
+}
+
+// in names the function a label is in for diagnostics
+func in(f *function) string {
+	if f.name == "" {
+		return ""
+	}
+	return " in " + f.name
+}
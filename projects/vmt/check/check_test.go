@@ -0,0 +1,115 @@
+package check
+
+import (
+	"testing"
+	"vmt/parser"
+)
+
+type file struct {
+	name string
+	code string
+}
+
+func parse(t *testing.T, files []file) *parser.Parser {
+	var p parser.Parser
+	for _, f := range files {
+		if err := p.Parse(f.code, f.name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return &p
+}
+
+func TestCheck(t *testing.T) {
+	p := parse(t, []file{
+		{ "Main", `function Main.main 1
+push local 1
+pop local 0
+label LOOP
+label LOOP
+if-goto LOOP
+goto END
+call Foo.bar 1
+call Foo.bar 2
+call Foo.baz 0
+call Output.printInt 1
+return` },
+		{ "Foo", `function Foo.bar 0
+push argument 1
+return` },
+	})
+
+	err := Check(p.Statements, p.Sources, Options{ External: OS })
+
+	ds, ok := err.(Diagnostics)
+	if !ok {
+		t.Fatalf("expected: Diagnostics, got: %T %v", err, err)
+	}
+
+	expected := []string{
+		"Main.vm:2: local 1 out of range, Main.main has 1 local(s)",
+		"Main.vm:5: duplicate label LOOP in Main.main",
+		"Main.vm:7: undefined label END in Main.main",
+		"Main.vm:8: Foo.bar called with 1 argument(s) but reads 2",
+		"Main.vm:9: Foo.bar called with 2 argument(s), Main.vm:8 calls it with 1",
+		"Main.vm:10: undefined function Foo.baz",
+	}
+
+	if len(ds) != len(expected) {
+		t.Fatalf("expected %d diagnostics, got: %d\n%v", len(expected), len(ds), err)
+	}
+	for i := range expected {
+		if ds[i].Error() != expected[i] {
+			t.Errorf("expected: %v, got: %v", expected[i], ds[i].Error())
+		}
+	}
+}
+
+func TestCheck_Labels(t *testing.T) {
+	// labels belong to the function they are in
+	p := parse(t, []file{
+		{ "Main", `function Main.a 0
+label LOOP
+goto LOOP
+function Main.b 0
+label LOOP
+goto LOOP
+goto OTHER` },
+	})
+
+	err := Check(p.Statements, p.Sources, Options{})
+
+	ds, ok := err.(Diagnostics)
+	if !ok || len(ds) != 1 {
+		t.Fatalf("expected one diagnostic, got: %v", err)
+	}
+	if ds[0].Error() != "Main.vm:7: undefined label OTHER in Main.b" {
+		t.Errorf("expected: Main.vm:7: undefined label OTHER in Main.b, got: %v", ds[0].Error())
+	}
+}
+
+func TestCheck_Clean(t *testing.T) {
+	p := parse(t, []file{
+		{ "Main", `function Main.main 2
+push constant 1
+pop local 1
+push local 1
+call Main.f 1
+pop temp 7
+push pointer 1
+call Math.multiply 2
+return
+function Main.f 0
+push argument 0
+return` },
+	})
+
+	if err := Check(p.Statements, p.Sources, Options{ External: OS }); err != nil {
+		t.Errorf("expected no diagnostics, got:\n%v", err)
+	}
+
+	// the os is only known when asked for
+	if err := Check(p.Statements, p.Sources, Options{}); err == nil {
+		t.Errorf("expected Math.multiply to be undefined")
+	}
+}
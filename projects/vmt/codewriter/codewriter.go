@@ -1,12 +1,16 @@
 package codewriter
 
 import (
+	"bufio"
+	"errors"
 	"fmt"
+	"io"
 	"strings"
 )
 
 type CodeWriter struct {
 	strings.Builder
+	labels labels
 }
 
 func (cw *CodeWriter) Writeln(s string, args ...interface{}){
@@ -18,31 +22,206 @@ func (cw *CodeWriter) Writeln(s string, args ...interface{}){
 	cw.WriteString("\n")
 }
 
-// Write takes a list of statements and builds the asembly code string.
-func Write(statements []Statement) string {
-	var cw CodeWriter
+// Bootstrap selects the code Write emits before the program
+type Bootstrap int
+
+const (
+	// BootstrapNone emits no bootstrap, the program starts at its first
+	// statement with SP left as the test scripts set it
+	BootstrapNone Bootstrap = iota
+	// BootstrapSpec sets SP to 256 and calls Sys.init
+	BootstrapSpec
+	// BootstrapEntry sets SP to 256 and calls WriteOptions.Entry
+	BootstrapEntry
+)
 
-	// bootstrap code
+// the function BootstrapSpec calls
+const SysInit = "Sys.init"
+
+type WriteOptions struct {
+	Bootstrap Bootstrap
+	// Entry is the function BootstrapEntry calls
+	Entry string
+	// Optimize fuses statements and runs the peephole pass over the code
+	Optimize bool
+	// Shared makes calls, returns and comparisons jump to one shared copy
+	// of their code rather than inlining it at every use
+	Shared bool
+}
+
+// bootstrap sets SP to 256 and calls entry with a real frame so entry can
+// return like any other function
+func (cw *CodeWriter) bootstrap(entry string) error {
 	cw.Writeln("// bootstrap")
-	cw.Writeln("@261")
+	cw.Writeln("@256")
 	cw.Writeln("D=A")
 	cw.Writeln("@SP")
 	cw.Writeln("M=D")
-	// call Sys.init
-	cw.Writeln("@LCL")
-	cw.Writeln("M=0")
-	cw.Writeln("@ARG")
-	cw.Writeln("M=0")
-	cw.Writeln("@THIS")
-	cw.Writeln("M=0")
-	cw.Writeln("@THAT")
-	cw.Writeln("M=0")
-	cw.Writeln("@Sys.init")
-	cw.Writeln("0;JMP")
-
-	for _, statement := range statements {
-		statement.Compile(&cw)
-	}
-
-	return cw.String()
-}
\ No newline at end of file
+
+	call := CallStatement{ Name: entry }
+	return call.Compile(cw)
+}
+
+// Write takes a list of statements and builds the asembly code string.
+func Write(statements []Statement, opts WriteOptions) (string, error) {
+	code, _, err := WriteSourceMap(statements, nil, opts)
+	return code, err
+}
+
+// WriteSourceMap builds the assembly code like Write along with a map from
+// rom addresses back to the vm lines, sources holds the line each statement
+// was parsed from. With no sources no map is built.
+func WriteSourceMap(statements []Statement, sources []Source, opts WriteOptions) (string, *SourceMap, error) {
+	var code strings.Builder
+	sm, err := WriteTo(&code, statements, sources, opts)
+	if err != nil {
+		return "", nil, err
+	}
+	return code.String(), sm, nil
+}
+
+// WriteTo writes the assembly code for the statements to w as each one is
+// translated rather than building the whole program first, returning the
+// source map like WriteSourceMap when there are sources.
+func WriteTo(w io.Writer, statements []Statement, sources []Source, opts WriteOptions) (*SourceMap, error) {
+	var cw CodeWriter
+
+	mapped := sources != nil
+	if mapped && len(sources) != len(statements) {
+		return nil, errors.New(fmt.Sprintf("source map: %d sources for %d statements", len(sources), len(statements)))
+	}
+
+	out := newOutput(w, opts.Optimize, sources)
+
+	if mapped {
+		cw.mark(-1)
+	}
+
+	switch opts.Bootstrap {
+		case BootstrapNone:
+		case BootstrapSpec:
+			if err := cw.bootstrap(SysInit); err != nil {
+				return nil, err
+			}
+		case BootstrapEntry:
+			if opts.Entry == "" {
+				return nil, errors.New("bootstrap: no entry function")
+			}
+			if err := cw.bootstrap(opts.Entry); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, errors.New(fmt.Sprintf("bootstrap: unknown mode %d", opts.Bootstrap))
+	}
+
+	// origins[i] is the index in statements statement i was made from,
+	// sharing swaps statements one for one
+	origins := make([]int, len(statements))
+	for i := range origins {
+		origins[i] = i
+	}
+
+	if opts.Shared {
+		statements = Share(statements)
+	}
+	if opts.Optimize {
+		var fused []int
+		statements, fused = optimize(statements)
+		for i, origin := range fused {
+			fused[i] = origins[origin]
+		}
+		origins = fused
+	}
+
+	for i, statement := range statements {
+		if mapped {
+			cw.mark(origins[i])
+		}
+		if err := statement.Compile(&cw); err != nil {
+			return nil, errors.New(fmt.Sprintf("%v: %s", statement, err.Error()))
+		}
+		if err := out.write(&cw); err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.Shared {
+		if mapped {
+			cw.mark(-1)
+		}
+		if err := cw.writeShared(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := out.write(&cw); err != nil {
+		return nil, err
+	}
+	if err := out.close(); err != nil {
+		return nil, err
+	}
+	return out.sm, nil
+}
+
+// output carries code on to a writer a statement at a time, through the
+// peephole pass when optimizing, stripping out the source map markers
+type output struct {
+	w        *bufio.Writer
+	peephole *peephole
+	sources  []Source
+	sm       *SourceMap
+	address  int
+	err      error
+}
+
+func newOutput(w io.Writer, optimize bool, sources []Source) *output {
+	out := &output{ w: bufio.NewWriter(w), sources: sources }
+	if optimize {
+		out.peephole = &peephole{ emit: out.emit }
+	}
+	if sources != nil {
+		out.sm = &SourceMap{}
+	}
+	return out
+}
+
+// write takes the code written so far and empties the code writer
+func (out *output) write(cw *CodeWriter) error {
+	code := cw.String()
+	cw.Reset()
+
+	if code != "" {
+		for _, line := range strings.Split(strings.TrimSuffix(code, "\n"), "\n") {
+			if out.peephole != nil {
+				out.peephole.add(line)
+			} else {
+				out.emit(line)
+			}
+		}
+	}
+	return out.err
+}
+
+func (out *output) emit(line string) {
+	if out.sm != nil && strings.HasPrefix(line, marker) {
+		out.sm.unmark(line, out.address, out.sources)
+		return
+	}
+
+	if Instructions(line) > 0 {
+		out.address++
+	}
+	if out.err == nil {
+		_, out.err = out.w.WriteString(line + "\n")
+	}
+}
+
+func (out *output) close() error {
+	if out.peephole != nil {
+		out.peephole.flush()
+	}
+	if out.err != nil {
+		return out.err
+	}
+	return out.w.Flush()
+}
@@ -1,6 +1,8 @@
 package codewriter
 
 import (
+	"errors"
+	"hackemu/hack"
 	"strings"
 	"testing"
 )
@@ -22,21 +24,6 @@ func TestCodewriter(t *testing.T) {
 	}
 
 	expected := []string{
-		"// bootstrap",
-		"@261",
-		"D=A",
-		"@SP",
-		"M=D",
-		"@LCL",
-		"M=0",
-		"@ARG",
-		"M=0",
-		"@THIS",
-		"M=0",
-		"@THAT",
-		"M=0",
-		"@Sys.init",
-		"0;JMP",
 		"// push constant 8",
 		"@8",
 		"D=A",
@@ -77,7 +64,12 @@ func TestCodewriter(t *testing.T) {
 		"M=D",
 	}
 
-	actual := strings.Split(Write(input), "\n")
+	code, err := Write(input, WriteOptions{})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	actual := strings.Split(code, "\n")
 
 	for i := range expected {
 		if actual[i] != expected[i] {
@@ -86,3 +78,184 @@ func TestCodewriter(t *testing.T) {
 	}
 }
 
+
+
+func TestBootstrap(t *testing.T) {
+	tests := []struct {
+		opts WriteOptions
+		first string
+		call string
+	}{
+		{ WriteOptions{ Bootstrap: BootstrapNone }, "// push constant 8", "" },
+		{ WriteOptions{ Bootstrap: BootstrapSpec }, "// bootstrap", "// call Sys.init 0" },
+		{ WriteOptions{ Bootstrap: BootstrapEntry, Entry: "Main.main" }, "// bootstrap", "// call Main.main 0" },
+	}
+
+	for _, test := range tests {
+		code, err := Write([]Statement{&PushConstStatement{ Argument: 8 }}, test.opts)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+
+		actual := strings.Split(code, "\n")
+		if actual[0] != test.first {
+			t.Errorf("expected: %v, got: %v", test.first, actual[0])
+		}
+		if test.call == "" {
+			continue
+		}
+
+		// SP=256 then a real call
+		expected := []string{ "@256", "D=A", "@SP", "M=D", test.call }
+		for i := range expected {
+			if actual[i + 1] != expected[i] {
+				t.Errorf("expected: %v, got: %v", expected[i], actual[i + 1])
+			}
+		}
+	}
+
+	if _, err := Write(nil, WriteOptions{ Bootstrap: BootstrapEntry }); err == nil {
+		t.Errorf("expected error bootstrapping without an entry")
+	}
+}
+
+// runHack assembles the code and runs it on the hack emulator with the stack
+// at 256 until it reaches the halt loop written after it
+// writes records each write it is given
+type writes []string
+
+func (w *writes) Write(p []byte) (int, error) {
+	*w = append(*w, string(p))
+	return len(p), nil
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestWriteTo(t *testing.T) {
+	pop := PopStatement{ }
+	pop.Location = "temp"
+	pop.Argument = 0
+
+	// long enough that the peephole pass hands lines on as it goes
+	var input []Statement
+	for i := 0; i < 2000; i++ {
+		input = append(input, &PushConstStatement{ Argument: 1 }, &pop)
+	}
+
+	var w writes
+	if _, err := WriteTo(&w, input, nil, WriteOptions{ Optimize: true }); err != nil {
+		t.Fatalf(err.Error())
+	}
+	if len(w) < 2 {
+		t.Errorf("expected the code written as it is translated, got: %d write(s)", len(w))
+	}
+
+	expected := strings.Repeat("// push constant 1\n// pop temp 0\nD=1\n@5\nM=D\n", 2000)
+	if actual := strings.Join(w, ""); actual != expected {
+		t.Errorf("expected %d bytes, got: %d", len(expected), len(actual))
+	}
+
+	if _, err := WriteTo(failingWriter{}, input, nil, WriteOptions{}); err == nil || err.Error() != "disk full" {
+		t.Errorf("expected: disk full, got: %v", err)
+	}
+}
+
+func runHack(t *testing.T, statements []Statement, opts WriteOptions) *hack.Machine {
+	code, err := Write(statements, opts)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	code += "(HALT)\n@HALT\n0;JMP\n"
+
+	program, err := hack.ParseProgram("test.asm", code)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	m := hack.New()
+	if err := m.Load(program); err != nil {
+		t.Fatalf(err.Error())
+	}
+	m.RAM[0] = 256
+	m.Run(10000)
+
+	return m
+}
+
+func TestReturnNoArgs(t *testing.T) {
+	pop := PopStatement{}
+	pop.Location = "temp"
+
+	statements := []Statement{
+		&PushConstStatement{ Argument: 3 },
+		&CallStatement{ Name: "Main.f", Nargs: 0 },
+		&AddStatement{},
+		&pop,
+		&LabelStatement{ Name: "END" },
+		&GotoStatement{ Name: "END" },
+		&FunctionStatement{ Name: "Main.f", Nvars: 0 },
+		&PushConstStatement{ Argument: 38 },
+		&ReturnStatement{},
+	}
+
+	for _, opts := range []WriteOptions{ {}, { Shared: true }, { Shared: true, Optimize: true } } {
+		m := runHack(t, statements, opts)
+
+		// the return value lands in arg[0], the slot the return address was
+		// in, just above the 3 pushed before the call
+		if m.RAM[5] != 41 {
+			t.Errorf("%+v: expected temp 0: 41, got: %d", opts, m.RAM[5])
+		}
+		if m.RAM[0] != 256 {
+			t.Errorf("%+v: expected SP 256, got: %d", opts, m.RAM[0])
+		}
+	}
+}
+
+// pushValue pushes any int16, push constant only takes 0..32767
+func pushValue(v int) []Statement {
+	switch {
+		case v == -32768:
+			return []Statement{ &PushConstStatement{ Argument: 32767 }, &NegStatement{}, &PushConstStatement{ Argument: 1 }, &SubStatement{} }
+		case v < 0:
+			return []Statement{ &PushConstStatement{ Argument: -v }, &NegStatement{} }
+	}
+	return []Statement{ &PushConstStatement{ Argument: v } }
+}
+
+func TestCompareExtremes(t *testing.T) {
+	values := []int{ 32767, 32766, 1, 0, -1, -2, -32767, -32768 }
+
+	for _, opts := range []WriteOptions{ {}, { Shared: true }, { Shared: true, Optimize: true } } {
+		for _, x := range values {
+			for _, y := range values {
+				var statements []Statement
+				for i, cmp := range []Statement{ &GtStatement{ }, &LtStatement{ }, &EqStatement{ } } {
+					statements = append(statements, pushValue(x)...)
+					statements = append(statements, pushValue(y)...)
+					statements = append(statements, cmp)
+					pop := PopStatement{}
+					pop.Location = "temp"
+					pop.Argument = i
+					statements = append(statements, &pop)
+				}
+
+				m := runHack(t, statements, opts)
+
+				expected := []bool{ x > y, x < y, x == y }
+				for i, name := range []string{ "gt", "lt", "eq" } {
+					if (m.RAM[5 + i] == -1) != expected[i] || (m.RAM[5 + i] != 0 && m.RAM[5 + i] != -1) {
+						t.Errorf("%+v: %d %s %d, expected: %v, got: %d", opts, x, name, y, expected[i], m.RAM[5 + i])
+					}
+				}
+				if m.RAM[0] != 256 {
+					t.Errorf("%+v: %d cmp %d, expected SP 256, got: %d", opts, x, y, m.RAM[0])
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package codewriter
+
+// Eliminate drops the functions that can not be reached by calls from entry.
+// Statements before the first function are always kept and their calls are
+// followed too. It returns the statements left and the names of the
+// functions removed, in the order they were defined.
+func Eliminate(statements []Statement, entry string) ([]Statement, []string) {
+	keep, removed := Reachable(statements, entry)
+
+	var kept []Statement
+	for i, statement := range statements {
+		if keep[i] {
+			kept = append(kept, statement)
+		}
+	}
+	return kept, removed
+}
+
+// Reachable is Eliminate reporting which statements to keep rather than
+// dropping them, so anything kept alongside the statements can be filtered
+// the same way
+func Reachable(statements []Statement, entry string) ([]bool, []string) {
+	// split the program into function bodies
+	owner := make([]string, len(statements))
+	bodies := map[string][]Statement{}
+	var order []string
+	var top []Statement
+	current := ""
+
+	for i, statement := range statements {
+		if f, ok := statement.(*FunctionStatement); ok {
+			current = f.Name
+			if _, ok := bodies[current]; !ok {
+				order = append(order, current)
+			}
+		}
+		owner[i] = current
+		if current == "" {
+			top = append(top, statement)
+		} else {
+			bodies[current] = append(bodies[current], statement)
+		}
+	}
+
+	// walk the calls out from the entry
+	reached := map[string]bool{}
+	var queue []string
+
+	visit := func(body []Statement) {
+		for _, statement := range body {
+			if call, ok := statement.(*CallStatement); ok && !reached[call.Name] {
+				reached[call.Name] = true
+				queue = append(queue, call.Name)
+			}
+		}
+	}
+
+	reached[entry] = true
+	queue = append(queue, entry)
+	visit(top)
+
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		visit(bodies[name])
+	}
+
+	keep := make([]bool, len(statements))
+	for i, name := range owner {
+		keep[i] = name == "" || reached[name]
+	}
+
+	var removed []string
+	for _, name := range order {
+		if !reached[name] {
+			removed = append(removed, name)
+		}
+	}
+
+	return keep, removed
+}
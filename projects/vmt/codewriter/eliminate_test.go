@@ -0,0 +1,63 @@
+package codewriter
+
+import (
+	"testing"
+)
+
+func TestEliminate(t *testing.T) {
+	input := []Statement{
+		&FunctionStatement{ Name: "Sys.init", Nvars: 0 },
+		&CallStatement{ Name: "Main.main", Nargs: 0 },
+		&FunctionStatement{ Name: "Output.unused", Nvars: 0 },
+		&CallStatement{ Name: "Output.helper", Nargs: 0 },
+		&ReturnStatement{},
+		&FunctionStatement{ Name: "Main.main", Nvars: 0 },
+		&CallStatement{ Name: "Main.main", Nargs: 0 },
+		&CallStatement{ Name: "Math.multiply", Nargs: 2 },
+		&ReturnStatement{},
+		&FunctionStatement{ Name: "Output.helper", Nvars: 0 },
+		&ReturnStatement{},
+		&FunctionStatement{ Name: "Math.multiply", Nvars: 0 },
+		&ReturnStatement{},
+	}
+
+	kept, removed := Eliminate(input, "Sys.init")
+
+	expected := []string{ "Output.unused", "Output.helper" }
+	if len(removed) != len(expected) {
+		t.Fatalf("expected removed: %v, got: %v", expected, removed)
+	}
+	for i := range expected {
+		if removed[i] != expected[i] {
+			t.Errorf("expected: %v, got: %v", expected[i], removed[i])
+		}
+	}
+
+	if len(kept) != 8 {
+		t.Fatalf("expected 8 statements kept, got: %d", len(kept))
+	}
+	if f, ok := kept[2].(*FunctionStatement); !ok || f.Name != "Main.main" {
+		t.Errorf("expected: < function Main.main 0 >, got: %v", kept[2])
+	}
+}
+
+func TestEliminateTopLevel(t *testing.T) {
+	// statements outside of any function are kept along with what they call
+	input := []Statement{
+		&PushConstStatement{ Argument: 1 },
+		&CallStatement{ Name: "Main.f", Nargs: 1 },
+		&FunctionStatement{ Name: "Main.f", Nvars: 0 },
+		&ReturnStatement{},
+		&FunctionStatement{ Name: "Main.g", Nvars: 0 },
+		&ReturnStatement{},
+	}
+
+	kept, removed := Eliminate(input, "Sys.init")
+
+	if len(kept) != 4 {
+		t.Errorf("expected 4 statements kept, got: %d", len(kept))
+	}
+	if len(removed) != 1 || removed[0] != "Main.g" {
+		t.Errorf("expected removed: [Main.g], got: %v", removed)
+	}
+}
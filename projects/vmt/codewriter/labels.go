@@ -0,0 +1,62 @@
+package codewriter
+
+import (
+	"errors"
+	"fmt"
+)
+
+// labels hands out the labels generated code jumps to. A label is named after
+// the function it is in and numbered by how many of its kind came before it
+// in that function, <function>$$<kind>.<n>, so labels can not collide across
+// files and only change when their own function does. Label commands are
+// named <function>$<label> as the spec has them, the parser keeps $ out of
+// label names so neither can collide with the other or with a function.
+type labels struct {
+	function string
+	// next number for each function and kind
+	counts map[string]int
+	// user labels defined so far, by their full name
+	defined map[string]bool
+}
+
+// enter starts numbering the labels of a function
+func (l *labels) enter(function string) {
+	l.function = function
+}
+
+// next returns a new label of a kind: ret, eq, gt, lt, if or cmp
+func (l *labels) next(kind string) string {
+	if l.counts == nil {
+		l.counts = map[string]int{}
+	}
+
+	key := fmt.Sprintf("%s$$%s", l.function, kind)
+	n := l.counts[key]
+	l.counts[key]++
+
+	return fmt.Sprintf("%s.%d", key, n)
+}
+
+// define returns the label for a label command, a name can only be defined
+// once in a function
+func (l *labels) define(function, name string) (string, error) {
+	if l.defined == nil {
+		l.defined = map[string]bool{}
+	}
+
+	label := userLabel(function, name)
+	if l.defined[label] {
+		if function == "" {
+			return "", errors.New(fmt.Sprintf("duplicate label: %s", name))
+		}
+		return "", errors.New(fmt.Sprintf("duplicate label: %s in %s", name, function))
+	}
+	l.defined[label] = true
+
+	return label, nil
+}
+
+// userLabel is the label a label, goto or if-goto command refers to
+func userLabel(function, name string) string {
+	return fmt.Sprintf("%s$%s", function, name)
+}
@@ -0,0 +1,129 @@
+package codewriter
+
+import (
+	"hackemu/hack"
+	"strings"
+	"testing"
+)
+
+func TestLabels(t *testing.T) {
+	var l labels
+
+	l.enter("Foo.bar")
+	expected := []string{ "Foo.bar$$eq.0", "Foo.bar$$eq.1", "Foo.bar$$ret.0" }
+	actual := []string{ l.next("eq"), l.next("eq"), l.next("ret") }
+
+	l.enter("Foo.baz")
+	expected = append(expected, "Foo.baz$$eq.0")
+	actual = append(actual, l.next("eq"))
+
+	for i := range expected {
+		if actual[i] != expected[i] {
+			t.Errorf("expected: %v, got: %v", expected[i], actual[i])
+		}
+	}
+}
+
+func TestLabels_Duplicate(t *testing.T) {
+	var l labels
+
+	if label, err := l.define("Foo.bar", "LOOP"); err != nil || label != "Foo.bar$LOOP" {
+		t.Errorf("expected: Foo.bar$LOOP, got: %v, %v", label, err)
+	}
+	// the same name in another function is a different label
+	if _, err := l.define("Foo.baz", "LOOP"); err != nil {
+		t.Errorf("expected no error, got: %v", err)
+	}
+
+	_, err := l.define("Foo.bar", "LOOP")
+	if err == nil || err.Error() != "duplicate label: LOOP in Foo.bar" {
+		t.Errorf("expected: duplicate label: LOOP in Foo.bar, got: %v", err)
+	}
+
+	input := []Statement{
+		&FunctionStatement{ Name: "Foo.bar" },
+		&LabelStatement{ Name: "LOOP", Function: "Foo.bar" },
+		&LabelStatement{ Name: "LOOP", Function: "Foo.bar" },
+	}
+	if _, err := Write(input, WriteOptions{}); err == nil {
+		t.Errorf("expected an error for a duplicate label")
+	}
+}
+
+func TestLabels_Functions(t *testing.T) {
+	// a label can not take the name of a function, Sys.init.LOOP
+	input := []Statement{
+		&FunctionStatement{ Name: "Sys.init" },
+		&LabelStatement{ Name: "LOOP", Function: "Sys.init" },
+		&GotoStatement{ Name: "LOOP", Function: "Sys.init" },
+		&FunctionStatement{ Name: "Sys.init.LOOP" },
+		&EqStatement{ },
+		&ReturnStatement{ },
+	}
+
+	code, err := Write(input, WriteOptions{})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	for _, label := range []string{ "(Sys.init)", "(Sys.init$LOOP)", "(Sys.init.LOOP)", "(Sys.init.LOOP$$eq.0.true)" } {
+		if n := strings.Count(code, label + "\n"); n != 1 {
+			t.Errorf("expected %s once, got: %d", label, n)
+		}
+	}
+	if _, err := hack.ParseProgram("test.asm", code); err != nil {
+		t.Errorf("expected the code to assemble, got: %v", err)
+	}
+}
+
+// functionCode is the code written for a function, up to the next one
+func functionCode(code, name string) string {
+	start := strings.Index(code, "// function " + name)
+	end := strings.Index(code[start + 1:], "// function ")
+	if end < 0 {
+		return code[start:]
+	}
+	return code[start:start + 1 + end]
+}
+
+func TestLabels_Stable(t *testing.T) {
+	bar := []Statement{
+		&FunctionStatement{ Name: "Foo.bar" },
+		&EqStatement{ },
+		&CallStatement{ Name: "Foo.baz" },
+		&ReturnStatement{ },
+	}
+	baz := []Statement{
+		&FunctionStatement{ Name: "Foo.baz" },
+		&LtStatement{ },
+		&CallStatement{ Name: "Foo.bar" },
+		&ReturnStatement{ },
+	}
+
+	before, err := Write(append(append([]Statement{}, bar...), baz...), WriteOptions{})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	// another comparison and call in Foo.bar leaves Foo.baz as it was
+	changed := append([]Statement{}, bar[:3]...)
+	changed = append(changed, &GtStatement{ }, &CallStatement{ Name: "Foo.baz" }, &ReturnStatement{ })
+	after, err := Write(append(changed, baz...), WriteOptions{})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if functionCode(before, "Foo.baz") != functionCode(after, "Foo.baz") {
+		t.Errorf("expected Foo.baz unchanged, got:\n%s\nand:\n%s", functionCode(before, "Foo.baz"), functionCode(after, "Foo.baz"))
+	}
+
+	// and every label is only defined once
+	seen := map[string]bool{}
+	for _, line := range strings.Split(after, "\n") {
+		if strings.HasPrefix(line, "(") {
+			if seen[line] {
+				t.Errorf("label defined twice: %s", line)
+			}
+			seen[line] = true
+		}
+	}
+}
@@ -0,0 +1,497 @@
+package codewriter
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ---------------------------------------------------------------------------------
+// Statement fusion ----------------------------------------------------------------
+// ---------------------------------------------------------------------------------
+
+// MoveStatement is a push immediately followed by a pop, the value is moved
+// through D without touching the stack
+type MoveStatement struct {
+	Push Statement
+	Pop  Statement
+}
+
+func (s *MoveStatement) String() string {
+	return fmt.Sprintf("< %s; %s >", command(s.Push), command(s.Pop))
+}
+
+func (s *MoveStatement) Compile(cw *CodeWriter) error {
+	cw.Writeln("// %s", command(s.Push))
+	cw.Writeln("// %s", command(s.Pop))
+
+	if err := prepareStore(cw, s.Pop); err != nil {
+		return err
+	}
+	if err := load(cw, s.Push); err != nil {
+		return err
+	}
+	return store(cw, s.Pop)
+}
+
+// OperandStatement is a push immediately followed by add, sub, and or or,
+// the pushed value is applied to the top of the stack in place
+type OperandStatement struct {
+	Push Statement
+	Op   Statement
+}
+
+func (s *OperandStatement) String() string {
+	return fmt.Sprintf("< %s; %s >", command(s.Push), command(s.Op))
+}
+
+func (s *OperandStatement) Compile(cw *CodeWriter) error {
+	comp, ok := binaryOp(s.Op)
+	if !ok {
+		return errors.New(fmt.Sprintf("not a binary operator: %v", s.Op))
+	}
+
+	cw.Writeln("// %s", command(s.Push))
+	cw.Writeln("// %s", command(s.Op))
+
+	if err := load(cw, s.Push); err != nil {
+		return err
+	}
+	cw.Writeln("@SP")
+	cw.Writeln("A=M-1")
+	cw.Writeln("M=%s", comp)
+
+	return nil
+}
+
+// Optimize fuses pairs of statements that would push a value only to pop it
+// straight back off
+func Optimize(statements []Statement) []Statement {
+	optimized, _ := optimize(statements)
+	return optimized
+}
+
+// optimize is Optimize also returning the index of the first statement each
+// optimized statement was made from
+func optimize(statements []Statement) ([]Statement, []int) {
+	var optimized []Statement
+	var origins []int
+
+	for i := 0; i < len(statements); i++ {
+		origins = append(origins, i)
+
+		if i + 1 < len(statements) && canLoad(statements[i]) {
+			next := statements[i + 1]
+
+			if canStore(next) {
+				optimized = append(optimized, &MoveStatement{ Push: statements[i], Pop: next })
+				i++
+				continue
+			}
+			if _, ok := binaryOp(next); ok {
+				optimized = append(optimized, &OperandStatement{ Push: statements[i], Op: next })
+				i++
+				continue
+			}
+		}
+
+		optimized = append(optimized, statements[i])
+	}
+
+	return optimized, origins
+}
+
+// command is the vm command a statement was parsed from
+func command(s Statement) string {
+	if str, ok := s.(fmt.Stringer); ok {
+		return strings.TrimSpace(strings.Trim(str.String(), "<>"))
+	}
+	return fmt.Sprintf("%T", s)
+}
+
+// binaryOp is the comp applying D to the top of the stack for add, sub, and and or
+func binaryOp(s Statement) (string, bool) {
+	switch s.(type) {
+		case *AddStatement:
+			return "D+M", true
+		case *SubStatement:
+			return "M-D", true
+		case *AndStatement:
+			return "D&M", true
+		case *OrStatement:
+			return "D|M", true
+	}
+	return "", false
+}
+
+// largest index a pop walks to with A=A+1 rather than computing the address
+// up front
+const maxWalk = 4
+
+// virtualAddress is the fixed address of a pointer or temp entry
+func virtualAddress(s *LocationStatement) (string, bool) {
+	switch s.Location {
+		case "pointer":
+			if s.Argument == 0 {
+				return "@THIS", true
+			}
+			return "@THAT", true
+		case "temp":
+			return fmt.Sprintf("@%d", 5 + s.Argument), true
+	}
+	return "", false
+}
+
+func canLoad(s Statement) bool {
+	switch s.(type) {
+		case *PushConstStatement, *PushStaticStatement, *PushLocationStatement:
+			return true
+	}
+	return false
+}
+
+func canStore(s Statement) bool {
+	switch s.(type) {
+		case *PopStatement, *PopStaticStatement:
+			return true
+	}
+	return false
+}
+
+// load writes the instructions that put a push's value in D
+func load(cw *CodeWriter, s Statement) error {
+	switch s := s.(type) {
+		case *PushConstStatement:
+			switch s.Argument {
+				case 0:
+					cw.Writeln("D=0")
+				case 1:
+					cw.Writeln("D=1")
+				default:
+					cw.Writeln("@%d", s.Argument)
+					cw.Writeln("D=A")
+			}
+			return nil
+
+		case *PushStaticStatement:
+			cw.Writeln("@%s.%d", s.File, s.Argument)
+			cw.Writeln("D=M")
+			return nil
+
+		case *PushLocationStatement:
+			if address, ok := virtualAddress(&s.LocationStatement); ok {
+				cw.Writeln(address)
+				cw.Writeln("D=M")
+				return nil
+			}
+
+			address, err := s.Address()
+			if err != nil {
+				return err
+			}
+			cw.Writeln(address)
+			if s.Argument == 0 {
+				cw.Writeln("A=M")
+			} else {
+				cw.Writeln("D=M")
+				cw.Writeln("@%d", s.Argument)
+				cw.Writeln("A=D+A")
+			}
+			cw.Writeln("D=M")
+			return nil
+	}
+
+	return errors.New(fmt.Sprintf("cannot load: %v", s))
+}
+
+// prepareStore writes anything a pop needs before D is loaded, a pop far
+// into a segment has its address worked out into R15 up front
+func prepareStore(cw *CodeWriter, s Statement) error {
+	pop, ok := s.(*PopStatement)
+	if !ok || pop.Argument <= maxWalk {
+		return nil
+	}
+	if _, ok := virtualAddress(&pop.LocationStatement); ok {
+		return nil
+	}
+
+	address, err := pop.Address()
+	if err != nil {
+		return err
+	}
+	cw.Writeln(address)
+	cw.Writeln("D=M")
+	cw.Writeln("@%d", pop.Argument)
+	cw.Writeln("D=D+A")
+	cw.Writeln("@R15")
+	cw.Writeln("M=D")
+
+	return nil
+}
+
+// store writes the instructions that put D in a pop's location
+func store(cw *CodeWriter, s Statement) error {
+	switch s := s.(type) {
+		case *PopStaticStatement:
+			cw.Writeln("@%s.%d", s.File, s.Argument)
+			cw.Writeln("M=D")
+			return nil
+
+		case *PopStatement:
+			if address, ok := virtualAddress(&s.LocationStatement); ok {
+				cw.Writeln(address)
+				cw.Writeln("M=D")
+				return nil
+			}
+
+			if s.Argument > maxWalk {
+				cw.Writeln("@R15")
+				cw.Writeln("A=M")
+				cw.Writeln("M=D")
+				return nil
+			}
+
+			address, err := s.Address()
+			if err != nil {
+				return err
+			}
+			cw.Writeln(address)
+			if s.Argument == 0 {
+				cw.Writeln("A=M")
+			} else {
+				cw.Writeln("A=M+1")
+				for i := 1; i < s.Argument; i++ {
+					cw.Writeln("A=A+1")
+				}
+			}
+			cw.Writeln("M=D")
+			return nil
+	}
+
+	return errors.New(fmt.Sprintf("cannot store: %v", s))
+}
+
+// ---------------------------------------------------------------------------------
+// Peephole ------------------------------------------------------------------------
+// ---------------------------------------------------------------------------------
+
+// a rule looks at the last few instructions written and returns how many of
+// them to replace and what with, n is 0 when the rule does not apply
+type rule func(tail []string) (n int, replace []string)
+
+// pattern is a rule replacing an exact run of instructions
+func pattern(match []string, replace ...string) rule {
+	return func(tail []string) (int, []string) {
+		if len(tail) < len(match) {
+			return 0, nil
+		}
+		tail = tail[len(tail) - len(match):]
+		for i := range match {
+			if tail[i] != match[i] {
+				return 0, nil
+			}
+		}
+		return len(match), replace
+	}
+}
+
+// deadA drops an A instruction straight away overwritten by another
+func deadA(tail []string) (int, []string) {
+	if len(tail) < 2 {
+		return 0, nil
+	}
+	a, b := tail[len(tail) - 2], tail[len(tail) - 1]
+	if strings.HasPrefix(a, "@") && strings.HasPrefix(b, "@") {
+		return 2, []string{ b }
+	}
+	return 0, nil
+}
+
+// deadD drops a store to D that the next instruction overwrites unread
+func deadD(tail []string) (int, []string) {
+	if len(tail) < 2 {
+		return 0, nil
+	}
+	a, b := tail[len(tail) - 2], tail[len(tail) - 1]
+
+	destA, _, jumpA := splitC(a)
+	destB, compB, _ := splitC(b)
+	if destA == "D" && jumpA == "" && strings.Contains(destB, "D") && !strings.Contains(compB, "D") {
+		return 2, []string{ b }
+	}
+	return 0, nil
+}
+
+// splitC splits a c instruction into its fields, anything else has none
+func splitC(instruction string) (dest, comp, jump string) {
+	if instruction == "" || strings.HasPrefix(instruction, "@") || strings.HasPrefix(instruction, "(") {
+		return "", "", ""
+	}
+	comp = instruction
+	if i := strings.Index(comp, "="); i >= 0 {
+		dest, comp = comp[:i], comp[i + 1:]
+	}
+	if i := strings.Index(comp, ";"); i >= 0 {
+		comp, jump = comp[:i], comp[i + 1:]
+	}
+	return dest, comp, jump
+}
+
+var rules = []rule{
+	// a push straight into a pop leaves the value in D and SP where it was
+	pattern(
+		[]string{ "@SP", "A=M", "M=D", "@SP", "M=M+1", "@SP", "AM=M-1", "D=M" },
+		"@SP", "A=M", "M=D",
+	),
+	// neg and not of a value just pushed
+	pattern(
+		[]string{ "M=D", "@SP", "M=M+1", "@SP", "A=M-1", "M=-M" },
+		"M=-D", "@SP", "M=M+1",
+	),
+	pattern(
+		[]string{ "M=D", "@SP", "M=M+1", "@SP", "A=M-1", "M=!M" },
+		"M=!D", "@SP", "M=M+1",
+	),
+	// storing D to a register and reading it straight back
+	pattern([]string{ "@R13", "M=D", "@R13", "D=M" }, "@R13", "M=D"),
+	pattern([]string{ "@R14", "M=D", "@R14", "D=M" }, "@R14", "M=D"),
+	pattern([]string{ "@R15", "M=D", "@R15", "D=M" }, "@R15", "M=D"),
+	deadA,
+	deadD,
+}
+
+// the most instructions any rule looks at
+const window = 8
+
+func isComment(line string) bool {
+	return strings.HasPrefix(strings.TrimSpace(line), "//")
+}
+
+// lines of assembly the peephole pass holds back before handing them on, a
+// run of replacements never reaches further back than this
+const settle = 256
+
+// peephole rewrites assembly a line at a time, passing each line on to emit
+// once it is too far back to be rewritten
+type peephole struct {
+	out  []string
+	emit func(line string)
+}
+
+// Peephole rewrites short runs of instructions in generated assembly.
+// Comments are kept and looked past, labels are never matched so a run
+// never spans a jump target.
+func Peephole(code string) string {
+	var out []string
+	p := peephole{ emit: func(line string) { out = append(out, line) } }
+
+	for _, line := range strings.Split(code, "\n") {
+		p.add(line)
+	}
+	p.flush()
+
+	return strings.Join(out, "\n")
+}
+
+func (p *peephole) add(line string) {
+	p.out = append(p.out, line)
+	if isComment(line) || strings.TrimSpace(line) == "" {
+		return
+	}
+
+	// a replacement can complete another rule, keep going until none apply
+	for applied := true; applied; {
+		applied = false
+
+		// the last few instructions and where they are in out
+		var tail []string
+		var at []int
+		for i := len(p.out) - 1; i >= 0 && len(tail) < window; i-- {
+			if isComment(p.out[i]) || strings.TrimSpace(p.out[i]) == "" {
+				continue
+			}
+			tail = append([]string{ strings.TrimSpace(p.out[i]) }, tail...)
+			at = append([]int{ i }, at...)
+		}
+
+		for _, r := range rules {
+			n, replace := r(tail)
+			if n == 0 {
+				continue
+			}
+
+			// drop the matched instructions, keeping the comments between
+			// them, and write the replacement where the run began
+			first := at[len(at) - n]
+			var kept []string
+			for i := first + 1; i < len(p.out); i++ {
+				if isComment(p.out[i]) || strings.TrimSpace(p.out[i]) == "" {
+					kept = append(kept, p.out[i])
+				}
+			}
+			p.out = append(append(p.out[:first], replace...), kept...)
+			applied = true
+			break
+		}
+	}
+
+	// hand on the lines that have settled
+	if len(p.out) >= 2 * settle {
+		n := len(p.out) - settle
+		for _, line := range p.out[:n] {
+			p.emit(line)
+		}
+		p.out = append(p.out[:0], p.out[n:]...)
+	}
+}
+
+// flush hands on every line still held back
+func (p *peephole) flush() {
+	for _, line := range p.out {
+		p.emit(line)
+	}
+	p.out = nil
+}
+
+// Instructions counts the instructions in assembly code, comments and
+// labels take no space in rom
+func Instructions(code string) int {
+	n := 0
+	for _, line := range strings.Split(code, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || isComment(line) || strings.HasPrefix(line, "(") {
+			continue
+		}
+		n++
+	}
+	return n
+}
+
+// Counter counts the instructions of the assembly code written through it
+// a line at a time, passing the code on to W. A nil W discards the code so
+// a program can be measured without being kept.
+type Counter struct {
+	W io.Writer
+	N int
+	// line holds the start of a line split across writes
+	line []byte
+}
+
+func (c *Counter) Write(p []byte) (int, error) {
+	if c.W != nil {
+		if n, err := c.W.Write(p); err != nil {
+			return n, err
+		}
+	}
+
+	for _, b := range p {
+		if b != '\n' {
+			c.line = append(c.line, b)
+			continue
+		}
+		c.N += Instructions(string(c.line))
+		c.line = c.line[:0]
+	}
+	return len(p), nil
+}
@@ -0,0 +1,179 @@
+package codewriter
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestOptimize(t *testing.T) {
+	pushLoc := PushLocationStatement{}
+	pushLoc.Location = "local"
+	pushLoc.Argument = 0
+
+	pop := PopStatement{}
+	pop.Location = "local"
+	pop.Argument = 1
+
+	input := []Statement{
+		&pushLoc,
+		&pop,
+		&PushConstStatement{ Argument: 1 },
+		&AddStatement{},
+		&NegStatement{},
+		&PushStaticStatement{ File: "Foo", Argument: 2 },
+	}
+
+	actual := Optimize(input)
+
+	expected := []string{
+		"< push local 0; pop local 1 >",
+		"< push constant 1; add >",
+		"< neg >",
+		"< push static 2 >",
+	}
+
+	if len(actual) != len(expected) {
+		t.Fatalf("expected %d statements, got: %d", len(expected), len(actual))
+	}
+	for i := range expected {
+		if command(actual[i]) != strings.Trim(expected[i], "<> ") {
+			t.Errorf("expected: %v, got: %v", expected[i], actual[i])
+		}
+	}
+}
+
+func TestFusedStatements(t *testing.T) {
+	pushLoc := PushLocationStatement{}
+	pushLoc.Location = "local"
+	pushLoc.Argument = 0
+
+	popNear := PopStatement{}
+	popNear.Location = "local"
+	popNear.Argument = 2
+
+	popFar := PopStatement{}
+	popFar.Location = "argument"
+	popFar.Argument = 9
+
+	popTemp := PopStatement{}
+	popTemp.Location = "temp"
+	popTemp.Argument = 3
+
+	tests := []struct {
+		input Statement
+		expected []string
+	}{
+		{
+			&MoveStatement{ Push: &pushLoc, Pop: &popNear },
+			[]string{ "// push local 0", "// pop local 2", "@LCL", "A=M", "D=M", "@LCL", "A=M+1", "A=A+1", "M=D" },
+		},
+		{
+			&MoveStatement{ Push: &PushConstStatement{ Argument: 7 }, Pop: &popFar },
+			[]string{ "// push constant 7", "// pop argument 9", "@ARG", "D=M", "@9", "D=D+A", "@R15", "M=D", "@7", "D=A", "@R15", "A=M", "M=D" },
+		},
+		{
+			&MoveStatement{ Push: &PushStaticStatement{ File: "Foo", Argument: 1 }, Pop: &popTemp },
+			[]string{ "// push static 1", "// pop temp 3", "@Foo.1", "D=M", "@8", "M=D" },
+		},
+		{
+			&OperandStatement{ Push: &PushConstStatement{ Argument: 1 }, Op: &SubStatement{} },
+			[]string{ "// push constant 1", "// sub", "D=1", "@SP", "A=M-1", "M=M-D" },
+		},
+	}
+
+	for _, test := range tests {
+		var cw CodeWriter
+		if err := test.input.Compile(&cw); err != nil {
+			t.Fatalf(err.Error())
+		}
+
+		actual := strings.Split(strings.TrimSpace(cw.String()), "\n")
+		if len(actual) != len(test.expected) {
+			t.Fatalf("%v: expected %d lines, got: %d\n%s", test.input, len(test.expected), len(actual), cw.String())
+		}
+		for i := range test.expected {
+			if actual[i] != test.expected[i] {
+				t.Errorf("%v: expected: %v, got: %v", test.input, test.expected[i], actual[i])
+			}
+		}
+	}
+}
+
+func TestPeephole(t *testing.T) {
+	tests := []struct {
+		input string
+		expected string
+	}{
+		// push then pop
+		{
+			"@SP\nA=M\nM=D\n@SP\nM=M+1\n// pop\n@SP\nAM=M-1\nD=M",
+			"@SP\nA=M\nM=D\n// pop",
+		},
+		// not of a pushed value
+		{
+			"@0\nD=A\n@SP\nA=M\nM=D\n@SP\nM=M+1\n@SP\nA=M-1\nM=!M",
+			"@0\nD=A\n@SP\nA=M\nM=!D\n@SP\nM=M+1",
+		},
+		// dead loads
+		{ "@5\n@6\nD=A", "@6\nD=A" },
+		{ "D=A\nD=M", "D=M" },
+		{ "D=A\nD=D+M", "D=A\nD=D+M" },
+		// labels end a run
+		{ "@5\n(LOOP)\n@6", "@5\n(LOOP)\n@6" },
+		{ "D=A\n(LOOP)\nD=M", "D=A\n(LOOP)\nD=M" },
+	}
+
+	for _, test := range tests {
+		actual := Peephole(test.input)
+		if actual != test.expected {
+			t.Errorf("expected:\n%v\ngot:\n%v", test.expected, actual)
+		}
+	}
+}
+
+func TestInstructions(t *testing.T) {
+	code := "// push constant 1\n@1\nD=A\n(LOOP)\n@LOOP\n0;JMP\n"
+	if n := Instructions(code); n != 4 {
+		t.Errorf("expected: 4, got: %d", n)
+	}
+}
+
+func TestCounter(t *testing.T) {
+	input := []Statement{
+		&PushConstStatement{ Argument: 1 },
+		&PushConstStatement{ Argument: 2 },
+		&AddStatement{},
+	}
+
+	for _, opts := range []WriteOptions{ {}, { Optimize: true }, { Bootstrap: BootstrapSpec } } {
+		code, err := Write(input, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var out strings.Builder
+		counted := Counter{ W: &out }
+		if _, err := WriteTo(&counted, input, nil, opts); err != nil {
+			t.Fatal(err)
+		}
+		if out.String() != code {
+			t.Errorf("%+v: expected the code passed on unchanged", opts)
+		}
+		if counted.N != Instructions(code) {
+			t.Errorf("%+v: expected: %d, got: %d", opts, Instructions(code), counted.N)
+		}
+
+		// lines split across writes are counted once
+		var split Counter
+		for i := 0; i < len(code); i += 3 {
+			end := i + 3
+			if end > len(code) {
+				end = len(code)
+			}
+			split.Write([]byte(code[i:end]))
+		}
+		if split.N != counted.N {
+			t.Errorf("%+v: expected: %d, got: %d", opts, counted.N, split.N)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package codewriter
+
+import (
+	"fmt"
+)
+
+// labels of the shared routines
+const (
+	sharedCall   = "$$call"
+	sharedReturn = "$$return"
+	sharedEq     = "$$compare.eq"
+	sharedGt     = "$$compare.gt"
+	sharedLt     = "$$compare.lt"
+)
+
+// SharedCallStatement is a call made through the shared $$call routine, the
+// use site passes nargs in R13, the function in R14 and the return address
+// in D
+type SharedCallStatement struct {
+	CallStatement
+}
+
+func (s *SharedCallStatement) Compile(cw *CodeWriter) error {
+	returnId := cw.labels.next("ret")
+
+	cw.Writeln("// call %s %d", s.Name, s.Nargs)
+	cw.Writeln("@%d", s.Nargs)
+	cw.Writeln("D=A")
+	cw.Writeln("@R13")
+	cw.Writeln("M=D")
+	cw.Writeln("@%s", s.Name)
+	cw.Writeln("D=A")
+	cw.Writeln("@R14")
+	cw.Writeln("M=D")
+	cw.Writeln("@%s", returnId)
+	cw.Writeln("D=A")
+	cw.Writeln("@%s", sharedCall)
+	cw.Writeln("0;JMP")
+	cw.Writeln("(%s)", returnId)
+
+	return nil
+}
+
+// SharedReturnStatement is a return through the shared $$return routine
+type SharedReturnStatement struct {
+	ReturnStatement
+}
+
+func (s *SharedReturnStatement) Compile(cw *CodeWriter) error {
+	cw.Writeln("// return")
+	cw.Writeln("@%s", sharedReturn)
+	cw.Writeln("0;JMP")
+
+	return nil
+}
+
+// SharedCompareStatement is an eq, gt or lt made through the shared
+// $$compare routine, the use site passes the return address in D
+type SharedCompareStatement struct {
+	Op string
+}
+
+func (s *SharedCompareStatement) String() string { return fmt.Sprintf("< %s >", s.Op) }
+
+func (s *SharedCompareStatement) Compile(cw *CodeWriter) error {
+	returnId := cw.labels.next("cmp")
+
+	cw.Writeln("// %s", s.Op)
+	cw.Writeln("@%s", returnId)
+	cw.Writeln("D=A")
+	cw.Writeln("@$$compare.%s", s.Op)
+	cw.Writeln("0;JMP")
+	cw.Writeln("(%s)", returnId)
+
+	return nil
+}
+
+// Share swaps calls, returns and comparisons for ones made through the
+// shared routines written by writeShared
+func Share(statements []Statement) []Statement {
+	shared := make([]Statement, len(statements))
+
+	for i, statement := range statements {
+		switch s := statement.(type) {
+			case *CallStatement:
+				shared[i] = &SharedCallStatement{ CallStatement: *s }
+			case *ReturnStatement:
+				shared[i] = &SharedReturnStatement{}
+			case *EqStatement:
+				shared[i] = &SharedCompareStatement{ Op: "eq" }
+			case *GtStatement:
+				shared[i] = &SharedCompareStatement{ Op: "gt" }
+			case *LtStatement:
+				shared[i] = &SharedCompareStatement{ Op: "lt" }
+			default:
+				shared[i] = statement
+		}
+	}
+
+	return shared
+}
+
+// writeShared writes the $$call, $$return and $$compare routines after the
+// program, behind a loop that stops a program running off its end into them
+func (cw *CodeWriter) writeShared() error {
+	cw.Writeln("// $$halt")
+	cw.Writeln("($$halt)")
+	cw.Writeln("@$$halt")
+	cw.Writeln("0;JMP")
+
+	cw.Writeln("// $$call: R13 = nargs, R14 = function, D = return addr")
+	cw.Writeln("(%s)", sharedCall)
+
+	// save return addr
+	cw.Writeln("@SP")
+	cw.Writeln("A=M")
+	cw.Writeln("M=D")
+
+	// save lcl, arg, this and that
+	for _, pointer := range []string{ "LCL", "ARG", "THIS", "THAT" } {
+		cw.Writeln("@%s", pointer)
+		cw.Writeln("D=M")
+		cw.Writeln("@SP")
+		cw.Writeln("AM=M+1")
+		cw.Writeln("M=D")
+	}
+	cw.Writeln("@SP")
+	cw.Writeln("MD=M+1")
+
+	// set lcl
+	cw.Writeln("@LCL")
+	cw.Writeln("M=D")
+
+	// set arg
+	cw.Writeln("@R13")
+	cw.Writeln("D=D-M")
+	cw.Writeln("@5")
+	cw.Writeln("D=D-A")
+	cw.Writeln("@ARG")
+	cw.Writeln("M=D")
+
+	// jump to function
+	cw.Writeln("@R14")
+	cw.Writeln("A=M")
+	cw.Writeln("0;JMP")
+
+	cw.Writeln("// $$return")
+	cw.Writeln("(%s)", sharedReturn)
+	var ret ReturnStatement
+	if err := ret.Compile(cw); err != nil {
+		return err
+	}
+
+	cw.Writeln("// $$compare: D = return addr")
+	cw.Writeln("(%s)", sharedEq)
+	cw.Writeln("@R14")
+	cw.Writeln("M=D")
+	cw.Writeln("@SP")
+	cw.Writeln("AM=M-1")
+	cw.Writeln("D=M")
+	cw.Writeln("A=A-1")
+	cw.Writeln("D=M-D")
+	cw.Writeln("@%s.true", sharedEq)
+	cw.Writeln("D;JEQ")
+	cw.Writeln("D=0")
+	cw.Writeln("@%s.end", sharedEq)
+	cw.Writeln("0;JMP")
+	cw.Writeln("(%s.true)", sharedEq)
+	cw.Writeln("D=-1")
+	cw.Writeln("(%s.end)", sharedEq)
+	cw.Writeln("@SP")
+	cw.Writeln("A=M-1")
+	cw.Writeln("M=D")
+	cw.Writeln("@R14")
+	cw.Writeln("A=M")
+	cw.Writeln("0;JMP")
+
+	// gt and lt keep the return addr in R14 as writeOrdered uses R13
+	for _, cmp := range []struct{ label, jump string }{
+		{ sharedGt, "JGT" },
+		{ sharedLt, "JLT" },
+	} {
+		cw.Writeln("(%s)", cmp.label)
+		cw.Writeln("@R14")
+		cw.Writeln("M=D")
+		writeOrdered(cw, cmp.jump, cmp.label)
+		cw.Writeln("@R14")
+		cw.Writeln("A=M")
+		cw.Writeln("0;JMP")
+	}
+
+	return nil
+}
@@ -0,0 +1,100 @@
+package codewriter
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestShare(t *testing.T) {
+	input := []Statement{
+		&CallStatement{ Name: "Main.f", Nargs: 2 },
+		&GtStatement{ },
+		&AddStatement{},
+		&ReturnStatement{},
+	}
+
+	actual := Share(input)
+
+	if _, ok := actual[0].(*SharedCallStatement); !ok {
+		t.Errorf("expected: *SharedCallStatement, got: %T", actual[0])
+	}
+	if s, ok := actual[1].(*SharedCompareStatement); !ok || s.Op != "gt" {
+		t.Errorf("expected: < gt >, got: %v", actual[1])
+	}
+	if actual[2] != input[2] {
+		t.Errorf("expected: %v, got: %v", input[2], actual[2])
+	}
+	if _, ok := actual[3].(*SharedReturnStatement); !ok {
+		t.Errorf("expected: *SharedReturnStatement, got: %T", actual[3])
+	}
+}
+
+func TestSharedCall(t *testing.T) {
+	var cw CodeWriter
+	s := SharedCallStatement{ CallStatement{ Name: "Main.f", Nargs: 2 } }
+	cw.labels.enter("Main.main")
+	if err := s.Compile(&cw); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	expected := []string{
+		"// call Main.f 2",
+		"@2",
+		"D=A",
+		"@R13",
+		"M=D",
+		"@Main.f",
+		"D=A",
+		"@R14",
+		"M=D",
+		"@Main.main$$ret.0",
+		"D=A",
+		"@$$call",
+		"0;JMP",
+		"(Main.main$$ret.0)",
+	}
+
+	actual := strings.Split(strings.TrimSpace(cw.String()), "\n")
+	if len(actual) != len(expected) {
+		t.Fatalf("expected %d lines, got: %d", len(expected), len(actual))
+	}
+	for i := range expected {
+		if actual[i] != expected[i] {
+			t.Errorf("expected: %v, got: %v", expected[i], actual[i])
+		}
+	}
+}
+
+func TestWriteShared(t *testing.T) {
+	input := []Statement{
+		&FunctionStatement{ Name: "Main.f", Nvars: 0 },
+		&PushConstStatement{ Argument: 1 },
+		&PushConstStatement{ Argument: 2 },
+		&LtStatement{ },
+		&ReturnStatement{},
+	}
+
+	code, err := Write(input, WriteOptions{ Shared: true })
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	// each routine is written once, after the program
+	for _, label := range []string{ "($$halt)", "($$call)", "($$return)", "($$compare.eq)", "($$compare.gt)", "($$compare.lt)" } {
+		if n := strings.Count(code, label); n != 1 {
+			t.Errorf("expected %s once, got: %d", label, n)
+		}
+	}
+	if strings.Index(code, "($$halt)") < strings.Index(code, "// return") {
+		t.Errorf("expected the shared routines after the program")
+	}
+
+	// the unshared code is larger
+	plain, err := Write(input, WriteOptions{})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if strings.Contains(plain, "($$halt)") {
+		t.Errorf("expected no shared routines without WriteOptions.Shared")
+	}
+}
@@ -0,0 +1,93 @@
+package codewriter
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Source is a line of a source file
+type Source struct {
+	File string `json:"file"`
+	Line int    `json:"line"`
+}
+
+// Mapping ties the instructions from Address up to the next mapping to the
+// vm line they were translated from and, when known, the jack line that was
+// compiled to it. Generated code such as the bootstrap maps to no source.
+type Mapping struct {
+	Address int     `json:"address"`
+	VM      *Source `json:"vm,omitempty"`
+	Jack    *Source `json:"jack,omitempty"`
+}
+
+// SourceMap maps rom addresses back to the source they came from, the
+// mappings are in address order
+type SourceMap struct {
+	Mappings []Mapping `json:"mappings"`
+}
+
+// LineMap is the map the jack compiler writes beside a vm file, Lines[i] is
+// the jack line vm line i+1 was compiled from
+type LineMap struct {
+	File  string `json:"file"`
+	Lines []int  `json:"lines"`
+}
+
+// Lookup returns the mapping covering a rom address
+func (sm *SourceMap) Lookup(address int) (Mapping, bool) {
+	found := -1
+	for i, m := range sm.Mappings {
+		if m.Address > address {
+			break
+		}
+		found = i
+	}
+	if found < 0 {
+		return Mapping{}, false
+	}
+	return sm.Mappings[found], true
+}
+
+// Link fills in the jack line of every mapping from the vm file
+func (sm *SourceMap) Link(vmFile string, lm *LineMap) {
+	for i, m := range sm.Mappings {
+		if m.VM == nil || m.VM.File != vmFile || m.VM.Line < 1 || m.VM.Line > len(lm.Lines) {
+			continue
+		}
+		if line := lm.Lines[m.VM.Line - 1]; line > 0 {
+			sm.Mappings[i].Jack = &Source{ File: lm.File, Line: line }
+		}
+	}
+}
+
+// markers are comments written before each statement's code while mapping,
+// the peephole pass looks past them like any other comment
+const marker = "//@"
+
+// mark starts the code of the statement at index i, -1 for generated code
+func (cw *CodeWriter) mark(i int) {
+	cw.Writeln("%s%d", marker, i)
+}
+
+// unmark records the mapping for a marker found at address
+func (sm *SourceMap) unmark(line string, address int, sources []Source) {
+	i, _ := strconv.Atoi(line[len(marker):])
+
+	var m Mapping
+	m.Address = address
+	if i >= 0 {
+		source := sources[i]
+		m.VM = &source
+	}
+
+	// statements with no code, labels, give way to the next
+	if n := len(sm.Mappings); n > 0 && sm.Mappings[n - 1].Address == address {
+		sm.Mappings[n - 1] = m
+	} else {
+		sm.Mappings = append(sm.Mappings, m)
+	}
+}
+
+func (s Source) String() string {
+	return fmt.Sprintf("%s:%d", s.File, s.Line)
+}
@@ -0,0 +1,114 @@
+package codewriter
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWriteSourceMap(t *testing.T) {
+	pop := PopStatement{}
+	pop.Location = "local"
+	pop.Argument = 0
+
+	input := []Statement{
+		&FunctionStatement{ Name: "Main.main", Nvars: 1 },
+		&LabelStatement{ Name: "LOOP", Function: "Main.main" },
+		&PushConstStatement{ Argument: 7 },
+		&pop,
+		&GotoStatement{ Name: "LOOP", Function: "Main.main" },
+	}
+	sources := []Source{
+		{ "Main.vm", 1 },
+		{ "Main.vm", 2 },
+		{ "Main.vm", 4 },
+		{ "Main.vm", 5 },
+		{ "Main.vm", 6 },
+	}
+
+	code, sm, err := WriteSourceMap(input, sources, WriteOptions{})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if strings.Contains(code, marker) {
+		t.Errorf("expected no markers left in the code")
+	}
+	plain, _ := Write(input, WriteOptions{})
+	if code != plain {
+		t.Errorf("expected the same code as Write")
+	}
+
+	// function is 9 instructions with one local, the label has no code and
+	// gives way to the push
+	expected := []struct{ address, line int }{
+		{ 0, 1 },
+		{ 9, 4 },
+		{ 16, 5 },
+		{ 28, 6 },
+	}
+
+	if len(sm.Mappings) != 4 {
+		t.Fatalf("expected 4 mappings, got: %v", sm.Mappings)
+	}
+	for _, e := range expected {
+		m, ok := sm.Lookup(e.address)
+		if !ok || m.VM == nil || m.VM.Line != e.line {
+			t.Errorf("address %d: expected Main.vm:%d, got: %+v", e.address, e.line, m)
+		}
+	}
+
+	sm.Link("Main.vm", &LineMap{ File: "Main.jack", Lines: []int{ 3, 3, 0, 5, 5, 7 } })
+	if m, _ := sm.Lookup(10); m.Jack == nil || *m.Jack != (Source{ "Main.jack", 5 }) {
+		t.Errorf("expected Main.jack:5, got: %+v", m.Jack)
+	}
+}
+
+func TestWriteSourceMapOptimized(t *testing.T) {
+	input := []Statement{
+		&FunctionStatement{ Name: "Main.main", Nvars: 0 },
+		&PushConstStatement{ Argument: 1 },
+		&PushConstStatement{ Argument: 2 },
+		&AddStatement{},
+		&CallStatement{ Name: "Main.main", Nargs: 1 },
+		&ReturnStatement{},
+	}
+	var sources []Source
+	for i := range input {
+		sources = append(sources, Source{ "Main.vm", i + 1 })
+	}
+
+	code, sm, err := WriteSourceMap(input, sources, WriteOptions{ Bootstrap: BootstrapSpec, Optimize: true, Shared: true })
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	// the bootstrap and shared routines map to nothing, the fused push and
+	// add map to the push
+	if sm.Mappings[0].Address != 0 || sm.Mappings[0].VM != nil {
+		t.Errorf("expected the bootstrap to map to no source, got: %+v", sm.Mappings[0])
+	}
+	last := sm.Mappings[len(sm.Mappings) - 1]
+	if last.VM != nil {
+		t.Errorf("expected the shared routines to map to no source, got: %+v", last)
+	}
+
+	var lines []int
+	for _, m := range sm.Mappings {
+		if m.VM != nil {
+			lines = append(lines, m.VM.Line)
+		}
+		if m.Address > Instructions(code) {
+			t.Errorf("address %d past the end of the code", m.Address)
+		}
+	}
+
+	expected := []int{ 1, 2, 3, 5, 6 }
+	if len(lines) != len(expected) {
+		t.Fatalf("expected lines %v, got: %v", expected, lines)
+	}
+	for i := range expected {
+		if lines[i] != expected[i] {
+			t.Errorf("expected line %d, got: %d", expected[i], lines[i])
+		}
+	}
+}
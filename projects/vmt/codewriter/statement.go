@@ -1,32 +1,33 @@
 package codewriter
 
 import (
+	"errors"
 	"fmt"
 )
 
 type Location string	
 
-func (l *Location) Address() string {
+func (l *Location) Address() (string, error) {
 	switch string(*l) {
 	case "local":
-		return "@LCL"
+		return "@LCL", nil
 	case "argument":
-		return "@ARG"
+		return "@ARG", nil
 	case "this":
-		return "@THIS"
+		return "@THIS", nil
 	case "that":
-		return "@THAT"
+		return "@THAT", nil
 	case "pointer":
-		return "@THIS"
+		return "@THIS", nil
 	case "temp":
-		return "@5"
+		return "@5", nil
 	default:
-		panic(fmt.Sprintf("invalid location: %s", *l))
+		return "", errors.New(fmt.Sprintf("invalid location: %s", *l))
 	}	
 }
 
 type Statement interface {
-	Compile(*CodeWriter)
+	Compile(*CodeWriter) error
 }
 
 
@@ -35,7 +36,7 @@ type LocationStatement struct {
 	Argument int
 }
 
-func (s *LocationStatement) Address() string {
+func (s *LocationStatement) Address() (string, error) {
 	return s.Location.Address()
 }
 
@@ -48,7 +49,7 @@ func (s *PushConstStatement) String() string {
 	return fmt.Sprintf("< push constant %d >", s.Argument)
 }
 
-func (s *PushConstStatement) Compile(cw *CodeWriter) {
+func (s *PushConstStatement) Compile(cw *CodeWriter) error {
 	cw.Writeln("// push constant %d", s.Argument)
 	cw.Writeln("@%d", s.Argument)
 	cw.Writeln("D=A")
@@ -57,6 +58,8 @@ func (s *PushConstStatement) Compile(cw *CodeWriter) {
 	cw.Writeln("M=D")
 	cw.Writeln("@SP")
 	cw.Writeln("M=M+1")
+
+	return nil
 }
 
 type PushStaticStatement struct {
@@ -68,7 +71,7 @@ func (s *PushStaticStatement) String() string {
 	return fmt.Sprintf("< push static %d >", s.Argument)
 }
 
-func (s *PushStaticStatement) Compile(cw *CodeWriter) {
+func (s *PushStaticStatement) Compile(cw *CodeWriter) error {
 	cw.Writeln("// push static %d", s.Argument)
 	cw.Writeln("@%s.%d", s.File, s.Argument)
 	cw.Writeln("D=M")
@@ -77,6 +80,8 @@ func (s *PushStaticStatement) Compile(cw *CodeWriter) {
 	cw.Writeln("M=D")
 	cw.Writeln("@SP")
 	cw.Writeln("M=M+1")
+
+	return nil
 }
 
 func getBaseLocation(location Location) string {
@@ -107,16 +112,21 @@ func (s *PushLocationStatement) String() string {
 	return fmt.Sprintf("< push %v %d >", s.Location, s.Argument)
 }
 
-func (s *PushLocationStatement) Compile(cw *CodeWriter) {
+func (s *PushLocationStatement) Compile(cw *CodeWriter) error {
+	address, err := s.Address()
+	if err != nil {
+		return err
+	}
+
 	cw.Writeln("// push %v %v", s.Location, s.Argument)
-	cw.Writeln(s.Address())
+	cw.Writeln(address)
 
+	base := getBaseLocation(s.Location)
 	if s.Argument > 0 {
-		base := getBaseLocation(s.Location)
 		cw.Writeln("D=%s", base)
 		cw.Writeln("@%d", s.Argument)
 		cw.Writeln("A=D+A")
-	} else {
+	} else if base == "M" {
 		cw.Writeln("A=M")
 	}
 
@@ -126,6 +136,8 @@ func (s *PushLocationStatement) Compile(cw *CodeWriter) {
 	cw.Writeln("M=D")
 	cw.Writeln("@SP")
 	cw.Writeln("M=M+1")
+
+	return nil
 }
 
 
@@ -137,13 +149,17 @@ func (s *PopStatement) String() string {
 	return fmt.Sprintf("< pop %v %d >", s.Location, s.Argument)
 }
 
-func (s *PopStatement) Compile(cw *CodeWriter) {
+func (s *PopStatement) Compile(cw *CodeWriter) error {
 	base := getBaseLocation(s.Location)
+	address, err := s.Address()
+	if err != nil {
+		return err
+	}
 
 	cw.Writeln("// pop %v %v", s.Location, s.Argument)
 
 	// set A to location + arg
-	cw.Writeln(s.Address())
+	cw.Writeln(address)
 	cw.Writeln("D=%s", base)
 	cw.Writeln("@%d", s.Argument)
 	cw.Writeln("D=D+A")
@@ -161,6 +177,8 @@ func (s *PopStatement) Compile(cw *CodeWriter) {
 	cw.Writeln("@R15")
 	cw.Writeln("A=M")
 	cw.Writeln("M=D")
+
+	return nil
 }
 
 type PopStaticStatement struct {
@@ -172,7 +190,7 @@ func (s *PopStaticStatement) String() string {
 	return fmt.Sprintf("< pop static %d>", s.Argument)
 }
 
-func (s *PopStaticStatement) Compile(cw *CodeWriter) {
+func (s *PopStaticStatement) Compile(cw *CodeWriter) error {
 	cw.Writeln("// pop static %v", s.Argument)
 
 	// set A to location + arg
@@ -192,44 +210,52 @@ func (s *PopStaticStatement) Compile(cw *CodeWriter) {
 	cw.Writeln("@R15")
 	cw.Writeln("A=M")
 	cw.Writeln("M=D")
+
+	return nil
 }
 
 type AddStatement struct { }
 func (s *AddStatement) String() string { return "< add >"}
-func (s *AddStatement) Compile(cw *CodeWriter) {
+func (s *AddStatement) Compile(cw *CodeWriter) error {
 	cw.Writeln("// add")
 	cw.Writeln("@SP")
 	cw.Writeln("AM=M-1")
 	cw.Writeln("D=M")
 	cw.Writeln("A=A-1")
 	cw.Writeln("M=D+M")
+
+	return nil
 }
 
 type SubStatement struct { }
 func (s *SubStatement) String() string { return "< sub >"}
-func (s *SubStatement) Compile(cw *CodeWriter) {
+func (s *SubStatement) Compile(cw *CodeWriter) error {
 	cw.Writeln("// sub")
 	cw.Writeln("@SP")
 	cw.Writeln("AM=M-1")
 	cw.Writeln("D=M")
 	cw.Writeln("A=A-1")
 	cw.Writeln("M=M-D")
+
+	return nil
 }
 
 type NegStatement struct { }
 func (s *NegStatement) String() string { return "< neg >"}
-func (s *NegStatement) Compile(cw *CodeWriter) {
+func (s *NegStatement) Compile(cw *CodeWriter) error {
 	cw.Writeln("// neg")
 	cw.Writeln("@SP")
 	cw.Writeln("A=M-1")
 	cw.Writeln("M=-M")
-}
 
-type EqStatement struct {
-	Id int
+	return nil
 }
-func (s *EqStatement) String() string { return fmt.Sprintf("< Eq %d >", s.Id) }
-func (s *EqStatement) Compile(cw *CodeWriter) {
+
+type EqStatement struct { }
+func (s *EqStatement) String() string { return "< eq >" }
+func (s *EqStatement) Compile(cw *CodeWriter) error {
+	label := cw.labels.next("eq")
+
 	cw.Writeln("// eq")
 
 		// load top of stack into D
@@ -242,80 +268,100 @@ func (s *EqStatement) Compile(cw *CodeWriter) {
 	cw.Writeln("D=M-D")
 
 		// in not greater
-	cw.Writeln("@is-eq-%d", s.Id)
+	cw.Writeln("@%s.true", label)
 	cw.Writeln("D;JEQ")
-	cw.Writeln("@end-%d", s.Id)
+	cw.Writeln("@%s.end", label)
 	cw.Writeln("D=0;JMP")
 
 		// is greater
-	cw.Writeln("(is-eq-%d)", s.Id)
+	cw.Writeln("(%s.true)", label)
 	cw.Writeln("D=-1")
 
-	cw.Writeln("(end-%d)", s.Id)
+	cw.Writeln("(%s.end)", label)
 	cw.Writeln("@SP")
 	cw.Writeln("A=M-1")
 	cw.Writeln("M=D")
-}
 
-type GtStatement struct {
-	Id int
+	return nil
 }
-func (s *GtStatement) String() string { return fmt.Sprintf("< gt %d >", s.Id)}
-func (s *GtStatement) Compile(cw *CodeWriter) {
+
+type GtStatement struct { }
+func (s *GtStatement) String() string { return "< gt >" }
+func (s *GtStatement) Compile(cw *CodeWriter) error {
 	cw.Writeln("// gt")
+	writeOrdered(cw, "JGT", cw.labels.next("gt"))
 
-		// load top of stack into D
-	cw.Writeln("@SP")
-	cw.Writeln("AM=M-1")
-	cw.Writeln("D=M")
+	return nil
+}
 
-		// check
-	cw.Writeln("A=A-1")
-	cw.Writeln("D=M-D")
+type LtStatement struct { }
+func (s *LtStatement) String() string { return "< lt >" }
+func (s *LtStatement) Compile(cw *CodeWriter) error {
+	cw.Writeln("// lt")
+	writeOrdered(cw, "JLT", cw.labels.next("lt"))
 
-		// in not greater
-	cw.Writeln("@is-gt-%d", s.Id)
-	cw.Writeln("D;JGT")
-	cw.Writeln("@end-%d", s.Id)
-	cw.Writeln("D=0;JMP")
+	return nil
+}
 
-		// is greater
-	cw.Writeln("(is-gt-%d)", s.Id)
-	cw.Writeln("D=-1")
+// writeOrdered pops y and replaces x with x > y for JGT or x < y for JLT.
+// x - y overflows when x and y have different signs, so the signs are
+// checked first and only values with the same sign are subtracted. The
+// labels used are name followed by .x_neg, .same_sign, .true and .end
+func writeOrdered(cw *CodeWriter, jump, name string) {
+	// when the signs differ the positive value is the greater
+	xPositive, xNegative := name + ".true", name + ".false"
+	if jump == "JLT" {
+		xPositive, xNegative = xNegative, xPositive
+	}
 
-	cw.Writeln("(end-%d)", s.Id)
+		// pop y into R13
 	cw.Writeln("@SP")
-	cw.Writeln("A=M-1")
+	cw.Writeln("AM=M-1")
+	cw.Writeln("D=M")
+	cw.Writeln("@R13")
 	cw.Writeln("M=D")
-}
-
-type LtStatement struct {
-	Id int
-}
-func (s *LtStatement) String() string { return fmt.Sprintf("< lt %d >", s.Id)}
-func (s *LtStatement) Compile(cw *CodeWriter) {
-	cw.Writeln("// lt")
 
-		// load top of stack into D
+		// load x
 	cw.Writeln("@SP")
-	cw.Writeln("AM=M-1")
+	cw.Writeln("A=M-1")
 	cw.Writeln("D=M")
+	cw.Writeln("@%s.x_neg", name)
+	cw.Writeln("D;JLT")
 
-		// check
-	cw.Writeln("A=A-1")
-	cw.Writeln("D=M-D")
+		// x >= 0, y < 0
+	cw.Writeln("@R13")
+	cw.Writeln("D=M")
+	cw.Writeln("@%s.same_sign", name)
+	cw.Writeln("D;JGE")
+	cw.Writeln("@%s", xPositive)
+	cw.Writeln("0;JMP")
 
-		// in not greater
-	cw.Writeln("@is-lt-%v", s.Id)
+		// x < 0, y >= 0
+	cw.Writeln("(%s.x_neg)", name)
+	cw.Writeln("@R13")
+	cw.Writeln("D=M")
+	cw.Writeln("@%s.same_sign", name)
 	cw.Writeln("D;JLT")
-	cw.Writeln("@end-%v", s.Id)
-	cw.Writeln("D=0;JMP")
+	cw.Writeln("@%s", xNegative)
+	cw.Writeln("0;JMP")
 
-		// is greater
-	cw.Writeln("(is-lt-%v)", s.Id)
+		// the same sign, x - y can not overflow
+	cw.Writeln("(%s.same_sign)", name)
+	cw.Writeln("@SP")
+	cw.Writeln("A=M-1")
+	cw.Writeln("D=M-D")
+	cw.Writeln("@%s.true", name)
+	cw.Writeln("D;%s", jump)
+
+	cw.Writeln("(%s.false)", name)
+	cw.Writeln("D=0")
+	cw.Writeln("@%s.end", name)
+	cw.Writeln("0;JMP")
+
+	cw.Writeln("(%s.true)", name)
 	cw.Writeln("D=-1")
 
-	cw.Writeln("(end-%v)", s.Id)
+	cw.Writeln("(%s.end)", name)
 	cw.Writeln("@SP")
 	cw.Writeln("A=M-1")
 	cw.Writeln("M=D")
@@ -323,54 +369,73 @@ func (s *LtStatement) Compile(cw *CodeWriter) {
 
 type AndStatement struct { }
 func (s *AndStatement) String() string { return "< and >"}
-func (s *AndStatement) Compile(cw *CodeWriter) {
+func (s *AndStatement) Compile(cw *CodeWriter) error {
 	cw.Writeln("// and")
 	cw.Writeln("@SP")
 	cw.Writeln("AM=M-1")
 	cw.Writeln("D=M")
 	cw.Writeln("A=A-1")
 	cw.Writeln("M=D&M")
+
+	return nil
 }
 
 type OrStatement struct { }
 func (s *OrStatement) String() string { return "< or >"}
-func (s *OrStatement) Compile(cw *CodeWriter) {
+func (s *OrStatement) Compile(cw *CodeWriter) error {
 	cw.Writeln("// or")
 	cw.Writeln("@SP")
 	cw.Writeln("AM=M-1")
 	cw.Writeln("D=M")
 	cw.Writeln("A=A-1")
 	cw.Writeln("M=D|M")
+
+	return nil
 }
 
 type NotStatement struct { }
 func (s *NotStatement) String() string { return "< not >"}
-func (s *NotStatement) Compile(cw *CodeWriter) {
+func (s *NotStatement) Compile(cw *CodeWriter) error {
 	cw.Writeln("// not")
 	cw.Writeln("@SP")
 	cw.Writeln("A=M-1")
 	cw.Writeln("M=!M")
+
+	return nil
 }
 
 type LabelStatement struct { Name string; Function string }
 func (s *LabelStatement) String() string { return fmt.Sprintf("< label %s >", s.Name)}
-func (s *LabelStatement) Compile(cw *CodeWriter) {
-	cw.Writeln("// label %s.%s", s.Function, s.Name)
-	cw.Writeln("(%s.%s)", s.Function, s.Name)
+func (s *LabelStatement) Compile(cw *CodeWriter) error {
+	label, err := cw.labels.define(s.Function, s.Name)
+	if err != nil {
+		return err
+	}
+
+	cw.Writeln("// label %s", label)
+	cw.Writeln("(%s)", label)
+
+	return nil
 }
 
 type GotoStatement struct { Name string; Function string }
 func (s *GotoStatement) String() string { return fmt.Sprintf("< goto %s >", s.Name)}
-func (s *GotoStatement) Compile(cw *CodeWriter) {
-	cw.Writeln("// goto %s.%s", s.Function, s.Name)
-	cw.Writeln("@%s.%s", s.Function, s.Name)
+func (s *GotoStatement) Compile(cw *CodeWriter) error {
+	label := userLabel(s.Function, s.Name)
+
+	cw.Writeln("// goto %s", label)
+	cw.Writeln("@%s", label)
 	cw.Writeln("0;JMP")
-} 
 
-type IfGotoStatement struct { Name string; Id int; Function string }
-func (s *IfGotoStatement) String() string { return fmt.Sprintf("< if-goto %s>", s.Name)}
-func (s *IfGotoStatement) Compile(cw *CodeWriter) {
-	cw.Writeln("// if-goto %s.%s", s.Function, s.Name)
+	return nil
+}
+
+type IfGotoStatement struct { Name string; Function string }
+func (s *IfGotoStatement) String() string { return fmt.Sprintf("< if-goto %s >", s.Name)}
+func (s *IfGotoStatement) Compile(cw *CodeWriter) error {
+	label, skip := userLabel(s.Function, s.Name), cw.labels.next("if")
+
+	cw.Writeln("// if-goto %s", label)
 
 	// pop top of stack
 	cw.Writeln("@SP")
@@ -378,16 +443,20 @@ func (s *IfGotoStatement) Compile(cw *CodeWriter) {
 	cw.Writeln("D=M")
 
 	// check eq 0
-	cw.Writeln("@%s.%s$%d", s.Function, s.Name, s.Id)
+	cw.Writeln("@%s", skip)
 	cw.Writeln("D;JEQ")
-	cw.Writeln("@%s.%s", s.Function, s.Name)
+	cw.Writeln("@%s", label)
 	cw.Writeln("0;JMP")
-	cw.Writeln("(%s.%s$%d)", s.Function, s.Name, s.Id)
+	cw.Writeln("(%s)", skip)
+
+	return nil
 }
 
 type FunctionStatement struct { Name string; Nvars int }
 func (s *FunctionStatement) String() string { return fmt.Sprintf("< function %s %d >", s.Name, s.Nvars)}
-func (s *FunctionStatement) Compile(cw *CodeWriter) {
+func (s *FunctionStatement) Compile(cw *CodeWriter) error {
+	cw.labels.enter(s.Name)
+
 	cw.Writeln("// function %s %d", s.Name, s.Nvars)
 	cw.Writeln("(%s)", s.Name)
 
@@ -407,33 +476,21 @@ func (s *FunctionStatement) Compile(cw *CodeWriter) {
 	cw.Writeln("D=A")
 	cw.Writeln("@SP")
 	cw.Writeln("M=D")
+
+	return nil
 }
 
 type CallStatement struct {
 	Name string
 	Nargs int
-	Id int
 }
 func (s *CallStatement) String() string { return fmt.Sprintf("< call %s %d >", s.Name, s.Nargs) }
-func (s *CallStatement) Compile(cw *CodeWriter) {
-	returnId := fmt.Sprintf("%s$ret.%d", s.Name, s.Id)
+func (s *CallStatement) Compile(cw *CodeWriter) error {
+	returnId := cw.labels.next("ret")
 	nargs := s.Nargs
 
 	cw.Writeln("// call %s %d", s.Name, s.Nargs)
 
-	// if narg == 0, push 0 onto the stack
-	// and set narg to 1
-	if nargs < 1 {
-		cw.Writeln("@0")
-		cw.Writeln("D=A")
-		cw.Writeln("@SP")
-		cw.Writeln("A=M")
-		cw.Writeln("M=D")
-		cw.Writeln("@SP")
-		cw.Writeln("M=M+1")
-		nargs = 1
-	}
-
 	// save return addr
 	cw.Writeln("@%s", returnId)
 	cw.Writeln("D=A")
@@ -494,14 +551,26 @@ func (s *CallStatement) Compile(cw *CodeWriter) {
 
 	// return addr
 	cw.Writeln("(%s)", returnId)
+
+	return nil
 }
 
 
 type ReturnStatement struct { }
 func (s *ReturnStatement) String() string { return "< return >" }
-func (s *ReturnStatement) Compile(cw *CodeWriter) {
+func (s *ReturnStatement) Compile(cw *CodeWriter) error {
 	cw.Writeln("// return")
 
+	// save the return addr at LCL-5 first, with no args
+	// arg[0] is the same slot and is overwritten below
+	cw.Writeln("@LCL")
+	cw.Writeln("D=M")
+	cw.Writeln("@5")
+	cw.Writeln("A=D-A")
+	cw.Writeln("D=M")
+	cw.Writeln("@R15")
+	cw.Writeln("M=D")
+
 	// set arg[0] to return val
 	cw.Writeln("@SP")
 	cw.Writeln("A=M-1")
@@ -550,18 +619,16 @@ func (s *ReturnStatement) Compile(cw *CodeWriter) {
 	cw.Writeln("@LCL")
 	cw.Writeln("M=D")
 
-	// reset addr and restore SP
-	cw.Writeln("@SP")
-	cw.Writeln("AM=M-1")
-	cw.Writeln("D=M")
-	cw.Writeln("@R15")
-	cw.Writeln("M=D")
-
+	// restore SP and jump to the return addr
 	cw.Writeln("@R14")
 	cw.Writeln("D=M")
 	cw.Writeln("@SP")
 	cw.Writeln("M=D")
 
+	// A=M;JMP would jump to the old A, R15
 	cw.Writeln("@R15")
-	cw.Writeln("A=M;JMP")
+	cw.Writeln("A=M")
+	cw.Writeln("0;JMP")
+
+	return nil
 }
\ No newline at end of file
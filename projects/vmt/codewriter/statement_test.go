@@ -66,6 +66,39 @@ func TestPushLocationStatement(t *testing.T) {
 	}
 }
 
+func TestPushLocationZero(t *testing.T) {
+	tests := []struct {
+		location Location
+		expected []string
+	}{
+		// a virtual segment is pushed from its own address
+		{ "temp", []string{ "// push temp 0", "@5", "D=M" } },
+		{ "pointer", []string{ "// push pointer 0", "@THIS", "D=M" } },
+		// other segments from the address their pointer holds
+		{ "local", []string{ "// push local 0", "@LCL", "A=M", "D=M" } },
+	}
+
+	for _, tt := range tests {
+		s := PushLocationStatement{}
+		s.Location = tt.location
+		var cw CodeWriter
+		s.Compile(&cw)
+		actual := strings.Split(strings.TrimSpace(cw.String()), "\n")
+		expected := append(tt.expected, "@SP", "A=M", "M=D", "@SP", "M=M+1")
+
+		if len(actual) != len(expected){
+			t.Errorf("%s : line count mismatch, expected: %v, got: %v", tt.location, len(expected), len(actual))
+			continue
+		}
+
+		for i := range actual {
+			if actual[i] != expected[i] {
+				t.Errorf("%s : expected: %v, got: %v", tt.location, expected[i], actual[i])
+			}
+		}
+	}
+}
+
 func TestPopStatement(t *testing.T) {
 	s := PopStatement{ }
 	s.Location = "local"
@@ -178,8 +211,9 @@ func TestNegStatement(t *testing.T) {
 }
 
 func TestEqStatement(t *testing.T) {
-	s := EqStatement{ Id: 1234 }
+	s := EqStatement{ }
 	var cw CodeWriter
+	cw.labels.enter("Foo.bar")
 	s.Compile(&cw)
 	actual := strings.Split(strings.TrimSpace(cw.String()), "\n")
 	expected := []string{
@@ -189,13 +223,13 @@ func TestEqStatement(t *testing.T) {
 		"D=M",
 		"A=A-1",
 		"D=M-D",
-		"@is-eq-1234",
+		"@Foo.bar$$eq.0.true",
 		"D;JEQ",
-		"@end-1234",
+		"@Foo.bar$$eq.0.end",
 		"D=0;JMP",
-		"(is-eq-1234)",
+		"(Foo.bar$$eq.0.true)",
 		"D=-1",
-		"(end-1234)",
+		"(Foo.bar$$eq.0.end)",
 		"@SP",
 		"A=M-1",
 		"M=D",
@@ -214,8 +248,9 @@ func TestEqStatement(t *testing.T) {
 }
 
 func TestGtStatement(t *testing.T) {
-	s := GtStatement{ Id: 1234 }
+	s := GtStatement{ }
 	var cw CodeWriter
+	cw.labels.enter("Foo.bar")
 	s.Compile(&cw)
 	actual := strings.Split(strings.TrimSpace(cw.String()), "\n")
 	expected := []string{
@@ -223,15 +258,39 @@ func TestGtStatement(t *testing.T) {
 		"@SP",
 		"AM=M-1",
 		"D=M",
-		"A=A-1",
+		"@R13",
+		"M=D",
+		"@SP",
+		"A=M-1",
+		"D=M",
+		"@Foo.bar$$gt.0.x_neg",
+		"D;JLT",
+		"@R13",
+		"D=M",
+		"@Foo.bar$$gt.0.same_sign",
+		"D;JGE",
+		"@Foo.bar$$gt.0.true",
+		"0;JMP",
+		"(Foo.bar$$gt.0.x_neg)",
+		"@R13",
+		"D=M",
+		"@Foo.bar$$gt.0.same_sign",
+		"D;JLT",
+		"@Foo.bar$$gt.0.false",
+		"0;JMP",
+		"(Foo.bar$$gt.0.same_sign)",
+		"@SP",
+		"A=M-1",
 		"D=M-D",
-		"@is-gt-1234",
+		"@Foo.bar$$gt.0.true",
 		"D;JGT",
-		"@end-1234",
-		"D=0;JMP",
-		"(is-gt-1234)",
+		"(Foo.bar$$gt.0.false)",
+		"D=0",
+		"@Foo.bar$$gt.0.end",
+		"0;JMP",
+		"(Foo.bar$$gt.0.true)",
 		"D=-1",
-		"(end-1234)",
+		"(Foo.bar$$gt.0.end)",
 		"@SP",
 		"A=M-1",
 		"M=D",
@@ -250,8 +309,9 @@ func TestGtStatement(t *testing.T) {
 }
 
 func TestLtStatement(t *testing.T) {
-	s := LtStatement{ Id: 1234 }
+	s := LtStatement{ }
 	var cw CodeWriter
+	cw.labels.enter("Foo.bar")
 	s.Compile(&cw)
 	actual := strings.Split(strings.TrimSpace(cw.String()), "\n")
 	expected := []string{
@@ -259,15 +319,39 @@ func TestLtStatement(t *testing.T) {
 		"@SP",
 		"AM=M-1",
 		"D=M",
-		"A=A-1",
+		"@R13",
+		"M=D",
+		"@SP",
+		"A=M-1",
+		"D=M",
+		"@Foo.bar$$lt.0.x_neg",
+		"D;JLT",
+		"@R13",
+		"D=M",
+		"@Foo.bar$$lt.0.same_sign",
+		"D;JGE",
+		"@Foo.bar$$lt.0.false",
+		"0;JMP",
+		"(Foo.bar$$lt.0.x_neg)",
+		"@R13",
+		"D=M",
+		"@Foo.bar$$lt.0.same_sign",
+		"D;JLT",
+		"@Foo.bar$$lt.0.true",
+		"0;JMP",
+		"(Foo.bar$$lt.0.same_sign)",
+		"@SP",
+		"A=M-1",
 		"D=M-D",
-		"@is-lt-1234",
+		"@Foo.bar$$lt.0.true",
 		"D;JLT",
-		"@end-1234",
-		"D=0;JMP",
-		"(is-lt-1234)",
+		"(Foo.bar$$lt.0.false)",
+		"D=0",
+		"@Foo.bar$$lt.0.end",
+		"0;JMP",
+		"(Foo.bar$$lt.0.true)",
 		"D=-1",
-		"(end-1234)",
+		"(Foo.bar$$lt.0.end)",
 		"@SP",
 		"A=M-1",
 		"M=D",
@@ -367,8 +451,8 @@ func TestLabelStatement(t *testing.T) {
 	s.Compile(&cw)
 	actual := strings.Split(strings.TrimSpace(cw.String()), "\n")
 	expected := []string{
-		"// label f.test",
-		"(f.test)",
+		"// label f$test",
+		"(f$test)",
 	}
 
 	if len(actual) != len(expected){
@@ -389,8 +473,8 @@ func TestGotoStatement(t *testing.T) {
 	s.Compile(&cw)
 	actual := strings.Split(strings.TrimSpace(cw.String()), "\n")
 	expected := []string{
-		"// goto f.test",
-		"@f.test",
+		"// goto f$test",
+		"@f$test",
 		"0;JMP",
 	}
 
@@ -407,20 +491,21 @@ func TestGotoStatement(t *testing.T) {
 }
 
 func TestIfGotoStatement(t *testing.T) {
-	s := IfGotoStatement{ Name: "test", Id: 2, Function: "f" }
+	s := IfGotoStatement{ Name: "test", Function: "f" }
 	var cw CodeWriter
+	cw.labels.enter("f")
 	s.Compile(&cw)
 	actual := strings.Split(strings.TrimSpace(cw.String()), "\n")
 	expected := []string{
-		"// if-goto f.test",
+		"// if-goto f$test",
 		"@SP",
 		"AM=M-1",
 		"D=M",
-		"@f.test$2",
+		"@f$$if.0",
 		"D;JEQ",
-		"@f.test",
+		"@f$test",
 		"0;JMP",
-		"(f.test$2)",
+		"(f$$if.0)",
 	}
 
 	if len(actual) != len(expected){
@@ -466,4 +551,89 @@ func TestFunctionStatement(t *testing.T) {
 			t.Errorf("expected: %v, got: %v", expected[i], actual[i])
 		}
 	}
-}
\ No newline at end of file
+}
+
+func TestReturnStatement(t *testing.T) {
+	s := ReturnStatement{ }
+	var cw CodeWriter
+	s.Compile(&cw)
+	actual := strings.Split(strings.TrimSpace(cw.String()), "\n")
+	expected := []string{
+		"// return",
+		// the return address is saved before the return value is written,
+		// with no arguments arg[0] is its slot
+		"@LCL",
+		"D=M",
+		"@5",
+		"A=D-A",
+		"D=M",
+		"@R15",
+		"M=D",
+		"@SP",
+		"A=M-1",
+		"D=M",
+		"@ARG",
+		"A=M",
+		"M=D",
+		"@LCL",
+		"D=M",
+		"@SP",
+		"M=D",
+		"@ARG",
+		"D=M+1",
+		"@R14",
+		"M=D",
+		"@SP",
+		"AM=M-1",
+		"D=M",
+		"@THAT",
+		"M=D",
+		"@SP",
+		"AM=M-1",
+		"D=M",
+		"@THIS",
+		"M=D",
+		"@SP",
+		"AM=M-1",
+		"D=M",
+		"@ARG",
+		"M=D",
+		"@SP",
+		"AM=M-1",
+		"D=M",
+		"@LCL",
+		"M=D",
+		"@R14",
+		"D=M",
+		"@SP",
+		"M=D",
+		"@R15",
+		"A=M",
+		"0;JMP",
+	}
+
+	if len(actual) != len(expected){
+		t.Errorf("line count mismatch, expected: %v, got: %v", len(expected), len(actual))
+		t.FailNow()
+	}
+
+	for i := range actual {
+		if actual[i] != expected[i] {
+			t.Errorf("expected: %v, got: %v", expected[i], actual[i])
+		}
+	}
+}
+
+func TestInvalidLocation(t *testing.T) {
+	s := PushLocationStatement{}
+	s.Location = "nowhere"
+	var cw CodeWriter
+
+	if err := s.Compile(&cw); err == nil {
+		t.Errorf("expected error compiling: %v", s.String())
+	}
+
+	if _, err := Write([]Statement{&s}, WriteOptions{}); err == nil {
+		t.Errorf("expected error writing: %v", s.String())
+	}
+}
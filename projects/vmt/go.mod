@@ -1,3 +1,15 @@
 module vmt
 
 go 1.17
+
+require (
+	hackemu v0.0.0
+	hasm v0.0.0
+	jack v0.0.0
+)
+
+replace (
+	hackemu => ../hackemu
+	hasm => ../hasm
+	jack => ../jack
+)
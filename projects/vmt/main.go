@@ -1,42 +1,87 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"io/fs"
 	"io/ioutil"
 	"log"
 	"os"
 	"path/filepath"
+	"vmt/check"
 	"vmt/codewriter"
 	"vmt/parser"
 )
 
+var bootstrap = flag.String("bootstrap", "auto", "bootstrap code: auto, none or spec, auto bootstraps directories with a Sys.vm")
+var optimize = flag.Bool("optimize", false, "fuse statements and run the peephole optimizer over the asm")
+var shared = flag.Bool("shared", false, "jump to shared call, return and comparison routines rather than inlining them")
+var eliminate = flag.Bool("eliminate", false, "drop functions that can not be reached from the entry point")
+var sourceMap = flag.Bool("sourcemap", false, "also write a <name>.asm.map json file mapping rom addresses to vm and jack lines")
+var entry = flag.String("entry", "", "bootstrap by calling this function instead of Sys.init")
+var checkFirst = flag.Bool("check", false, "check the program as vmt check does before translating it, a single file of a larger program will not pass")
+
 func main(){
+	flag.Parse()
 
 	// check args
-	if len(os.Args) != 2 {
+	args := flag.Args()
+	checkOnly := len(args) > 0 && args[0] == "check"
+	if checkOnly {
+		args = args[1:]
+	}
+
+	if len(args) != 1 {
 		fmt.Println("Error: No file name provided")
-		fmt.Println("useage: vmt <path>")
-		return
+		fmt.Println("useage: vmt [-bootstrap auto|none|spec] [-entry function] [-optimize] [-shared] [-eliminate] [-sourcemap] [-check] <path>")
+		fmt.Println("       vmt check <path>")
+		fmt.Println("       a path of - reads vm code from stdin and writes the asm to stdout")
+		os.Exit(2)
 	}
 
-	path := os.Args[1]
+	path := args[0]
+
+	if checkOnly {
+
+		if err := checkPath(path); err != nil {
+			fmt.Println(err.Error())
+			os.Exit(1)
+		}
+		fmt.Println("No problems found")
+
+	} else if path == "-" {
+
+		if err := translateStdin(); err != nil {
+			fmt.Fprintln(os.Stderr, "Error: translating stdin")
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
 
-	if isFile(path){
+	} else if isFile(path){
 		if !checkExt(path) {
 			fmt.Printf("Invalid file type, expected: '.vm', got: '%v'\n", filepath.Ext(path))
-			return
+			os.Exit(2)
 		}
 		
-		translateFile(path)
+		if err := translateFile(path); err != nil {
+			fmt.Println("Error: translating file")
+			fmt.Println(err.Error())
+			os.Exit(1)
+		}
 
 	} else if isDir(path) {
 
-		translateDir(path)
+		if err := translateDir(path); err != nil {
+			fmt.Println("Error: translating directory")
+			fmt.Println(err.Error())
+			os.Exit(1)
+		}
 
 	} else {	
 		fmt.Printf("Error: could not find file: %v\n", path)
-		return
+		os.Exit(2)
 	}
 }
 
@@ -88,24 +133,43 @@ func isDir(filename string) bool {
 	return info.IsDir()
 }
 
-func translateFile(path string){
-	var parser parser.Parser
+// writeOptions picks the bootstrap from the flags, auto bootstraps a
+// directory that has a Sys.vm and leaves single files without one
+func writeOptions(path string) (codewriter.WriteOptions, error) {
+	if *entry != "" {
+		return codewriter.WriteOptions{ Bootstrap: codewriter.BootstrapEntry, Entry: *entry, Optimize: *optimize, Shared: *shared }, nil
+	}
+
+	switch *bootstrap {
+		case "none":
+			return codewriter.WriteOptions{ Bootstrap: codewriter.BootstrapNone, Optimize: *optimize, Shared: *shared }, nil
+		case "spec":
+			return codewriter.WriteOptions{ Bootstrap: codewriter.BootstrapSpec, Optimize: *optimize, Shared: *shared }, nil
+		case "auto":
+			if isDir(path) && isFile(filepath.Join(path, "Sys.vm")) {
+				return codewriter.WriteOptions{ Bootstrap: codewriter.BootstrapSpec, Optimize: *optimize, Shared: *shared }, nil
+			}
+			return codewriter.WriteOptions{ Bootstrap: codewriter.BootstrapNone, Optimize: *optimize, Shared: *shared }, nil
+	}
+
+	return codewriter.WriteOptions{}, errors.New(fmt.Sprintf("invalid bootstrap: %s, expected: auto, none or spec", *bootstrap))
+}
+
+func translateFile(path string) error {
+	var p parser.Parser
 	fileOutPath := replaceExt(path, ".asm")
 
 	// translate code
-	data := readFile(path)
-
-	parser.Parse(data, "")
-	code := codewriter.Write(parser.Statements)
+	if err := parseFile(&p, path); err != nil {
+		return err
+	}
 
-	writeFile(fileOutPath, code)
-	fmt.Println("Success!!")
-	fmt.Printf("output file: %v\n", fileOutPath)
+	return write(&p, []string{ path }, path, fileOutPath)
 }
 
-func translateDir(dir string) {
-	var parser parser.Parser
-	fileOutPath := replaceExt(dir, fmt.Sprintf("%s.asm", filepath.Base(dir)))
+func translateDir(dir string) error {
+	var p parser.Parser
+	fileOutPath := filepath.Join(dir, fmt.Sprintf("%s.asm", filepath.Base(dir)))
 
 	// get .vm files
 	files, err := filepath.Glob(filepath.Join(dir, "*.vm"))
@@ -113,32 +177,215 @@ func translateDir(dir string) {
 		log.Fatal(err)
 	}
 
-	// move sys.vm to start of slice
-	// found := false
-	// for i, file := range files {
-	// 	if strings.ToLower(filepath.Base(file)) == "sys.vm" {
-	// 		found = true
-	// 		temp := files[0]
-	// 		files[0] = files[i]
-	// 		files[i] = temp
-	// 		break
-	// 	}
-	// }
+	// translate code, carrying on past bad files so every bad line is reported
+	var errs parser.ErrorList
 
-	// if !found {
-	// 	panic("sys.vm not found")
-	// }
+	for _, file := range files {
+		if err := parseFile(&p, file); err != nil {
+			list, ok := err.(parser.ErrorList)
+			if !ok {
+				return err
+			}
+			errs = append(errs, list...)
+		}
+	}
 
-	// translate code
+	if len(errs) > 0 {
+		return errs
+	}
 
+	return write(&p, files, dir, fileOutPath)
+}
+
+// translateStdin translates vm code piped in, statics are named after the
+// class of the function they are in as there is no file name
+func translateStdin() error {
+	var p parser.Parser
+
+	if *sourceMap {
+		return errors.New("-sourcemap needs an output file")
+	}
+	if err := p.ParseReader(os.Stdin, ""); err != nil {
+		return err
+	}
+
+	return write(&p, nil, "-", "-")
+}
+
+// checkPath parses the vm code at path and runs the checker over it
+// without translating it
+func checkPath(path string) error {
+	var p parser.Parser
+
+	var files []string
+	if path == "-" {
+		if err := p.ParseReader(os.Stdin, ""); err != nil {
+			return err
+		}
+	} else if isFile(path) {
+		if !checkExt(path) {
+			return errors.New(fmt.Sprintf("Invalid file type, expected: '.vm', got: '%v'", filepath.Ext(path)))
+		}
+		files = []string{ path }
+	} else if isDir(path) {
+		var err error
+		files, err = filepath.Glob(filepath.Join(path, "*.vm"))
+		if err != nil {
+			return err
+		}
+	} else {
+		return errors.New(fmt.Sprintf("Error: could not find file: %v", path))
+	}
+
+	var errs parser.ErrorList
 	for _, file := range files {
-		data := readFile(file)
-		parser.Parse(data, removeExt(filepath.Base(file)))
+		if err := parseFile(&p, file); err != nil {
+			list, ok := err.(parser.ErrorList)
+			if !ok {
+				return err
+			}
+			errs = append(errs, list...)
+		}
+	}
+	if len(errs) > 0 {
+		return errs
 	}
 
-	code := codewriter.Write(parser.Statements)
-	writeFile(fileOutPath, code)
+	return check.Check(p.Statements, p.Sources, checkOptions())
+}
+
+// checkOptions lets programs call the jack os without defining it
+func checkOptions() check.Options {
+	return check.Options{ External: check.OS }
+}
 
-	fmt.Println("Success!!")
-	fmt.Printf("output file: %v\n", fileOutPath)
-}
\ No newline at end of file
+// parseFile parses a vm file a line at a time
+func parseFile(p *parser.Parser, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return p.ParseReader(f, removeExt(filepath.Base(path)))
+}
+
+// write translates the statements parsed from the vm files at path and
+// writes the asm file, or stdout for -, and its source map when asked for
+// one, reporting the functions eliminated and how much smaller the code got
+func write(p *parser.Parser, files []string, path, fileOutPath string) error {
+	opts, err := writeOptions(path)
+	if err != nil {
+		return err
+	}
+
+	if *checkFirst {
+		if err := check.Check(p.Statements, p.Sources, checkOptions()); err != nil {
+			return err
+		}
+	}
+
+	statements, sources := p.Statements, p.Sources
+	all := statements
+	var removed []string
+
+	if *eliminate {
+		entry := opts.Entry
+		switch opts.Bootstrap {
+			case codewriter.BootstrapNone:
+				return errors.New("-eliminate needs an entry point, use -bootstrap spec or -entry")
+			case codewriter.BootstrapSpec:
+				entry = codewriter.SysInit
+		}
+		var keep []bool
+		keep, removed = codewriter.Reachable(statements, entry)
+		statements, sources = nil, nil
+		for i, k := range keep {
+			if k {
+				statements = append(statements, p.Statements[i])
+				sources = append(sources, p.Sources[i])
+			}
+		}
+	}
+
+	if !*sourceMap {
+		sources = nil
+	}
+
+	// reports go to stderr when the asm is going to stdout
+	report := os.Stdout
+	out := os.Stdout
+	if fileOutPath == "-" {
+		report = os.Stderr
+	} else {
+		out, err = os.Create(fileOutPath)
+		if err != nil {
+			return err
+		}
+		defer out.Close()
+	}
+
+	counter := codewriter.Counter{ W: out }
+	sm, err := codewriter.WriteTo(&counter, statements, sources, opts)
+	if err != nil {
+		return err
+	}
+
+	if *sourceMap {
+		if err := writeSourceMap(sm, files, fileOutPath + ".map"); err != nil {
+			return err
+		}
+	}
+
+	if fileOutPath != "-" {
+		fmt.Fprintln(report, "Success!!")
+		fmt.Fprintf(report, "output file: %v\n", fileOutPath)
+		if *sourceMap {
+			fmt.Fprintf(report, "source map: %v\n", fileOutPath + ".map")
+		}
+	}
+
+	if *eliminate {
+		fmt.Fprintf(report, "removed %d unused function(s)\n", len(removed))
+		for _, name := range removed {
+			fmt.Fprintf(report, "  %s\n", name)
+		}
+	}
+
+	if *eliminate || opts.Optimize || opts.Shared {
+		// count the plain translation without keeping it
+		var plain codewriter.Counter
+		opts.Optimize, opts.Shared = false, false
+		if _, err := codewriter.WriteTo(&plain, all, nil, opts); err != nil {
+			return err
+		}
+
+		before, after := plain.N, counter.N
+		fmt.Fprintf(report, "instructions: %d before, %d after (%d saved)\n", before, after, before - after)
+	}
+
+	return nil
+}
+
+// writeSourceMap links in the jack line maps the compiler wrote beside any
+// of the vm files and writes the source map as json
+func writeSourceMap(sm *codewriter.SourceMap, files []string, path string) error {
+	for _, file := range files {
+		if !isFile(file + ".map") {
+			continue
+		}
+
+		var lm codewriter.LineMap
+		if err := json.Unmarshal([]byte(readFile(file + ".map")), &lm); err != nil {
+			return errors.New(fmt.Sprintf("%s.map: %s", file, err.Error()))
+		}
+		sm.Link(filepath.Base(file), &lm)
+	}
+
+	data, err := json.MarshalIndent(sm, "", "  ")
+	if err != nil {
+		return err
+	}
+	writeFile(path, string(data))
+	return nil
+}
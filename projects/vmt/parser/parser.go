@@ -1,149 +1,330 @@
 package parser
 
 import (
+	"bufio"
+	"errors"
+	"io"
 	"fmt"
+	"regexp"
 	"strconv"
 	"strings"
 	cw "vmt/codewriter"
 )
 
 type Parser struct {
-	id int
 	Statements []cw.Statement
+	// Sources holds the file and line each statement was parsed from
+	Sources []cw.Source
 	Function string
 }
 
-func (p *Parser) Parse(bytecode string, file string) {
-	for _, line := range strings.Split(bytecode, "\n") {
-		stmt, ok := p.parseLine(line, p.id, file)
-		p.id++
+// Error is a line that could not be parsed
+type Error struct {
+	File string
+	Line int
+	Text string
+	Msg  string
+}
+
+func (e *Error) Error() string {
+	if e.File == "" {
+		return fmt.Sprintf("line %d: %s: %s", e.Line, e.Msg, e.Text)
+	}
+	return fmt.Sprintf("%s.vm:%d: %s: %s", e.File, e.Line, e.Msg, e.Text)
+}
+
+// ErrorList holds every bad line of a parse
+type ErrorList []*Error
+
+func (el ErrorList) Error() string {
+	msgs := make([]string, len(el))
+	for i, err := range el {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
 
-		if !ok {
-			panic(fmt.Sprintf("error parsing line: %s", line))
+// Parse parses every line of a vm file, file is the name statics are
+// prefixed with. Bad lines are skipped and returned together as an ErrorList.
+func (p *Parser) Parse(bytecode string, file string) error {
+	return p.ParseReader(strings.NewReader(bytecode), file)
+}
+
+// ParseReader parses a vm file a line at a time like Parse. With no file
+// name, as when reading stdin, statics are prefixed with the class of the
+// function they are in.
+func (p *Parser) ParseReader(r io.Reader, file string) error {
+	var errs ErrorList
+
+	scanner := bufio.NewScanner(r)
+	for i := 0; scanner.Scan(); i++ {
+		line := scanner.Text()
+
+		prefix := file
+		if prefix == "" {
+			prefix = p.class()
+		}
+
+		stmt, err := p.parseLine(line, prefix)
+
+		if err != nil {
+			errs = append(errs, &Error{
+				File: file,
+				Line: i + 1,
+				Text: strings.TrimSpace(line),
+				Msg: err.Error(),
+			})
+			continue
 		}
 
 		if stmt != nil {
-			p.Statements = append(p.Statements, stmt)		
+			p.Statements = append(p.Statements, stmt)
+			p.Sources = append(p.Sources, cw.Source{ File: sourceFile(file), Line: i + 1 })
 		}
 	}
+
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// class is the class of the function being parsed
+func (p *Parser) class() string {
+	if i := strings.Index(p.Function, "."); i >= 0 {
+		return p.Function[:i]
+	}
+	return p.Function
+}
+
+func sourceFile(file string) string {
+	if file == "" {
+		return "-"
+	}
+	return file + ".vm"
 }
 
-func (p *Parser) parseLine(bytecode string, n int, file string) (cw.Statement, bool) {
+// the largest value push constant can load
+const maxConstant = 32767
+
+// segment sizes, 0 for segments without a fixed size
+var segments = map[string]int{
+	"constant": 0,
+	"static": 0,
+	"local": 0,
+	"argument": 0,
+	"this": 0,
+	"that": 0,
+	"pointer": 2,
+	"temp": 8,
+}
+
+// the number of operands each command other than push and pop takes
+var operands = map[string]int{
+	"add": 0, "sub": 0, "neg": 0, "eq": 0, "gt": 0, "lt": 0, "and": 0, "or": 0, "not": 0,
+	"label": 1, "goto": 1, "if-goto": 1,
+	"function": 2, "call": 2, "return": 0,
+}
+
+// expectArgs checks the number of operands following the command
+func expectArgs(words []string, n int) error {
+	if len(words) - 1 < n {
+		return errors.New(fmt.Sprintf("%s expects %d operand(s), got %d", words[0], n, len(words) - 1))
+	}
+	if len(words) - 1 > n {
+		return errors.New(fmt.Sprintf("unexpected operand after %s: %s", words[0], words[n + 1]))
+	}
+	return nil
+}
+
+func parseIndex(s string) (int, error) {
+	arg, err := strconv.Atoi(s)
+	if err != nil || arg < 0 {
+		return 0, errors.New(fmt.Sprintf("invalid index: %s", s))
+	}
+	return arg, nil
+}
+
+// parseSegment parses the segment and index operands of push and pop
+func parseSegment(words []string) (string, int, error) {
+	if err := expectArgs(words, 2); err != nil {
+		return "", 0, err
+	}
+
+	segment := words[1]
+	size, ok := segments[segment]
+	if !ok {
+		return "", 0, errors.New(fmt.Sprintf("unknown segment: %s", segment))
+	}
+
+	arg, err := parseIndex(words[2])
+	if err != nil {
+		return "", 0, err
+	}
+
+	if size > 0 && arg >= size {
+		return "", 0, errors.New(fmt.Sprintf("%s index out of range 0..%d: %d", segment, size - 1, arg))
+	}
+	if segment == "constant" && arg > maxConstant {
+		return "", 0, errors.New(fmt.Sprintf("constant out of range 0..%d: %d", maxConstant, arg))
+	}
+
+	return segment, arg, nil
+}
+
+// labelRe is the vm symbol grammar label, goto and if-goto names must follow,
+// the same the assembler accepts less the $
+var labelRe = regexp.MustCompile(`^[A-Za-z_.:][A-Za-z0-9_.:]*$`)
+
+// parseLabel checks the name given to label, goto and if-goto. $ is left out
+// of the label alphabet, the code writer names these <function>$<label> and
+// the labels it generates <function>$$<kind>.<n>, so a $ here could take the
+// name of a generated label.
+func parseLabel(name string) (string, error) {
+	if strings.Contains(name, "$") {
+		return "", errors.New(fmt.Sprintf("invalid label, $ is reserved: %s", name))
+	}
+	if !labelRe.MatchString(name) {
+		return "", errors.New(fmt.Sprintf("invalid label: %s", name))
+	}
+	return name, nil
+}
+
+func (p *Parser) parseLine(bytecode string, file string) (cw.Statement, error) {
 	code := strings.Split(bytecode, "//")[0]
 	code = strings.TrimSpace(code)
 	if len(code) == 0 {
-		return nil, true
+		return nil, nil
 	}
 
 	words := strings.Fields(code)
 
 	switch words[0] {
 		case "push":
-			arg, err := strconv.Atoi(words[2])
-			if err != nil { panic(err) }
-			switch words[1] {
+			segment, arg, err := parseSegment(words)
+			if err != nil { return nil, err }
+			switch segment {
 			case "constant":
 				statement := &cw.PushConstStatement{
 					Argument: arg,
 				}
-				return statement, true
+				return statement, nil
 			case "static":
 				statement := &cw.PushStaticStatement{
 					File: file,
 					Argument: arg,
 				}
-				return statement, true
+				return statement, nil
 			default:
 				statement := &cw.PushLocationStatement{}
-				statement.Location = cw.Location(words[1])
+				statement.Location = cw.Location(segment)
 				statement.Argument = arg
-				return statement, true
+				return statement, nil
 			}
 
 		case "pop":
-			arg, err := strconv.Atoi(words[2])
-			if err != nil { panic(err) }
+			segment, arg, err := parseSegment(words)
+			if err != nil { return nil, err }
 
-			switch words[1] {
+			switch segment {
+			case "constant":
+				return nil, errors.New("cannot pop to constant")
 			case "static":
 				statement := &cw.PopStaticStatement{
 					File: file,
 					Argument: arg,
 				}
-				return statement, true
+				return statement, nil
 			default:
 				statement := &cw.PopStatement{}
-				statement.Location = cw.Location(words[1])
+				statement.Location = cw.Location(segment)
 				statement.Argument = arg
-				return statement, true
+				return statement, nil
 			}
+	}
+
+	// the remaining commands take a fixed number of operands
+	nargs, ok := operands[words[0]]
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("unknown command: %s", words[0]))
+	}
+	if err := expectArgs(words, nargs); err != nil {
+		return nil, err
+	}
+
+	switch words[0] {
 		case "add":
 			statement := &cw.AddStatement{}
-			return statement, true
+			return statement, nil
 
 		case "sub":
 			statement := &cw.SubStatement{}
-			return statement, true
+			return statement, nil
 
 		case "neg":
 			statement := &cw.NegStatement{}
-			return statement, true
-			
+			return statement, nil
+
 		case "eq":
-			statement := &cw.EqStatement{ Id: n }
-			return statement, true
-			
+			statement := &cw.EqStatement{}
+			return statement, nil
+
 		case "gt":
-			statement := &cw.GtStatement{ Id: n }
-			return statement, true
+			statement := &cw.GtStatement{}
+			return statement, nil
 
 		case "lt":
-			statement := &cw.LtStatement{ Id: n }
-			return statement, true
+			statement := &cw.LtStatement{}
+			return statement, nil
 
 		case "and":
 			statement := &cw.AndStatement{}
-			return statement, true
+			return statement, nil
 
 		case "or":
 			statement := &cw.OrStatement{}
-			return statement, true
+			return statement, nil
 
 		case "not":
 			statement := &cw.NotStatement{}
-			return statement, true
+			return statement, nil
 
 		case "label":
-			statement := &cw.LabelStatement{ Name: words[1], Function: p.Function }
-			return statement, true
+			name, err := parseLabel(words[1])
+			if err != nil { return nil, err }
+			statement := &cw.LabelStatement{ Name: name, Function: p.Function }
+			return statement, nil
 
 		case "goto":
-			statement := &cw.GotoStatement{ Name: words[1], Function: p.Function }
-			return statement, true
+			name, err := parseLabel(words[1])
+			if err != nil { return nil, err }
+			statement := &cw.GotoStatement{ Name: name, Function: p.Function }
+			return statement, nil
 
 		case "if-goto":
-			statement := &cw.IfGotoStatement{ Name: words[1], Id: n, Function: p.Function}
-			return statement, true
+			name, err := parseLabel(words[1])
+			if err != nil { return nil, err }
+			statement := &cw.IfGotoStatement{ Name: name, Function: p.Function }
+			return statement, nil
 
 		case "function":
-			arg, err := strconv.Atoi(words[2])
-			if err != nil { panic(err) }
+			arg, err := parseIndex(words[2])
+			if err != nil { return nil, err }
 			statement := &cw.FunctionStatement{ Name: words[1], Nvars: arg }
 			p.Function = words[1]
-			return statement, true
+			return statement, nil
 
 		case "call":
-			arg, err := strconv.Atoi(words[2])
-			if err != nil { panic(err) }
-			statement := &cw.CallStatement{ Name: words[1], Nargs: arg, Id: n }
-			return statement, true
+			arg, err := parseIndex(words[2])
+			if err != nil { return nil, err }
+			statement := &cw.CallStatement{ Name: words[1], Nargs: arg }
+			return statement, nil
 
-		case "return":
+		default: // return
 			statement := &cw.ReturnStatement{}
-			return statement, true
-		
-		default: 
-			return nil, false
-
+			return statement, nil
 	}
-}
\ No newline at end of file
+}
@@ -1,6 +1,7 @@
 package parser
 
 import (
+	"strings"
 	"testing"
 	cw "vmt/codewriter"
 )
@@ -15,9 +16,9 @@ func expectEq(t *testing.T, a, b interface{}){
 func TestParseLine_ExtraSpaces(t *testing.T){
 	line := " push  local  3 "
 	var p Parser
-	s, parsed := p.parseLine(line, 1, "")
+	s, err := p.parseLine(line, "")
 
-	if !parsed {
+	if err != nil {
 		t.Errorf("failed to parse line: %s", line)
 		t.FailNow()
 	}
@@ -30,9 +31,9 @@ func TestParseLine_ExtraSpaces(t *testing.T){
 func TestParseLine_Comments(t *testing.T){
 	line := "push local 3 // hello world"
 	var p Parser
-	s, parsed := p.parseLine(line, 1, "")
+	s, err := p.parseLine(line, "")
 
-	if !parsed {
+	if err != nil {
 		t.Errorf("failed to parse line: %s", line)
 		t.FailNow()
 	}
@@ -45,9 +46,9 @@ func TestParseLine_Comments(t *testing.T){
 func TestParseLine_Fail(t *testing.T){
 	line := "pish local 3"
 	var p Parser
-	_, parsed := p.parseLine(line, 1, "")
+	_, err := p.parseLine(line, "")
 
-	if parsed {
+	if err == nil {
 		t.Errorf("failed to parse line: %s", line)
 		t.FailNow()
 	}
@@ -56,9 +57,9 @@ func TestParseLine_Fail(t *testing.T){
 func TestParseLine_Push(t *testing.T){
 	line := "push local 3"
 	var p Parser
-	s, parsed := p.parseLine(line, 1, "")
+	s, err := p.parseLine(line, "")
 
-	if !parsed {
+	if err != nil {
 		t.Errorf("failed to parse line: %s", line)
 		t.FailNow()
 	}
@@ -74,9 +75,9 @@ func TestParseLine_Push(t *testing.T){
 func TestParseLine_PushConst(t *testing.T){
 	line := "push constant 3"
 	var p Parser
-	s, parsed := p.parseLine(line, 1, "")
+	s, err := p.parseLine(line, "")
 
-	if !parsed {
+	if err != nil {
 		t.Errorf("failed to parse line: %s", line)
 		t.FailNow()
 	}
@@ -91,9 +92,9 @@ func TestParseLine_PushConst(t *testing.T){
 func TestParseLine_Pop(t *testing.T){
 	line := "pop local 3"
 	var p Parser
-	s, parsed := p.parseLine(line, 1, "")
+	s, err := p.parseLine(line, "")
 
-	if !parsed {
+	if err != nil {
 		t.Errorf("failed to parse line: %s", line)
 		t.FailNow()
 	}
@@ -109,9 +110,9 @@ func TestParseLine_Pop(t *testing.T){
 func TestParseLine_Add(t *testing.T){
 	line := "add"
 	var p Parser
-	s, parsed := p.parseLine(line, 1, "")
+	s, err := p.parseLine(line, "")
 
-	if !parsed {
+	if err != nil {
 		t.Errorf("failed to parse line: %s", line)
 		t.FailNow()
 	}
@@ -124,9 +125,9 @@ func TestParseLine_Add(t *testing.T){
 func TestParseLine_Sub(t *testing.T){
 	line := "sub"
 	var p Parser
-	s, parsed := p.parseLine(line, 1, "")
+	s, err := p.parseLine(line, "")
 
-	if !parsed {
+	if err != nil {
 		t.Errorf("failed to parse line: %s", line)
 		t.FailNow()
 	}
@@ -139,9 +140,9 @@ func TestParseLine_Sub(t *testing.T){
 func TestParseLine_Neg(t *testing.T){
 	line := "neg"
 	var p Parser
-	s, parsed := p.parseLine(line, 1, "")
+	s, err := p.parseLine(line, "")
 
-	if !parsed {
+	if err != nil {
 		t.Errorf("failed to parse line: %s", line)
 		t.FailNow()
 	}
@@ -154,60 +155,54 @@ func TestParseLine_Neg(t *testing.T){
 func TestParseLine_Eq(t *testing.T){
 	line := "eq"
 	var p Parser
-	s, parsed := p.parseLine(line, 1, "")
+	s, err := p.parseLine(line, "")
 
-	if !parsed {
+	if err != nil {
 		t.Errorf("failed to parse line: %s", line)
 		t.FailNow()
 	}
 
-	if stmt, ok := s.(*cw.EqStatement); ok {
-		expectEq(t, stmt.Id, 1)
-	} else {
-		t.Errorf("expected: EqStatement, got: %T", stmt)
+	if _, ok := s.(*cw.EqStatement); !ok {
+		t.Errorf("expected: EqStatement, got: %T", s)
 	}
 }
 
 func TestParseLine_Gt(t *testing.T){
 	line := "gt"
 	var p Parser
-	s, parsed := p.parseLine(line, 1, "")
+	s, err := p.parseLine(line, "")
 
-	if !parsed {
+	if err != nil {
 		t.Errorf("failed to parse line: %s", line)
 		t.FailNow()
 	}
 
-	if stmt, ok := s.(*cw.GtStatement); ok {
-		expectEq(t, stmt.Id, 1)
-	} else {
-		t.Errorf("expected: GtStatement, got: %T", stmt)
+	if _, ok := s.(*cw.GtStatement); !ok {
+		t.Errorf("expected: GtStatement, got: %T", s)
 	}
 }
 
 func TestParseLine_Lt(t *testing.T){
 	line := "lt"
 	var p Parser
-	s, parsed := p.parseLine(line, 1, "")
+	s, err := p.parseLine(line, "")
 
-	if !parsed {
+	if err != nil {
 		t.Errorf("failed to parse line: %s", line)
 		t.FailNow()
 	}
 
-	if stmt, ok := s.(*cw.LtStatement); ok {
-		expectEq(t, stmt.Id, 1)
-	} else {
-		t.Errorf("expected: LtStatement, got: %T", stmt)
+	if _, ok := s.(*cw.LtStatement); !ok {
+		t.Errorf("expected: LtStatement, got: %T", s)
 	}
 }
 
 func TestParseLine_And(t *testing.T){
 	line := "and"
 	var p Parser
-	s, parsed := p.parseLine(line, 1, "")
+	s, err := p.parseLine(line, "")
 
-	if !parsed {
+	if err != nil {
 		t.Errorf("failed to parse line: %s", line)
 		t.FailNow()
 	}
@@ -220,9 +215,9 @@ func TestParseLine_And(t *testing.T){
 func TestParseLine_Or(t *testing.T){
 	line := "or"
 	var p Parser
-	s, parsed := p.parseLine(line, 1, "")
+	s, err := p.parseLine(line, "")
 
-	if !parsed {
+	if err != nil {
 		t.Errorf("failed to parse line: %s", line)
 		t.FailNow()
 	}
@@ -235,9 +230,9 @@ func TestParseLine_Or(t *testing.T){
 func TestParseLine_Not(t *testing.T){
 	line := "not"
 	var p Parser
-	s, parsed := p.parseLine(line, 1, "")
+	s, err := p.parseLine(line, "")
 
-	if !parsed {
+	if err != nil {
 		t.Errorf("failed to parse line: %s", line)
 		t.FailNow()
 	}
@@ -248,53 +243,160 @@ func TestParseLine_Not(t *testing.T){
 }
 
 func TestParseLine_Label(t *testing.T){
-	line := "label hello-world"
+	line := "label hello_world"
 	var p Parser
-	s, parsed := p.parseLine(line, 1, "")
+	s, err := p.parseLine(line, "")
 
-	if !parsed {
+	if err != nil {
 		t.Errorf("failed to parse line: %s", line)
 		t.FailNow()
 	}
 
 	if stmt, ok := s.(*cw.LabelStatement); ok {
-		expectEq(t, stmt.Name, "hello-world")
+		expectEq(t, stmt.Name, "hello_world")
 	} else {
 		t.Errorf("expected: LabelStatement, got: %T", stmt)
 	}
 }
 
 func TestParseLine_Goto(t *testing.T){
-	line := "goto hello-world"
+	line := "goto hello_world"
 	var p Parser
-	s, parsed := p.parseLine(line, 1, "")
+	s, err := p.parseLine(line, "")
 
-	if !parsed {
+	if err != nil {
 		t.Errorf("failed to parse line: %s", line)
 		t.FailNow()
 	}
 
 	if stmt, ok := s.(*cw.GotoStatement); ok {
-		expectEq(t, stmt.Name, "hello-world")
+		expectEq(t, stmt.Name, "hello_world")
 	} else {
 		t.Errorf("expected: GotoStatement, got: %T", stmt)
 	}
 }
 
 func TestParseLine_IfGoto(t *testing.T){
-	line := "if-goto hello-world"
+	line := "if-goto hello_world"
 	var p Parser
-	s, parsed := p.parseLine(line, 1, "")
+	s, err := p.parseLine(line, "")
 
-	if !parsed {
+	if err != nil {
 		t.Errorf("failed to parse line: %s", line)
 		t.FailNow()
 	}
 
 	if stmt, ok := s.(*cw.IfGotoStatement); ok {
-		expectEq(t, stmt.Name, "hello-world")
-		expectEq(t, stmt.Id, 1)
+		expectEq(t, stmt.Name, "hello_world")
 	} else {
 		t.Errorf("expected: IfGotoStatement, got: %T", stmt)
 	}
-}
\ No newline at end of file
+}
+
+func TestParseLine_ReservedLabel(t *testing.T){
+	// $ would let a label take the name of a generated one, $eq.0 in Foo.bar
+	// is Foo.bar$$eq.0
+	tests := []string{
+		"label $eq.0",
+		"goto $eq.0",
+		"if-goto $ret.1",
+	}
+
+	for _, line := range tests {
+		var p Parser
+		_, err := p.parseLine(line, "")
+		if err == nil {
+			t.Errorf("expected error parsing: %s", line)
+			continue
+		}
+		expectEq(t, err.Error(), "invalid label, $ is reserved: " + strings.Fields(line)[1])
+	}
+}
+
+func TestParse_LabelErrors(t *testing.T){
+	// names the assembler would reject are reported at their line
+	input := `function Foo.bar 0
+label hello-world
+goto a@b
+if-goto 1st
+label ok_1.a:b
+goto ok_1.a:b`
+
+	var p Parser
+	err := p.Parse(input, "Foo")
+
+	errs, ok := err.(ErrorList)
+	if !ok {
+		t.Errorf("expected: ErrorList, got: %T", err)
+		t.FailNow()
+	}
+
+	expected := []string{
+		"Foo.vm:2: invalid label: hello-world: label hello-world",
+		"Foo.vm:3: invalid label: a@b: goto a@b",
+		"Foo.vm:4: invalid label: 1st: if-goto 1st",
+	}
+	expectEq(t, len(errs), len(expected))
+	for i, e := range errs {
+		expectEq(t, e.Error(), expected[i])
+	}
+}
+
+func TestParse_Errors(t *testing.T){
+	input := `push constant 1
+pish local 3
+push local
+push local x
+push nowhere 1
+pop constant 2
+push temp 8
+push pointer 2
+push constant 32768
+add 1
+label
+call Foo.bar
+function Foo.bar -1
+add`
+
+	var p Parser
+	err := p.Parse(input, "Foo")
+
+	errs, ok := err.(ErrorList)
+	if !ok {
+		t.Errorf("expected: ErrorList, got: %T", err)
+		t.FailNow()
+	}
+
+	expectEq(t, len(errs), 12)
+	for i, e := range errs {
+		expectEq(t, e.File, "Foo")
+		expectEq(t, e.Line, i + 2)
+	}
+
+	expectEq(t, errs[0].Text, "pish local 3")
+	expectEq(t, errs[0].Error(), "Foo.vm:2: unknown command: pish: pish local 3")
+
+	// good lines are still parsed
+	expectEq(t, len(p.Statements), 2)
+	expectEq(t, len(p.Sources), 2)
+	expectEq(t, p.Sources[1], cw.Source{ File: "Foo.vm", Line: 14 })
+}
+
+func TestParseReader_Stdin(t *testing.T){
+	input := `push static 0
+function Foo.bar 0
+push static 1
+function Baz.qux 0
+pop static 2`
+
+	var p Parser
+	if err := p.ParseReader(strings.NewReader(input), ""); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	// with no file name statics take the class of their function
+	expectEq(t, p.Statements[0].(*cw.PushStaticStatement).File, "")
+	expectEq(t, p.Statements[2].(*cw.PushStaticStatement).File, "Foo")
+	expectEq(t, p.Statements[4].(*cw.PopStaticStatement).File, "Baz")
+	expectEq(t, p.Sources[4], cw.Source{ File: "-", Line: 5 })
+}
@@ -0,0 +1,51 @@
+// Package translator is the vm translator as a library, it parses vm code
+// into statements and translates statements into hack assembly.
+package translator
+
+import (
+	"io"
+	"vmt/codewriter"
+	"vmt/parser"
+)
+
+// Statement is a parsed vm command
+type Statement = codewriter.Statement
+
+// Options control the assembly Translate writes
+type Options struct {
+	codewriter.WriteOptions
+
+	// SingleFile translates the statements as one standalone file the way
+	// the project 7 translator did, with no bootstrap whatever the
+	// WriteOptions ask for
+	SingleFile bool
+}
+
+// Parse reads a vm file, file is its name without the .vm extension and
+// prefixes its statics. Every bad line is returned in a parser.ErrorList.
+func Parse(r io.Reader, file string) ([]Statement, error) {
+	var p parser.Parser
+	if err := p.ParseReader(r, file); err != nil {
+		return nil, err
+	}
+	return p.Statements, nil
+}
+
+// Translate builds the assembly code for the statements of one or more
+// parsed files
+func Translate(statements []Statement, opts Options) (string, error) {
+	if opts.SingleFile {
+		opts.Bootstrap = codewriter.BootstrapNone
+	}
+	return codewriter.Write(statements, opts.WriteOptions)
+}
+
+// TranslateTo writes the assembly code for the statements to w as it is
+// translated
+func TranslateTo(w io.Writer, statements []Statement, opts Options) error {
+	if opts.SingleFile {
+		opts.Bootstrap = codewriter.BootstrapNone
+	}
+	_, err := codewriter.WriteTo(w, statements, nil, opts.WriteOptions)
+	return err
+}
@@ -0,0 +1,77 @@
+package translator
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"vmt/codewriter"
+	"vmt/parser"
+)
+
+func TestParse(t *testing.T) {
+	statements, err := Parse(strings.NewReader("push constant 7\npush static 1\nadd\n"), "Foo")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if len(statements) != 3 {
+		t.Fatalf("expected 3 statements, got: %d", len(statements))
+	}
+	if s, ok := statements[1].(*codewriter.PushStaticStatement); !ok || s.File != "Foo" {
+		t.Errorf("expected: push static Foo.1, got: %v", statements[1])
+	}
+
+	_, err = Parse(strings.NewReader("push constant 7\npish\n"), "Foo")
+	if errs, ok := err.(parser.ErrorList); !ok || len(errs) != 1 {
+		t.Errorf("expected one parse error, got: %v", err)
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	f, err := os.Open("../../07/StackArithmetic/SimpleAdd/SimpleAdd.vm")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer f.Close()
+
+	statements, err := Parse(f, "SimpleAdd")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	bootstrapped, err := Translate(statements, Options{ WriteOptions: codewriter.WriteOptions{ Bootstrap: codewriter.BootstrapSpec } })
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !strings.HasPrefix(bootstrapped, "// bootstrap") {
+		t.Errorf("expected a bootstrap")
+	}
+
+	// the project 7 tests set SP themselves
+	single, err := Translate(statements, Options{ WriteOptions: codewriter.WriteOptions{ Bootstrap: codewriter.BootstrapSpec }, SingleFile: true })
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !strings.HasPrefix(single, "// push constant 7") {
+		t.Errorf("expected no bootstrap, got:\n%s", single)
+	}
+}
+
+func TestTranslateTo(t *testing.T) {
+	statements, err := Parse(strings.NewReader("push constant 7\npush constant 8\nadd\n"), "Foo")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	expected, err := Translate(statements, Options{ SingleFile: true })
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	var actual strings.Builder
+	if err := TranslateTo(&actual, statements, Options{ SingleFile: true }); err != nil {
+		t.Fatalf(err.Error())
+	}
+	if actual.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, actual.String())
+	}
+}
@@ -0,0 +1,313 @@
+package vm
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+)
+
+// Builtin implements an os function natively, it is passed the call's
+// arguments and returns the value pushed in place of them, void functions
+// return 0 as their vm code would
+type Builtin func(m *Machine, args []int16) (int16, error)
+
+// DefaultBuiltins covers enough of the jack os to run compiled programs
+// headless: Math, Memory, Array, String, Output, Keyboard and Sys. Screen
+// calls do nothing and the Keyboard reads from Input.
+func DefaultBuiltins() map[string]Builtin {
+	b := map[string]Builtin{
+		"Math.init":     void,
+		"Math.multiply": func(m *Machine, a []int16) (int16, error) { return a[0] * a[1], nil },
+		"Math.divide":   divide,
+		"Math.min":      func(m *Machine, a []int16) (int16, error) { return min(a[0], a[1]), nil },
+		"Math.max":      func(m *Machine, a []int16) (int16, error) { return max(a[0], a[1]), nil },
+		"Math.abs":      func(m *Machine, a []int16) (int16, error) { return abs(a[0]), nil },
+		"Math.sqrt":     func(m *Machine, a []int16) (int16, error) { return int16(math.Sqrt(float64(a[0]))), nil },
+
+		"Memory.init":    void,
+		"Memory.alloc":   func(m *Machine, a []int16) (int16, error) { return m.alloc(int(a[0])) },
+		"Memory.deAlloc": void,
+		"Memory.peek":    func(m *Machine, a []int16) (int16, error) { return m.peek(a[0]) },
+		"Memory.poke":    func(m *Machine, a []int16) (int16, error) { return 0, m.poke(a[0], a[1]) },
+
+		"Array.new":     func(m *Machine, a []int16) (int16, error) { return m.alloc(int(a[0])) },
+		"Array.dispose": void,
+
+		"String.new":           newString,
+		"String.dispose":       void,
+		"String.length":        func(m *Machine, a []int16) (int16, error) { return m.peek(a[0] + 1) },
+		"String.charAt":        func(m *Machine, a []int16) (int16, error) { return m.peek(a[0] + 2 + a[1]) },
+		"String.setCharAt":     func(m *Machine, a []int16) (int16, error) { return 0, m.poke(a[0]+2+a[1], a[2]) },
+		"String.appendChar":    appendChar,
+		"String.eraseLastChar": eraseLastChar,
+		"String.intValue":      intValue,
+		"String.setInt":        setInt,
+		"String.backSpace":     func(m *Machine, a []int16) (int16, error) { return 129, nil },
+		"String.doubleQuote":   func(m *Machine, a []int16) (int16, error) { return 34, nil },
+		"String.newLine":       func(m *Machine, a []int16) (int16, error) { return 128, nil },
+
+		"Output.init":       void,
+		"Output.moveCursor": void,
+		"Output.printChar":  func(m *Machine, a []int16) (int16, error) { m.print(string(char(a[0]))); return 0, nil },
+		"Output.printInt":   func(m *Machine, a []int16) (int16, error) { m.print(strconv.Itoa(int(a[0]))); return 0, nil },
+		"Output.println":    func(m *Machine, a []int16) (int16, error) { m.print("\n"); return 0, nil },
+		"Output.backSpace":  func(m *Machine, a []int16) (int16, error) { m.print("\b"); return 0, nil },
+		"Output.printString": func(m *Machine, a []int16) (int16, error) {
+			s, err := m.String(a[0])
+			m.print(s)
+			return 0, err
+		},
+
+		"Keyboard.init":       void,
+		"Keyboard.keyPressed": void,
+		"Keyboard.readChar":   readChar,
+		"Keyboard.readLine":   readLine,
+		"Keyboard.readInt":    readInt,
+
+		"Sys.wait":  void,
+		"Sys.halt":  func(m *Machine, a []int16) (int16, error) { m.halted = true; return 0, nil },
+		"Sys.error": func(m *Machine, a []int16) (int16, error) { return 0, errors.New(fmt.Sprintf("Sys.error %d", a[0])) },
+	}
+
+	for _, name := range []string{"init", "clearScreen", "setColor", "drawPixel", "drawLine", "drawRectangle", "drawCircle"} {
+		b["Screen."+name] = void
+	}
+
+	return b
+}
+
+func void(m *Machine, args []int16) (int16, error) { return 0, nil }
+
+func min(a, b int16) int16 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max(a, b int16) int16 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func abs(a int16) int16 {
+	if a < 0 {
+		return -a
+	}
+	return a
+}
+
+func divide(m *Machine, a []int16) (int16, error) {
+	if a[1] == 0 {
+		return 0, errors.New("division by zero")
+	}
+	return a[0] / a[1], nil
+}
+
+// char maps the jack character set onto printable runes
+func char(c int16) rune {
+	switch c {
+	case 128:
+		return '\n'
+	case 129:
+		return '\b'
+	}
+	return rune(c)
+}
+
+func (m *Machine) print(s string) {
+	if m.Output != nil {
+		m.Output.Write([]byte(s))
+	}
+}
+
+// readRune reads the next character of Input, io.EOF once it is exhausted
+func (m *Machine) readRune() (rune, error) {
+	if m.Input == nil {
+		return 0, io.EOF
+	}
+	if m.input == nil {
+		m.input = bufio.NewReader(m.Input)
+	}
+	r, _, err := m.input.ReadRune()
+	return r, err
+}
+
+// readString reads a line of Input without its newline
+func (m *Machine) readString() (string, error) {
+	var line []rune
+	for {
+		r, err := m.readRune()
+		if err == io.EOF && len(line) > 0 {
+			return string(line), nil
+		}
+		if err != nil {
+			return "", err
+		}
+		if r == '\n' {
+			return string(line), nil
+		}
+		line = append(line, r)
+	}
+}
+
+func readChar(m *Machine, a []int16) (int16, error) {
+	r, err := m.readRune()
+	if err == io.EOF {
+		return 0, errors.New("Keyboard.readChar: end of input")
+	}
+	if r == '\n' {
+		return 128, err
+	}
+	return int16(r), err
+}
+
+// prompt prints a message string and reads a line of Input for a Keyboard
+// builtin
+func (m *Machine) prompt(name string, message int16) (string, error) {
+	s, err := m.String(message)
+	if err != nil {
+		return "", err
+	}
+	m.print(s)
+
+	line, err := m.readString()
+	if err == io.EOF {
+		return "", errors.New(fmt.Sprintf("%s: end of input", name))
+	}
+	return line, err
+}
+
+func readLine(m *Machine, a []int16) (int16, error) {
+	line, err := m.prompt("Keyboard.readLine", a[0])
+	if err != nil {
+		return 0, err
+	}
+
+	s, err := newString(m, []int16{int16(len([]rune(line)))})
+	if err != nil {
+		return 0, err
+	}
+	for _, r := range line {
+		if _, err := appendChar(m, []int16{s, int16(r)}); err != nil {
+			return 0, err
+		}
+	}
+	return s, nil
+}
+
+func readInt(m *Machine, a []int16) (int16, error) {
+	line, err := m.prompt("Keyboard.readInt", a[0])
+	if err != nil {
+		return 0, err
+	}
+	return leadingInt(line), nil
+}
+
+func (m *Machine) peek(address int16) (int16, error) {
+	if address < 0 {
+		return 0, errors.New(fmt.Sprintf("invalid address %d", address))
+	}
+	return m.RAM[address], nil
+}
+
+func (m *Machine) poke(address, v int16) error {
+	if address < 0 {
+		return errors.New(fmt.Sprintf("invalid address %d", address))
+	}
+	m.RAM[address] = v
+	return nil
+}
+
+// alloc hands out heap blocks, freed blocks are never reused
+func (m *Machine) alloc(size int) (int16, error) {
+	if size < 0 {
+		return 0, errors.New(fmt.Sprintf("invalid allocation size %d", size))
+	}
+	if m.heap+size > 16384 {
+		return 0, errors.New("heap overflow")
+	}
+	a := m.heap
+	m.heap += size
+	return int16(a), nil
+}
+
+// strings are laid out as [max length, length, chars...]
+
+func newString(m *Machine, a []int16) (int16, error) {
+	s, err := m.alloc(int(a[0]) + 2)
+	if err != nil {
+		return 0, err
+	}
+	m.RAM[s] = a[0]
+	m.RAM[s+1] = 0
+	return s, nil
+}
+
+func appendChar(m *Machine, a []int16) (int16, error) {
+	s, c := a[0], a[1]
+	if s < 0 || m.RAM[s+1] >= m.RAM[s] {
+		return 0, errors.New("string is full")
+	}
+	m.RAM[s+2+m.RAM[s+1]] = c
+	m.RAM[s+1]++
+	return s, nil
+}
+
+func eraseLastChar(m *Machine, a []int16) (int16, error) {
+	if a[0] < 0 || m.RAM[a[0]+1] == 0 {
+		return 0, errors.New("string is empty")
+	}
+	m.RAM[a[0]+1]--
+	return 0, nil
+}
+
+func intValue(m *Machine, a []int16) (int16, error) {
+	s, err := m.String(a[0])
+	if err != nil {
+		return 0, err
+	}
+	return leadingInt(s), nil
+}
+
+// leadingInt parses the leading digits of s only, like the os version
+func leadingInt(s string) int16 {
+	end := 0
+	for end < len(s) && (s[end] >= '0' && s[end] <= '9' || (end == 0 && s[end] == '-')) {
+		end++
+	}
+	v, _ := strconv.Atoi(s[:end])
+	return int16(v)
+}
+
+func setInt(m *Machine, a []int16) (int16, error) {
+	s := a[0]
+	digits := strconv.Itoa(int(a[1]))
+	if s < 0 || int(m.RAM[s]) < len(digits) {
+		return 0, errors.New("string is too short")
+	}
+	for i, c := range digits {
+		m.RAM[int(s)+2+i] = int16(c)
+	}
+	m.RAM[s+1] = int16(len(digits))
+	return 0, nil
+}
+
+// String reads a string object built by the String builtins
+func (m *Machine) String(s int16) (string, error) {
+	if s < 0 || int(s)+1 >= RAMSize {
+		return "", errors.New(fmt.Sprintf("invalid string %d", s))
+	}
+
+	n := int(m.RAM[s+1])
+	runes := make([]rune, 0, n)
+	for i := 0; i < n; i++ {
+		runes = append(runes, char(m.RAM[int(s)+2+i]))
+	}
+	return string(runes), nil
+}
@@ -0,0 +1,443 @@
+package vm
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	cw "vmt/codewriter"
+)
+
+// the standard hack vm memory mapping
+const (
+	SP   = 0
+	LCL  = 1
+	ARG  = 2
+	THIS = 3
+	THAT = 4
+
+	TempBase   = 5
+	StaticBase = 16
+	StackBase  = 256
+	HeapBase   = 2048
+
+	RAMSize = 32768
+
+	// MaxProgram is the most statements a program can have, return
+	// addresses are saved on the stack as 16 bit statement indexes and the
+	// bootstrap returns to the index past the last statement
+	MaxProgram = 65535
+)
+
+// Stop is the reason Run returned
+type Stop int
+
+const (
+	Limit Stop = iota
+	Breakpoint
+	Halted
+)
+
+func (s Stop) String() string {
+	switch s {
+	case Breakpoint:
+		return "breakpoint"
+	case Halted:
+		return "halted"
+	}
+	return "limit"
+}
+
+// Frame is an active function call
+type Frame struct {
+	Function string
+	// Return is the statement index execution continues at on return
+	Return int
+}
+
+// Machine executes vm statements directly against a hack style ram. The
+// stack, segment pointers and temp live in ram as they would once
+// translated, statics are allocated from 16 per file in the order they
+// first appear, as the assembler would.
+type Machine struct {
+	RAM [RAMSize]int16
+
+	// PC is the index of the next statement to run
+	PC     int
+	Frames []Frame
+	Steps  int
+
+	// Builtins are called for functions the program does not define
+	Builtins map[string]Builtin
+
+	// Output receives what the Output builtins print, nil discards it
+	Output io.Writer
+	// Input is read by the Keyboard builtins, nil reads as empty
+	Input io.Reader
+
+	program     []cw.Statement
+	functions   map[string]int
+	labels      map[string]int
+	statics     map[string]int
+	breakpoints map[int]bool
+	halted      bool
+
+	// input buffers Input so lines can be read from it
+	input *bufio.Reader
+
+	// heap is the next free address handed out by the Memory.alloc builtin
+	heap int
+}
+
+func vmError(pc int, stmt cw.Statement, format string, args ...interface{}) error {
+	return errors.New(fmt.Sprintf("statement %d %v: %s", pc, stmt, fmt.Sprintf(format, args...)))
+}
+
+// New loads a program, execution starts at Sys.init if it is defined and at
+// the first statement otherwise. No frame is pushed for Sys.init, the
+// segment pointers are left for the caller to set up or Bootstrap.
+func New(program []cw.Statement) (*Machine, error) {
+	if len(program) > MaxProgram {
+		return nil, errors.New(fmt.Sprintf("program too large: %d statements, at most %d", len(program), MaxProgram))
+	}
+
+	m := &Machine{
+		Builtins:    DefaultBuiltins(),
+		program:     program,
+		functions:   map[string]int{},
+		labels:      map[string]int{},
+		statics:     map[string]int{},
+		breakpoints: map[int]bool{},
+		heap:        HeapBase,
+	}
+
+	// resolve functions, labels and statics up front
+	for i, stmt := range program {
+		switch s := stmt.(type) {
+		case *cw.FunctionStatement:
+			if _, ok := m.functions[s.Name]; ok {
+				return nil, vmError(i, stmt, "function %s is already defined", s.Name)
+			}
+			m.functions[s.Name] = i
+		case *cw.LabelStatement:
+			m.labels[label(s.Function, s.Name)] = i
+		case *cw.PushStaticStatement:
+			m.static(s.File, s.Argument)
+		case *cw.PopStaticStatement:
+			m.static(s.File, s.Argument)
+		}
+	}
+
+	for i, stmt := range program {
+		switch s := stmt.(type) {
+		case *cw.GotoStatement:
+			if _, ok := m.labels[label(s.Function, s.Name)]; !ok {
+				return nil, vmError(i, stmt, "undefined label %s", s.Name)
+			}
+		case *cw.IfGotoStatement:
+			if _, ok := m.labels[label(s.Function, s.Name)]; !ok {
+				return nil, vmError(i, stmt, "undefined label %s", s.Name)
+			}
+		}
+	}
+
+	if i, ok := m.functions["Sys.init"]; ok {
+		m.PC = i
+		m.Frames = []Frame{{Function: "Sys.init", Return: -1}}
+	}
+
+	return m, nil
+}
+
+func label(function, name string) string {
+	return function + "$" + name
+}
+
+func (m *Machine) static(file string, index int) int {
+	name := fmt.Sprintf("%s.%d", file, index)
+	if a, ok := m.statics[name]; ok {
+		return a
+	}
+	a := StaticBase + len(m.statics)
+	m.statics[name] = a
+	return a
+}
+
+// Bootstrap sets the stack pointer to 256 and calls Sys.init, as the
+// translated bootstrap code does
+func (m *Machine) Bootstrap() error {
+	i, ok := m.functions["Sys.init"]
+	if !ok {
+		return errors.New("bootstrap: Sys.init is not defined")
+	}
+
+	m.RAM[SP] = StackBase
+	m.Frames = nil
+	m.PC = len(m.program)
+	m.call("Sys.init", 0, i)
+	return nil
+}
+
+// Statement returns the statement at index i, nil past the end of the program
+func (m *Machine) Statement(i int) cw.Statement {
+	if i < 0 || i >= len(m.program) {
+		return nil
+	}
+	return m.program[i]
+}
+
+// Function returns the name of the function currently running
+func (m *Machine) Function() string {
+	if len(m.Frames) == 0 {
+		return ""
+	}
+	return m.Frames[len(m.Frames)-1].Function
+}
+
+// Halted reports whether the program has run past its end or called Sys.halt
+func (m *Machine) Halted() bool {
+	return m.halted || m.PC < 0 || m.PC >= len(m.program)
+}
+
+// ---------------------------------------------------------------------------------
+// Breakpoints ---------------------------------------------------------------------
+// ---------------------------------------------------------------------------------
+
+// Break sets a breakpoint on a statement index
+func (m *Machine) Break(i int) {
+	m.breakpoints[i] = true
+}
+
+// BreakFunction sets a breakpoint on the entry of a function
+func (m *Machine) BreakFunction(name string) error {
+	i, ok := m.functions[name]
+	if !ok {
+		return errors.New(fmt.Sprintf("undefined function %s", name))
+	}
+	m.Break(i)
+	return nil
+}
+
+// ClearBreakpoints removes every breakpoint
+func (m *Machine) ClearBreakpoints() {
+	m.breakpoints = map[int]bool{}
+}
+
+// ---------------------------------------------------------------------------------
+// Execution -----------------------------------------------------------------------
+// ---------------------------------------------------------------------------------
+
+// Run steps until the program halts, a breakpoint is reached or limit steps
+// have run, a limit of 0 runs without a limit. The breakpoint at the
+// statement Run starts on is stepped over so Run can resume from it.
+func (m *Machine) Run(limit int) (Stop, error) {
+	for n := 0; limit == 0 || n < limit; n++ {
+		m.skipLabels()
+		if m.Halted() {
+			return Halted, nil
+		}
+		if n > 0 && m.breakpoints[m.PC] {
+			return Breakpoint, nil
+		}
+		if err := m.Step(); err != nil {
+			return Halted, err
+		}
+	}
+
+	if m.Halted() {
+		return Halted, nil
+	}
+	return Limit, nil
+}
+
+func (m *Machine) skipLabels() {
+	for !m.Halted() {
+		if _, ok := m.program[m.PC].(*cw.LabelStatement); !ok {
+			return
+		}
+		m.PC++
+	}
+}
+
+func (m *Machine) push(v int16) {
+	m.RAM[m.RAM[SP]] = v
+	m.RAM[SP]++
+}
+
+func (m *Machine) pop() int16 {
+	m.RAM[SP]--
+	return m.RAM[m.RAM[SP]]
+}
+
+func boolValue(b bool) int16 {
+	if b {
+		return -1
+	}
+	return 0
+}
+
+// address returns the ram address of a segment entry
+func (m *Machine) address(segment cw.Location, index int) (int, bool) {
+	switch segment {
+	case "local":
+		return int(m.RAM[LCL]) + index, true
+	case "argument":
+		return int(m.RAM[ARG]) + index, true
+	case "this":
+		return int(m.RAM[THIS]) + index, true
+	case "that":
+		return int(m.RAM[THAT]) + index, true
+	case "pointer":
+		return THIS + index, index >= 0 && index < 2
+	case "temp":
+		return TempBase + index, index >= 0 && index < 8
+	}
+	return 0, false
+}
+
+// Step executes a single statement, labels are not statements that run and
+// are passed over as the course vm emulator does
+func (m *Machine) Step() (err error) {
+	m.skipLabels()
+	if m.Halted() {
+		return errors.New("program has halted")
+	}
+
+	pc := m.PC
+	stmt := m.program[pc]
+	m.PC++
+	m.Steps++
+
+	// a stack or segment pointer outside of ram shows up as an out of range index
+	defer func() {
+		if r := recover(); r != nil {
+			err = vmError(pc, stmt, "%v", r)
+		}
+	}()
+
+	switch s := stmt.(type) {
+	case *cw.PushConstStatement:
+		m.push(int16(s.Argument))
+
+	case *cw.PushStaticStatement:
+		m.push(m.RAM[m.static(s.File, s.Argument)])
+
+	case *cw.PopStaticStatement:
+		m.RAM[m.static(s.File, s.Argument)] = m.pop()
+
+	case *cw.PushLocationStatement:
+		a, ok := m.address(s.Location, s.Argument)
+		if !ok || a < 0 || a >= RAMSize {
+			return vmError(pc, stmt, "invalid address %s %d", s.Location, s.Argument)
+		}
+		m.push(m.RAM[a])
+
+	case *cw.PopStatement:
+		a, ok := m.address(s.Location, s.Argument)
+		if !ok || a < 0 || a >= RAMSize {
+			return vmError(pc, stmt, "invalid address %s %d", s.Location, s.Argument)
+		}
+		m.RAM[a] = m.pop()
+
+	case *cw.AddStatement:
+		y, x := m.pop(), m.pop()
+		m.push(x + y)
+	case *cw.SubStatement:
+		y, x := m.pop(), m.pop()
+		m.push(x - y)
+	case *cw.NegStatement:
+		m.push(-m.pop())
+	case *cw.AndStatement:
+		y, x := m.pop(), m.pop()
+		m.push(x & y)
+	case *cw.OrStatement:
+		y, x := m.pop(), m.pop()
+		m.push(x | y)
+	case *cw.NotStatement:
+		m.push(^m.pop())
+	case *cw.EqStatement:
+		y, x := m.pop(), m.pop()
+		m.push(boolValue(x == y))
+	case *cw.GtStatement:
+		y, x := m.pop(), m.pop()
+		m.push(boolValue(x > y))
+	case *cw.LtStatement:
+		y, x := m.pop(), m.pop()
+		m.push(boolValue(x < y))
+
+	case *cw.GotoStatement:
+		m.PC = m.labels[label(s.Function, s.Name)]
+
+	case *cw.IfGotoStatement:
+		if m.pop() != 0 {
+			m.PC = m.labels[label(s.Function, s.Name)]
+		}
+
+	case *cw.FunctionStatement:
+		for i := 0; i < s.Nvars; i++ {
+			m.push(0)
+		}
+
+	case *cw.CallStatement:
+		if i, ok := m.functions[s.Name]; ok {
+			m.call(s.Name, s.Nargs, i)
+			break
+		}
+
+		builtin, ok := m.Builtins[s.Name]
+		if !ok {
+			return vmError(pc, stmt, "undefined function %s", s.Name)
+		}
+
+		args := make([]int16, s.Nargs)
+		for i := s.Nargs - 1; i >= 0; i-- {
+			args[i] = m.pop()
+		}
+		v, err := builtin(m, args)
+		if err != nil {
+			return vmError(pc, stmt, "%s", err.Error())
+		}
+		m.push(v)
+
+	case *cw.ReturnStatement:
+		m.ret()
+
+	default:
+		return vmError(pc, stmt, "unsupported statement")
+	}
+
+	return nil
+}
+
+// call saves the caller's frame and jumps to the function at index i
+func (m *Machine) call(name string, nargs, i int) {
+	m.push(int16(m.PC))
+	m.push(m.RAM[LCL])
+	m.push(m.RAM[ARG])
+	m.push(m.RAM[THIS])
+	m.push(m.RAM[THAT])
+	m.RAM[ARG] = m.RAM[SP] - 5 - int16(nargs)
+	m.RAM[LCL] = m.RAM[SP]
+
+	m.Frames = append(m.Frames, Frame{Function: name, Return: m.PC})
+	m.PC = i
+}
+
+// ret restores the caller's frame, leaving the return value on its stack
+func (m *Machine) ret() {
+	frame := m.RAM[LCL]
+	retAddr := m.RAM[frame-5]
+
+	m.RAM[m.RAM[ARG]] = m.pop()
+	m.RAM[SP] = m.RAM[ARG] + 1
+	m.RAM[THAT] = m.RAM[frame-1]
+	m.RAM[THIS] = m.RAM[frame-2]
+	m.RAM[ARG] = m.RAM[frame-3]
+	m.RAM[LCL] = m.RAM[frame-4]
+
+	if len(m.Frames) > 0 {
+		m.Frames = m.Frames[:len(m.Frames)-1]
+	}
+	// return addresses are unsigned, past 32767 they wrap in the int16 ram
+	m.PC = int(uint16(retAddr))
+}
@@ -0,0 +1,393 @@
+package vm
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	cw "vmt/codewriter"
+	"vmt/parser"
+)
+
+func assert(t *testing.T, n string, a, b interface{}) {
+	if a != b {
+		t.Fatalf("%s : expected: %v <%T>   got: %v <%T>", n, a, a, b, b)
+	}
+}
+
+func readFile(t *testing.T, path string) string {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	return string(data)
+}
+
+func parse(t *testing.T, files ...string) []cw.Statement {
+	var p parser.Parser
+	for _, file := range files {
+		if err := p.Parse(readFile(t, file), strings.TrimSuffix(filepath.Base(file), ".vm")); err != nil {
+			t.Fatalf(err.Error())
+		}
+	}
+	return p.Statements
+}
+
+func machine(t *testing.T, files ...string) *Machine {
+	m, err := New(parse(t, files...))
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	return m
+}
+
+var comments = regexp.MustCompile(`//.*`)
+
+// runVME runs the handful of commands the course VME scripts use: load,
+// set, repeat n { vmstep } and output against the compare file
+func runVME(t *testing.T, script string) {
+	dir := filepath.Dir(script)
+	source := comments.ReplaceAllString(readFile(t, script), "")
+	source = strings.NewReplacer("{", ";", "}", ";", ",", ";").Replace(source)
+
+	var compare [][]string
+	for _, line := range strings.Split(readFile(t, strings.TrimSuffix(script, "VME.tst")+".cmp"), "\n") {
+		if fields := strings.Split(strings.Trim(strings.TrimSpace(line), "|"), "|"); len(fields) > 0 && fields[0] != "" {
+			compare = append(compare, fields)
+		}
+	}
+
+	var m *Machine
+	var columns []string
+	row := 0
+	repeat := 1
+
+	for _, cmd := range strings.Split(source, ";") {
+		words := strings.Fields(cmd)
+		if len(words) == 0 {
+			continue
+		}
+
+		switch words[0] {
+		case "load":
+			files := words[1:]
+			if len(files) == 0 {
+				files, _ = filepath.Glob(filepath.Join(dir, "*.vm"))
+			} else {
+				files[0] = filepath.Join(dir, files[0])
+			}
+			m = machine(t, files...)
+
+		case "set":
+			v, err := strconv.Atoi(words[2])
+			if err != nil {
+				t.Fatalf(err.Error())
+			}
+			setVariable(t, m, words[1], int16(v))
+
+		case "repeat":
+			repeat, _ = strconv.Atoi(words[1])
+
+		case "vmstep":
+			for i := 0; i < repeat; i++ {
+				if err := m.Step(); err != nil {
+					t.Fatalf("%s : %s", script, err.Error())
+				}
+			}
+			repeat = 1
+
+		case "output-list":
+			columns = nil
+			for _, col := range words[1:] {
+				columns = append(columns, col[:strings.Index(col, "%")])
+			}
+			row++ // header
+
+		case "output":
+			for i, col := range columns {
+				index, _ := strconv.Atoi(strings.Trim(col, "RAM[]"))
+				expected := strings.TrimSpace(compare[row][i])
+				assert(t, script+" "+col, expected, strconv.Itoa(int(m.RAM[index])))
+			}
+			row++
+		}
+	}
+}
+
+var segments = map[string]int{"sp": SP, "local": LCL, "argument": ARG, "this": THIS, "that": THAT}
+
+func setVariable(t *testing.T, m *Machine, name string, v int16) {
+	i := strings.Index(name, "[")
+	if i < 0 {
+		m.RAM[segments[name]] = v
+		return
+	}
+
+	index, err := strconv.Atoi(name[i+1 : len(name)-1])
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if name[:i] == "RAM" {
+		m.RAM[index] = v
+	} else {
+		m.RAM[int(m.RAM[segments[name[:i]]])+index] = v
+	}
+}
+
+func TestVMEScripts(t *testing.T) {
+	for _, pattern := range []string{"../../07/*/*/*VME.tst", "../../08/*/*/*VME.tst"} {
+		scripts, err := filepath.Glob(pattern)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		for _, script := range scripts {
+			runVME(t, script)
+		}
+	}
+}
+
+const sum = `
+function Sys.init 0
+push constant 4
+call Main.sum 1
+pop static 0
+label END
+goto END
+function Main.sum 1
+push constant 0
+pop local 0
+label LOOP
+push argument 0
+push constant 0
+eq
+if-goto DONE
+push local 0
+push argument 0
+add
+pop local 0
+push argument 0
+push constant 1
+sub
+pop argument 0
+goto LOOP
+label DONE
+push local 0
+return
+`
+
+func TestBootstrapAndFrames(t *testing.T) {
+	var p parser.Parser
+	p.Parse(sum, "Sys")
+
+	m, err := New(p.Statements)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if err := m.Bootstrap(); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	assert(t, "Bootstrap", int16(261), m.RAM[SP])
+	assert(t, "Bootstrap", "Sys.init", m.Function())
+
+	if err := m.BreakFunction("Main.sum"); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	stop, err := m.Run(1000)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	assert(t, "Breakpoint", Breakpoint, stop)
+	assert(t, "Breakpoint", "Main.sum", m.Function())
+	assert(t, "Breakpoint", 2, len(m.Frames))
+
+	// resuming steps over the breakpoint it stopped at
+	m.ClearBreakpoints()
+	m.Break(5) // goto END
+	stop, err = m.Run(1000)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	assert(t, "Return", Breakpoint, stop)
+	assert(t, "Return", "Sys.init", m.Function())
+	assert(t, "Return", int16(10), m.RAM[StaticBase])
+	assert(t, "Return", int16(261), m.RAM[SP])
+
+	// the END loop never halts
+	m.ClearBreakpoints()
+	stop, err = m.Run(100)
+	assert(t, "Limit", Limit, stop)
+}
+
+func TestBuiltins(t *testing.T) {
+	var p parser.Parser
+	p.Parse(`
+		function Main.main 0
+		push constant 2
+		call String.new 1
+		push constant 72
+		call String.appendChar 2
+		push constant 105
+		call String.appendChar 2
+		call Output.printString 1
+		pop temp 0
+		push constant 6
+		push constant 7
+		call Math.multiply 2
+		call Output.printInt 1
+		pop temp 0
+		call Sys.halt 0
+	`, "Main")
+
+	m, err := New(p.Statements)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	m.RAM[SP] = StackBase
+
+	var out strings.Builder
+	m.Output = &out
+
+	stop, err := m.Run(0)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	assert(t, "Builtins", Halted, stop)
+	assert(t, "Builtins", "Hi42", out.String())
+}
+
+func TestKeyboard(t *testing.T) {
+	var p parser.Parser
+	p.Parse(`
+		function Main.main 0
+		push constant 1
+		call String.new 1
+		push constant 63
+		call String.appendChar 2
+		call Keyboard.readLine 1
+		call Output.printString 1
+		pop temp 0
+		push constant 0
+		call String.new 1
+		call Keyboard.readInt 1
+		push constant 0
+		call String.new 1
+		call Keyboard.readInt 1
+		add
+		call Output.printInt 1
+		pop temp 0
+		call Keyboard.readChar 0
+		call Output.printChar 1
+		pop temp 0
+		call Keyboard.readChar 0
+		call Output.printChar 1
+		pop temp 0
+		call Sys.halt 0
+	`, "Main")
+
+	m, err := New(p.Statements)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	m.RAM[SP] = StackBase
+
+	var out strings.Builder
+	m.Output = &out
+	m.Input = strings.NewReader("Hi\n40\n2x\nz\n")
+
+	stop, err := m.Run(0)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	assert(t, "Keyboard", Halted, stop)
+	assert(t, "Keyboard", "?Hi42z\n", out.String())
+
+	// reading past the end of input is an error
+	m, err = New(p.Statements)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	m.RAM[SP] = StackBase
+	m.Input = strings.NewReader("Hi\n40")
+
+	if _, err := m.Run(0); err == nil || !strings.Contains(err.Error(), "Keyboard.readInt: end of input") {
+		t.Errorf("expected end of input error, got: %v", err)
+	}
+}
+
+func TestLargeProgram(t *testing.T) {
+	// return addresses past 32767 are negative as int16
+	var code strings.Builder
+	code.WriteString("function Sys.init 0\n")
+	for i := 0; i < 17000; i++ {
+		code.WriteString("push constant 1\npop temp 0\n")
+	}
+	code.WriteString("call Main.f 0\npop temp 1\ncall Sys.halt 0\nfunction Main.f 0\npush constant 7\nreturn\n")
+
+	var p parser.Parser
+	if err := p.Parse(code.String(), "Main"); err != nil {
+		t.Fatalf(err.Error())
+	}
+	m, err := New(p.Statements)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if err := m.Bootstrap(); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	stop, err := m.Run(0)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	assert(t, "LargeProgram", Halted, stop)
+	assert(t, "LargeProgram", int16(7), m.RAM[TempBase+1])
+
+	// larger than a return address can hold
+	program := make([]cw.Statement, MaxProgram+1)
+	for i := range program {
+		program[i] = &cw.AddStatement{}
+	}
+	if _, err := New(program); err == nil || err.Error() != "program too large: 65536 statements, at most 65535" {
+		t.Errorf("expected: program too large, got: %v", err)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	tests := []string{
+		"goto NOWHERE",
+		"function A.f 0\nfunction A.f 0",
+	}
+
+	for _, test := range tests {
+		var p parser.Parser
+		p.Parse(test, "A")
+		if _, err := New(p.Statements); err == nil {
+			t.Errorf("expected error loading: %s", test)
+		}
+	}
+
+	var p parser.Parser
+	p.Parse("push constant 1\ncall Nope.nope 1", "A")
+	m, err := New(p.Statements)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	m.RAM[SP] = StackBase
+	if _, err := m.Run(0); err == nil {
+		t.Errorf("expected error calling an undefined function")
+	}
+
+	p = parser.Parser{}
+	p.Parse("pop temp 0", "A")
+	m, err = New(p.Statements)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if _, err := m.Run(0); err == nil {
+		t.Errorf("expected error popping an empty stack")
+	}
+}